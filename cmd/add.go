@@ -80,17 +80,19 @@ var networkOptions = []string{"auto", "tailscale", "direct"}
 // ── Model ─────────────────────────────────────────────────────────────────────
 
 type addWizard struct {
-	step    int
-	values  []string // resolved value per step (empty = not yet filled)
-	buf     string   // live text buffer for the current text step
-	netSel  int      // cursor for network selection
-	errMsg  string
-	done    bool
-	aborted bool
+	step     int
+	values   []string // resolved value per step (empty = not yet filled)
+	kept     []bool   // per step: the default was taken rather than chosen
+	buf      string   // live text buffer for the current text step
+	netSel   int      // cursor for network selection
+	netMoved bool     // the network cursor was moved off the default
+	errMsg   string
+	done     bool
+	aborted  bool
 }
 
 func newAddWizard() addWizard {
-	return addWizard{values: make([]string, len(steps))}
+	return addWizard{values: make([]string, len(steps)), kept: make([]bool, len(steps))}
 }
 
 func (m addWizard) Init() tea.Cmd { return nil }
@@ -126,12 +128,14 @@ func (m addWizard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case "enter":
 		if m.step == netStep {
 			m.values[netStep] = networkOptions[m.netSel]
+			m.kept[netStep] = !m.netMoved
 			m.done = true
 			return m, tea.Quit
 		}
 		// Validate text input.
 		val := strings.TrimSpace(m.buf)
 		s := steps[m.step]
+		m.kept[m.step] = val == ""
 		if val == "" {
 			if s.required {
 				m.errMsg = fmt.Sprintf("%s is required", s.label)
@@ -164,10 +168,12 @@ func (m addWizard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "up", "k":
 				if m.netSel > 0 {
 					m.netSel--
+					m.netMoved = true
 				}
 			case "down", "j":
 				if m.netSel < len(networkOptions)-1 {
 					m.netSel++
+					m.netMoved = true
 				}
 			}
 		} else {
@@ -267,12 +273,64 @@ Advanced SSH options can be set with optional flags:
   --attempts N             Number of connection attempts before giving up (default 3)
   --alive-interval N       Seconds between keepalive packets (default 10)
   --alive-count N          Max unanswered keepalives before disconnect (default 60)
+  --jump HOST[,HOST…]      Jump host chain: sshtie profile names or user@host:port
+  --extends GROUP          Inherit defaults from a group in profiles.yaml
+  --forward SPEC           Port forward: L5432:localhost:5432, R8080:localhost:3000, D1080
+  --wake-mac MAC           Wake the server with Wake-on-LAN when it is asleep
+  --wake-relay PROFILE     Send the wake packet from this profile (on the server's LAN)
+  --multiplexer NAME       tmux (default), screen, zellij, or none for a bare shell
+  --transport TYPE         Reach the server through aws-ssm or gcp-iap instead of its SSH port
+  --instance ID            Instance for --transport (EC2 instance ID or GCE instance name)
+  --region R               AWS region for aws-ssm (default: the aws CLI's)
+  --zone Z                 GCE zone for gcp-iap
+  --project P              GCP project for gcp-iap (default: gcloud's)
 
 Example:
   sshtie add
-  sshtie add --forward-agent --attempts=5`,
+  sshtie add --forward-agent --attempts=5
+  sshtie add --jump bastion
+  sshtie add --jump bastion,ops@10.0.0.5:2222
+  sshtie add --forward L5432:localhost:5432 --forward D1080
+  sshtie add --wake-mac 3c:22:fb:12:34:56
+  sshtie add --multiplexer zellij
+  sshtie add --transport aws-ssm --instance i-0123456789abcdef0 --region eu-west-1
+  sshtie add --transport gcp-iap --instance build-vm-1 --zone europe-west1-b`,
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		// Validate forwards before the wizard so typos don't cost the answers.
+		forwardSpecs, _ := cmd.Flags().GetStringArray("forward")
+		var forwards []profile.Forward
+		for _, spec := range forwardSpecs {
+			f, err := profile.ParseForward(spec)
+			if err != nil {
+				return err
+			}
+			forwards = append(forwards, f)
+		}
+		mux, _ := cmd.Flags().GetString("multiplexer")
+		if _, err := profile.Multiplexer(profile.Profile{Multiplexer: mux}); err != nil {
+			return err
+		}
+		var transport profile.Transport
+		transport.Type, _ = cmd.Flags().GetString("transport")
+		transport.Instance, _ = cmd.Flags().GetString("instance")
+		transport.Region, _ = cmd.Flags().GetString("region")
+		transport.Zone, _ = cmd.Flags().GetString("zone")
+		transport.Project, _ = cmd.Flags().GetString("project")
+		if transport != (profile.Transport{}) {
+			if err := transport.Validate(); err != nil {
+				return err
+			}
+		}
+		wakeMAC, _ := cmd.Flags().GetString("wake-mac")
+		wakeRelay, _ := cmd.Flags().GetString("wake-relay")
+		wake := profile.Wake{MAC: wakeMAC, Relay: wakeRelay}
+		if wakeMAC != "" || wakeRelay != "" {
+			if err := wake.Validate(); err != nil {
+				return err
+			}
+		}
+
 		prog := tea.NewProgram(newAddWizard(), tea.WithAltScreen())
 		final, err := prog.Run()
 		if err != nil {
@@ -291,10 +349,13 @@ Example:
 			port = 22
 		}
 
+		extends, _ := cmd.Flags().GetString("extends")
+
 		// Store key as empty string when it matches the default
-		// so profile.DefaultKey() can manage it.
+		// so profile.DefaultKey() can manage it. Under a group only a
+		// key left at the default is dropped: one typed in overrides.
 		key := wiz.values[4]
-		if key == "~/.ssh/id_ed25519" {
+		if wiz.kept[4] || (extends == "" && key == "~/.ssh/id_ed25519") {
 			key = ""
 		}
 
@@ -302,6 +363,7 @@ Example:
 		attempts, _     := cmd.Flags().GetInt("attempts")
 		aliveInterval, _ := cmd.Flags().GetInt("alive-interval")
 		aliveCount, _    := cmd.Flags().GetInt("alive-count")
+		jump, _          := cmd.Flags().GetStringSlice("jump")
 
 		p := profile.Profile{
 			Name:        wiz.values[0],
@@ -310,12 +372,47 @@ Example:
 			Port:        port,
 			Key:         key,
 			TmuxSession: wiz.values[5],
+			Multiplexer: mux,
 			Network:     wiz.values[6],
 
 			ForwardAgent:        forwardAgent,
 			ConnectionAttempts:  attempts,
 			ServerAliveInterval: aliveInterval,
 			ServerAliveCountMax: aliveCount,
+
+			Jump:      jump,
+			Extends:   extends,
+			Transport: transport,
+			Forwards:  forwards,
+			Wake:      wake,
+		}
+
+		// With a group, a step left at its default means "inherit"; a value
+		// typed or picked is an override, even when it equals the default.
+		if extends != "" {
+			if wiz.kept[3] {
+				p.Port = 0
+			}
+			if wiz.kept[5] {
+				p.TmuxSession = ""
+			}
+			if wiz.kept[netStep] {
+				p.Network = ""
+			}
+		}
+
+		if len(jump) > 0 {
+			existing, err := profile.Load()
+			if err != nil {
+				return err
+			}
+			if _, err := profile.ResolveJumpChain(p, existing); err != nil {
+				return err
+			}
+		}
+
+		if err := profile.ValidateTransport(p); err != nil {
+			return err
 		}
 
 		if err := profile.Add(p); err != nil {
@@ -336,6 +433,24 @@ Example:
 		if aliveCount > 0 {
 			fmt.Printf("   ServerAliveCountMax: %d\n", aliveCount)
 		}
+		if len(jump) > 0 {
+			fmt.Printf("   Jump: %s\n", strings.Join(jump, " → "))
+		}
+		if extends != "" {
+			fmt.Printf("   Extends: %s\n", extends)
+		}
+		for _, f := range forwards {
+			fmt.Printf("   Forward: %s\n", f.String())
+		}
+		if wake.Enabled() {
+			fmt.Printf("   Wake-on-LAN: %s\n", wake.MAC)
+		}
+		if transport.Enabled() {
+			fmt.Printf("   Transport: %s\n", transport)
+		}
+		if offerKeySetup(p.Name) {
+			return nil
+		}
 		fmt.Printf("→ Try: sshtie connect %s\n", p.Name)
 		return nil
 	},
@@ -346,4 +461,15 @@ func init() {
 	addCmd.Flags().Int("attempts", 0, "ConnectionAttempts (default 3)")
 	addCmd.Flags().Int("alive-interval", 0, "ServerAliveInterval in seconds (default 10)")
 	addCmd.Flags().Int("alive-count", 0, "ServerAliveCountMax (default 60)")
+	addCmd.Flags().String("extends", "", "Group in profiles.yaml to inherit defaults from")
+	addCmd.Flags().StringSlice("jump", nil, "Jump host chain (profile names or user@host:port, outermost first)")
+	addCmd.Flags().StringArray("forward", nil, "Port forward (L…, R… or D… in ssh syntax); repeatable")
+	addCmd.Flags().String("multiplexer", "", "Multiplexer on the server: tmux (default), screen, zellij or none")
+	addCmd.Flags().String("transport", "", "Reach the server through a cloud tunnel: aws-ssm or gcp-iap")
+	addCmd.Flags().String("instance", "", "Instance for --transport (EC2 instance ID or GCE instance name)")
+	addCmd.Flags().String("region", "", "AWS region for --transport aws-ssm")
+	addCmd.Flags().String("zone", "", "GCE zone for --transport gcp-iap")
+	addCmd.Flags().String("project", "", "GCP project for --transport gcp-iap")
+	addCmd.Flags().String("wake-mac", "", "MAC address for Wake-on-LAN when the server is asleep")
+	addCmd.Flags().String("wake-relay", "", "Profile that sends the Wake-on-LAN packet (when not on the server's LAN)")
 }
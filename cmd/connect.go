@@ -3,35 +3,69 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"os/exec"
 
 	"github.com/spf13/cobra"
 
 	"github.com/ainsuotain/sshtie/internal/connector"
+	"github.com/ainsuotain/sshtie/internal/hostkey"
 	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/tmux"
 	"github.com/ainsuotain/sshtie/internal/tui"
 )
 
+var (
+	connectDryRun   bool
+	connectRetryAll bool
+	connectSession  string
+)
+
 var connectCmd = &cobra.Command{
 	Use:   "connect <name>",
-	Short: "Connect to a profile (mosh → ssh fallback → tmux)",
+	Short: "Connect to a profile (mosh+tmux → ssh+tmux → ssh, or the profile's strategies)",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		p, err := profile.Get(args[0])
 		if err != nil {
 			return err
 		}
+		if connectSession != "" {
+			if err := tmux.ValidName(connectSession); err != nil {
+				return err
+			}
+			p.TmuxSession = connectSession
+		}
+		if connectDryRun {
+			pv, err := connector.NewPreview(p, true)
+			if err != nil {
+				return err
+			}
+			printDryRun(p, pv)
+			return nil
+		}
 		return runConnect(p)
 	},
 }
 
+func init() {
+	connectCmd.Flags().BoolVar(&connectDryRun, "dry-run", false,
+		"show the strategy chain and exact commands without connecting")
+	connectCmd.Flags().BoolVar(&connectRetryAll, "retry-all", false,
+		"try every strategy, even ones that recently failed on this network")
+	connectCmd.Flags().StringVar(&connectSession, "session", "",
+		"attach to (or create) this tmux session instead of the profile's")
+}
+
 // runConnect shows the connection-progress TUI then executes the chosen action.
 // Shared by connectCmd, root shortcut, and the profile-picker TUI.
 func runConnect(p profile.Profile) error {
-	port := p.Port
-	if port == 0 {
-		port = 22
+	// Pick the address (and wake the server) once, so the checks and the
+	// session use the same one. Reconnects pick again from the saved list.
+	saved := p
+	p, picked, err := connector.Resolve(p)
+	if err != nil {
+		return err
 	}
+	opts := connector.ConnectOptions{RetryAll: connectRetryAll, Unresolved: saved}
 
 	// When launched from the Windows tray app (SSHTIE_KEEP_WINDOW=1), install
 	// a console close-event handler that hides the window instead of exiting.
@@ -40,10 +74,13 @@ func runConnect(p profile.Profile) error {
 		connector.InstallWindowHideHandler()
 	}
 
-	result, err := tui.RunConnect(p, !isKnownHost(p.Host, port))
+	result, err := tui.RunConnect(p, picked, connectSession == "")
 	if err != nil {
 		return err
 	}
+	if result.Session != "" {
+		p.TmuxSession = result.Session
+	}
 
 	// Terminal is fully restored here — safe to exec ssh/mosh.
 	switch result.Action {
@@ -52,30 +89,22 @@ func runConnect(p profile.Profile) error {
 			return err
 		}
 		fmt.Printf("\n→ Connecting to %s (%s@%s)…\n", p.Name, p.User, p.Host)
-		return connector.Connect(p)
+		return connector.ConnectWith(p, opts)
+
+	case tui.ConnectTrustNewKey:
+		if err := hostkey.Pin(p.Name, result.HostKey.Presented); err != nil {
+			return err
+		}
+		p.HostKeys = hostkey.Fingerprints(result.HostKey.Presented)
+		fmt.Printf("✅ Pinned the new host key of %s.\n", p.Name)
+		fmt.Printf("→ Connecting to %s (%s@%s)…\n", p.Name, p.User, p.Host)
+		return connector.ConnectWith(p, opts)
 
 	case tui.ConnectProceed:
 		fmt.Printf("→ Connecting to %s (%s@%s)…\n", p.Name, p.User, p.Host)
-		return connector.Connect(p)
+		return connector.ConnectWith(p, opts)
 
 	default: // ConnectQuit / ConnectNone
 		return nil
 	}
 }
-
-// isKnownHost reports whether the host is already in ~/.ssh/known_hosts.
-// Returns true (assume known) if ssh-keygen is unavailable.
-func isKnownHost(host string, port int) bool {
-	target := host
-	if port != 0 && port != 22 {
-		target = fmt.Sprintf("[%s]:%d", host, port)
-	}
-	err := exec.Command("ssh-keygen", "-F", target).Run()
-	if err == nil {
-		return true
-	}
-	if _, ok := err.(*exec.ExitError); ok {
-		return false
-	}
-	return true // ssh-keygen unavailable — assume known
-}
@@ -19,6 +19,10 @@ var doctorCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		p, _, err = connector.Resolve(p)
+		if err != nil {
+			return err
+		}
 
 		result, err := tui.RunDoctor(p)
 		if err != nil {
@@ -5,6 +5,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/ainsuotain/sshtie/internal/hostkey"
 	"github.com/ainsuotain/sshtie/internal/profile"
 	"github.com/ainsuotain/sshtie/internal/tui"
 )
@@ -32,41 +33,45 @@ func runEdit(name string) error {
 		if err := profile.Remove(name); err != nil {
 			return err
 		}
+		_ = hostkey.Forget(name)
 		fmt.Printf("✅ Profile '%s' removed.\n", name)
 		syncSSHConfig()
 		return nil
 	}
 
-	profiles, err := profile.Load()
-	if err != nil {
-		return err
-	}
-
 	finalName := name
 	if result.NewName != "" {
-		for _, existing := range profiles {
-			if existing.Name == result.NewName {
-				return fmt.Errorf("profile %q already exists", result.NewName)
-			}
-		}
 		finalName = result.NewName
 	}
 
-	for i, existing := range profiles {
-		if existing.Name != name {
-			continue
+	// Apply the edit and the rename against the latest file in one go under
+	// the profiles.yaml lock, so a concurrent change (e.g. from the tray)
+	// isn't overwritten and a failed rename leaves nothing half-applied.
+	// Rename also rewrites jump chains that point at this profile.
+	err = profile.Edit(name, finalName, func(pr *profile.Profile) error {
+		// Only touch the options the user actually moved, so values the
+		// profile inherits from its group stay inherited.
+		pr.ForwardAgent = result.ForwardAgent
+		if result.ConnectionAttempts != orDefault(pr.ConnectionAttempts, 3) {
+			pr.ConnectionAttempts = result.ConnectionAttempts
 		}
-		profiles[i].Name                = finalName
-		profiles[i].ForwardAgent        = result.ForwardAgent
-		profiles[i].ConnectionAttempts  = result.ConnectionAttempts
-		profiles[i].ServerAliveInterval = result.ServerAliveInterval
-		profiles[i].ServerAliveCountMax = result.ServerAliveCountMax
-		break
-	}
-
-	if err := profile.Save(profiles); err != nil {
+		if result.ServerAliveInterval != orDefault(pr.ServerAliveInterval, 10) {
+			pr.ServerAliveInterval = result.ServerAliveInterval
+		}
+		if result.ServerAliveCountMax != orDefault(pr.ServerAliveCountMax, 60) {
+			pr.ServerAliveCountMax = result.ServerAliveCountMax
+		}
+		if result.ForwardsChanged {
+			pr.Forwards = result.Forwards
+		}
+		return nil
+	})
+	if err != nil {
 		return err
 	}
+	if finalName != name {
+		_ = hostkey.Rename(name, finalName)
+	}
 
 	if finalName != name {
 		fmt.Printf("✅ Renamed '%s' → '%s'\n", name, finalName)
@@ -78,6 +83,14 @@ func runEdit(name string) error {
 	return nil
 }
 
+// orDefault returns v, or def when v is unset (<= 0).
+func orDefault(v, def int) int {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
+
 var editCmd = &cobra.Command{
 	Use:   "edit <name>",
 	Short: "Edit SSH options, rename, or delete a profile (interactive UI)",
@@ -96,6 +109,10 @@ SSH options:
   · ServerAliveCountMax — how many missed pings before disconnect
   · ForwardAgent        — SSH agent forwarding (for bastion hosts)
 
+Port forwards:
+  · type space-separated forwards in ssh syntax, e.g.
+    L5432:localhost:5432  R8080:localhost:3000  D1080
+
 Profile management (bottom section):
   · Rename  — type a new name and press enter
   · Delete  — press enter twice to confirm deletion`,
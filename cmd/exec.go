@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ainsuotain/sshtie/internal/connector"
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+var (
+	execTags        []string
+	execConcurrency int
+	execTimeout     time.Duration
+	execGroup       bool
+	execJSON        bool
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec <name>... | --tag <tag> -- <command>",
+	Short: "Run a command on one or many profiles",
+	Long: `Run a non-interactive command over ssh on one or more profiles, in
+parallel, using each profile's SSH options (key, jump chain, keepalives).
+
+Pick hosts by name, by --tag, or both. Output is prefixed with the profile
+name as it arrives; --group prints each host's output as one block instead.
+A summary of exit codes follows. --json prints machine-readable results.
+
+sshtie exec exits non-zero when any host fails.
+
+Example:
+  sshtie exec web1 -- uptime
+  sshtie exec --tag prod -- systemctl status app
+  sshtie exec --tag prod --concurrency 4 --timeout 30s --json -- df -h /`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		dash := cmd.ArgsLenAtDash()
+		if dash < 0 || dash == len(args) {
+			return fmt.Errorf("missing remote command — put it after --, e.g. sshtie exec web1 -- uptime")
+		}
+		if dash == 0 && len(execTags) == 0 {
+			return fmt.Errorf("name at least one profile or use --tag")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dash := cmd.ArgsLenAtDash()
+		targets, err := execTargets(args[:dash], execTags)
+		if err != nil {
+			return err
+		}
+		remote := args[dash:]
+
+		opts := connector.ExecOptions{Concurrency: execConcurrency, Timeout: execTimeout}
+		if !execJSON && !execGroup {
+			opts.Output = prefixPrinter(targets)
+		}
+		results := connector.ExecAll(targets, remote, opts)
+
+		if execJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(results); err != nil {
+				return err
+			}
+		} else {
+			if execGroup {
+				printGrouped(results)
+			}
+			printExecSummary(results)
+		}
+
+		failed := 0
+		for _, r := range results {
+			if !r.OK() {
+				failed++
+			}
+		}
+		if failed > 0 {
+			// Host failures are results, not a usage mistake.
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d of %d hosts failed", failed, len(results))
+		}
+		return nil
+	},
+}
+
+// execTargets resolves names and tags to profiles, each profile once, in
+// profiles.yaml order.
+func execTargets(names, tags []string) ([]profile.Profile, error) {
+	all, err := profile.Load()
+	if err != nil {
+		return nil, err
+	}
+	want := make(map[string]bool, len(names))
+	for _, n := range names {
+		want[n] = true
+	}
+	found := make(map[string]bool, len(names))
+	var out []profile.Profile
+	for _, p := range all {
+		match := want[p.Name]
+		for _, t := range tags {
+			for _, pt := range p.Tags {
+				if pt == t {
+					match = true
+				}
+			}
+		}
+		if match {
+			out = append(out, p)
+			found[p.Name] = true
+		}
+	}
+	for _, n := range names {
+		if !found[n] {
+			return nil, fmt.Errorf("profile %q not found", n)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no profiles tagged %s", strings.Join(tags, ", "))
+	}
+	return out, nil
+}
+
+// prefixPrinter prints each output line as "name │ line" as it arrives.
+func prefixPrinter(targets []profile.Profile) func(name, stream, line string) {
+	width := 0
+	for _, p := range targets {
+		if len(p.Name) > width {
+			width = len(p.Name)
+		}
+	}
+	var mu sync.Mutex
+	return func(name, stream, line string) {
+		mu.Lock()
+		defer mu.Unlock()
+		out := os.Stdout
+		if stream == "stderr" {
+			out = os.Stderr
+		}
+		fmt.Fprintf(out, "%-*s │ %s\n", width, name, line)
+	}
+}
+
+func printGrouped(results []connector.ExecResult) {
+	for _, r := range results {
+		fmt.Printf("── %s (%s) ──\n", r.Profile, r.Host)
+		fmt.Print(r.Stdout)
+		if r.Stdout != "" && !strings.HasSuffix(r.Stdout, "\n") {
+			fmt.Println()
+		}
+		fmt.Fprint(os.Stderr, r.Stderr)
+		if r.Stderr != "" && !strings.HasSuffix(r.Stderr, "\n") {
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+}
+
+func printExecSummary(results []connector.ExecResult) {
+	fmt.Println()
+	fmt.Printf("  %-16s %-24s %-6s %s\n", "NAME", "HOST", "EXIT", "TIME")
+	fmt.Println("  " + strings.Repeat("─", 64))
+	for _, r := range results {
+		icon := "✅"
+		if !r.OK() {
+			icon = "⚠ "
+		}
+		exit := "—"
+		if r.ExitCode >= 0 {
+			exit = fmt.Sprint(r.ExitCode)
+		}
+		fmt.Printf("  %-16s %-24s %-6s %-8s %s %s\n",
+			r.Profile, r.Host, exit, r.Duration.Round(100*time.Millisecond), icon, r.Error)
+	}
+	fmt.Println()
+}
+
+func init() {
+	execCmd.Flags().StringArrayVar(&execTags, "tag", nil, "run on every profile with this tag (repeatable)")
+	execCmd.Flags().IntVarP(&execConcurrency, "concurrency", "c", 8, "hosts to run at once (0 = all)")
+	execCmd.Flags().DurationVar(&execTimeout, "timeout", 60*time.Second, "per-host timeout (0 = none)")
+	execCmd.Flags().BoolVar(&execGroup, "group", false, "print each host's output as one block instead of prefixing lines")
+	execCmd.Flags().BoolVar(&execJSON, "json", false, "print results as JSON")
+	rootCmd.AddCommand(execCmd)
+}
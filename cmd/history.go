@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ainsuotain/sshtie/internal/history"
+)
+
+var historyLimit int
+
+var historyCmd = &cobra.Command{
+	Use:   "history [name]",
+	Short: "Summarize past connections per profile",
+	Long: `Show what sshtie did on past connections, read from
+~/.sshtie/history.jsonl: how many sessions each profile had, total connected
+time, how often sessions dropped and which method was used most.
+
+With a profile name, also list its most recent events (strategies tried,
+fallbacks, drops and reconnects).
+
+Example:
+  sshtie history
+  sshtie history homeserver --limit 50`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if historyLimit < 0 {
+			return fmt.Errorf("--limit must be 0 or more, got %d", historyLimit)
+		}
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		}
+		events, err := history.Read(name)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			if name != "" {
+				fmt.Printf("No history for '%s' yet.\n", name)
+			} else {
+				fmt.Println("No history yet. Connect with: sshtie connect <name>")
+			}
+			return nil
+		}
+
+		fmt.Println()
+		fmt.Printf("  %-16s %-9s %-7s %-10s %-7s %-10s %s\n",
+			"NAME", "SESSIONS", "FAILED", "TOTAL", "DROPS", "METHOD", "LAST")
+		fmt.Println("  " + strings.Repeat("─", 78))
+		for _, s := range history.Summarize(events) {
+			method := s.Method
+			if method == "" {
+				method = "—"
+			}
+			drops := fmt.Sprintf("%.0f%%", s.DropRate()*100)
+			fmt.Printf("  %-16s %-9d %-7d %-10s %-7s %-10s %s\n",
+				s.Profile, s.Sessions, s.Failed, s.Total.Round(time.Second), drops, method,
+				s.Last.Local().Format("2006-01-02 15:04"))
+		}
+		fmt.Println()
+
+		if name == "" {
+			return nil
+		}
+		events = history.Recent(events, historyLimit)
+		if len(events) == 0 {
+			return nil
+		}
+		fmt.Printf("  Recent events for '%s':\n\n", name)
+		for _, e := range events {
+			fmt.Printf("  %s  %-9s %s\n", e.Time.Local().Format("01-02 15:04:05"), e.Kind, describeEvent(e))
+		}
+		fmt.Println()
+		return nil
+	},
+}
+
+// describeEvent renders the details of one history event.
+func describeEvent(e history.Event) string {
+	secs := func(f float64) time.Duration {
+		return time.Duration(f * float64(time.Second)).Round(time.Second)
+	}
+	switch e.Kind {
+	case history.KindRun:
+		s := fmt.Sprintf("%s ran %s → %s", e.Strategy, secs(e.Duration), e.Outcome)
+		if e.Attempt > 0 {
+			s += fmt.Sprintf(" (reconnect %d)", e.Attempt)
+		}
+		if e.Outcome != history.OutcomeClean && e.Exit != "" {
+			s += " — " + e.Exit
+		}
+		return s
+	case history.KindReconnect:
+		return fmt.Sprintf("%s attempt %d after %s down", e.Strategy, e.Attempt, secs(e.Downtime))
+	default:
+		return fmt.Sprintf("%s: %s", e.Strategy, e.Reason)
+	}
+}
+
+func init() {
+	historyCmd.Flags().IntVar(&historyLimit, "limit", 20, "number of recent events to show with a profile name")
+	rootCmd.AddCommand(historyCmd)
+}
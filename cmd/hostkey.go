@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/ainsuotain/sshtie/internal/connector"
+	"github.com/ainsuotain/sshtie/internal/hostkey"
+	"github.com/ainsuotain/sshtie/internal/probe"
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+var hostkeyYes bool
+
+var hostkeyCmd = &cobra.Command{
+	Use:   "hostkey",
+	Short: "Pin, show or forget the SSH host keys sshtie trusts per profile",
+	Long: `sshtie pins every server's host keys the first time you connect to it:
+their fingerprints go into the profile (host_keys in profiles.yaml) and the
+keys into ~/.sshtie/known_hosts. From then on ssh checks the server strictly
+against those keys — whichever of the profile's addresses it is reached on —
+and a changed key stops the connection with an explanation instead of a
+wall of ssh warnings.
+
+Use pin to capture the keys up front, or to trust new ones after a server
+was reinstalled:
+
+  sshtie hostkey pin homeserver
+  sshtie hostkey show homeserver
+  sshtie hostkey forget homeserver   # back to trust-on-first-use`,
+	Args: cobra.NoArgs,
+}
+
+var hostkeyPinCmd = &cobra.Command{
+	Use:   "pin <name>",
+	Short: "Fetch a server's host keys and pin them",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := profile.Get(args[0])
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		p, _, err = connector.Resolve(p)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("→ Fetching host keys of %s (%s)…\n", p.Name, p.Host)
+		keys, err := probe.HostKeys(p, probe.Options{})
+		if err != nil {
+			return fmt.Errorf("fetch host keys: %w", err)
+		}
+		fmt.Println()
+		printHostKeys(p, keys)
+		fmt.Println()
+
+		port := p.Port
+		if port == 0 {
+			port = 22
+		}
+		addr := net.JoinHostPort(p.Host, strconv.Itoa(port))
+		err = hostkey.Verify(p, addr, keys)
+		if err == nil && !hostkey.Pinned(p) {
+			err = hostkey.KnownElsewhere(p.Name, addr, keys)
+		}
+		var changed *hostkey.ChangedError
+		if errors.As(err, &changed) {
+			fmt.Println(connector.ChangedHelp(changed))
+			fmt.Println()
+			if !hostkeyYes && !confirm("Trust the new keys?") {
+				fmt.Println("→ Cancelled. Nothing pinned.")
+				return nil
+			}
+		}
+
+		if err := hostkey.Pin(p.Name, keys); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Pinned %d host key(s) for %s.\n", len(keys), p.Name)
+		syncSSHConfig()
+		return nil
+	},
+}
+
+var hostkeyShowCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Show pinned host keys (all profiles, or one)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profiles, err := profile.Load()
+		if err != nil {
+			return err
+		}
+		if len(args) == 1 {
+			p, err := profile.Get(args[0])
+			if err != nil {
+				return err
+			}
+			profiles = []profile.Profile{p}
+		}
+
+		fmt.Println()
+		fmt.Printf("  %-16s %-22s %s\n", "NAME", "TYPE", "FINGERPRINT")
+		fmt.Println("  " + strings.Repeat("─", 90))
+		for _, p := range profiles {
+			if !hostkey.Pinned(p) {
+				fmt.Printf("  %-16s %-22s %s\n", p.Name, "─", "not pinned yet (pinned on first connect)")
+				continue
+			}
+			stored, _ := hostkey.Keys(p.Name)
+			types := map[string]string{}
+			for _, k := range stored {
+				types[hostkey.Fingerprint(k)] = k.Type()
+			}
+			for _, fp := range p.HostKeys {
+				typ, ok := types[fp]
+				if !ok {
+					typ = "(not on this machine)"
+				}
+				fmt.Printf("  %-16s %-22s %s\n", p.Name, typ, fp)
+			}
+		}
+		fmt.Println()
+		if path, err := hostkey.Path(); err == nil {
+			fmt.Printf("  Keys: %s\n\n", path)
+		}
+		return nil
+	},
+}
+
+var hostkeyForgetCmd = &cobra.Command{
+	Use:   "forget <name>",
+	Short: "Unpin a profile's host keys (they are pinned again on the next connect)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if _, err := profile.Get(name); err != nil {
+			return err
+		}
+		err := profile.Update(func(ps *[]profile.Profile) error {
+			for i := range *ps {
+				if (*ps)[i].Name == name {
+					(*ps)[i].HostKeys = nil
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if err := hostkey.Forget(name); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Host keys of %s forgotten.\n", name)
+		syncSSHConfig()
+		return nil
+	},
+}
+
+// printHostKeys lists keys with their type and fingerprint, marking the
+// ones p already pins.
+func printHostKeys(p profile.Profile, keys []ssh.PublicKey) {
+	for _, k := range keys {
+		mark := " "
+		if hostkey.Matches(p, k) {
+			mark = "✓"
+		}
+		fmt.Printf("  %s %-22s %s\n", mark, k.Type(), hostkey.Fingerprint(k))
+	}
+}
+
+// confirm asks a yes/no question on stdin; anything but y/yes is no.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func init() {
+	hostkeyPinCmd.Flags().BoolVarP(&hostkeyYes, "yes", "y", false, "trust changed keys without asking")
+	hostkeyCmd.AddCommand(hostkeyPinCmd, hostkeyShowCmd, hostkeyForgetCmd)
+	rootCmd.AddCommand(hostkeyCmd)
+}
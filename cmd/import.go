@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/sshconfig"
+	"github.com/ainsuotain/sshtie/internal/tui"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import profiles from other tools",
+	Args:  cobra.NoArgs,
+}
+
+var importSSHConfigCmd = &cobra.Command{
+	Use:   "ssh-config",
+	Short: "Import Host entries from ~/.ssh/config as profiles",
+	Long: `Read ~/.ssh/config (following Include) and pick which Host entries
+become sshtie profiles.
+
+HostName, User, Port, IdentityFile and ProxyJump are carried over, including
+values set by wildcard blocks such as "Host *". Wildcard-only patterns,
+Match blocks and sshtie's own managed block are skipped. Hosts whose name is
+already a profile can't be imported; hosts pointing at the same address as
+an existing profile are flagged and left unselected.
+
+Your ~/.ssh/config is not modified.
+
+Example:
+  sshtie import ssh-config
+  sshtie import ssh-config --all
+  sshtie import ssh-config --file ~/.ssh/work.conf`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, _ := cmd.Flags().GetString("file")
+		all, _ := cmd.Flags().GetBool("all")
+
+		if file == "" {
+			path, err := sshConfigPath()
+			if err != nil {
+				return err
+			}
+			file = path
+		}
+
+		hosts, err := sshconfig.Parse(file)
+		if err != nil {
+			return fmt.Errorf("read ssh config: %w", err)
+		}
+		if len(hosts) == 0 {
+			fmt.Printf("No importable Host entries in %s\n", file)
+			return nil
+		}
+
+		existing, err := profile.Load()
+		if err != nil {
+			return err
+		}
+		items := importItems(hosts, existing)
+
+		var chosen []int
+		if all {
+			for i, it := range items {
+				if !it.Blocked && it.Note == "" {
+					chosen = append(chosen, i)
+				}
+			}
+		} else {
+			chosen, err = tui.RunImport(items)
+			if err != nil {
+				return err
+			}
+		}
+		if len(chosen) == 0 {
+			fmt.Println("→ Nothing imported.")
+			return nil
+		}
+
+		var imported []profile.Profile
+		err = profile.Update(func(profiles *[]profile.Profile) error {
+			taken := make(map[string]bool, len(*profiles))
+			for _, p := range *profiles {
+				taken[p.Name] = true
+			}
+			for _, i := range chosen {
+				p := items[i].Profile
+				if taken[p.Name] {
+					fmt.Printf("⚠  Skipping '%s': profile already exists\n", p.Name)
+					continue
+				}
+				taken[p.Name] = true
+				*profiles = append(*profiles, p)
+				imported = append(imported, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("✅ Imported %d profile(s) from %s\n", len(imported), file)
+		for _, p := range imported {
+			fmt.Printf("   %-20s → %s@%s:%d\n", p.Name, p.User, p.Host, p.Port)
+		}
+		fmt.Println("→ Try: sshtie list")
+		return nil
+	},
+}
+
+// importItems turns parsed hosts into picker rows, flagging hosts that clash
+// with an existing profile by name (blocked) or by address (warned).
+func importItems(hosts []sshconfig.Host, existing []profile.Profile) []tui.ImportItem {
+	byName := make(map[string]bool, len(existing))
+	byAddr := make(map[string]string, len(existing))
+	for _, p := range existing {
+		byName[p.Name] = true
+		byAddr[importAddrKey(p)] = p.Name
+	}
+
+	items := make([]tui.ImportItem, 0, len(hosts))
+	for _, h := range hosts {
+		p := hostProfile(h)
+		it := tui.ImportItem{Profile: p, Source: h.Source}
+		if byName[p.Name] {
+			it.Blocked = true
+			it.Note = fmt.Sprintf("profile '%s' already exists", p.Name)
+		} else if other, ok := byAddr[importAddrKey(p)]; ok {
+			it.Note = fmt.Sprintf("same host as profile '%s'", other)
+		}
+		items = append(items, it)
+	}
+	return items
+}
+
+// hostProfile converts a Host entry into a profile with the same defaults
+// the add wizard uses.
+func hostProfile(h sshconfig.Host) profile.Profile {
+	port := h.Port
+	if port == 0 {
+		port = 22
+	}
+	return profile.Profile{
+		Name:        h.Alias,
+		Host:        h.HostName,
+		User:        h.User,
+		Port:        port,
+		Key:         h.IdentityFile,
+		TmuxSession: "main",
+		Network:     "auto",
+		Jump:        h.ProxyJump,
+	}
+}
+
+// importAddrKey identifies the account a profile logs into.
+func importAddrKey(p profile.Profile) string {
+	port := p.Port
+	if port == 0 {
+		port = 22
+	}
+	return fmt.Sprintf("%s@%s:%d", p.User, strings.ToLower(p.Host), port)
+}
+
+func init() {
+	importSSHConfigCmd.Flags().String("file", "", "SSH config to read (default ~/.ssh/config)")
+	importSSHConfigCmd.Flags().Bool("all", false, "Import every non-duplicate host without the picker")
+	importCmd.AddCommand(importSSHConfigCmd)
+	rootCmd.AddCommand(importCmd)
+}
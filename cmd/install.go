@@ -7,13 +7,22 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/charmbracelet/x/term"
 	"github.com/spf13/cobra"
 
+	"github.com/ainsuotain/sshtie/internal/cloud"
+	"github.com/ainsuotain/sshtie/internal/connector"
 	"github.com/ainsuotain/sshtie/internal/doctor"
+	"github.com/ainsuotain/sshtie/internal/hostkey"
+	"github.com/ainsuotain/sshtie/internal/locate"
+	"github.com/ainsuotain/sshtie/internal/probe"
 	"github.com/ainsuotain/sshtie/internal/profile"
 )
 
-var installTailscale bool
+var (
+	installTailscale bool
+	installET        bool
+)
 
 var installCmd = &cobra.Command{
 	Use:   "install <name>",
@@ -21,7 +30,14 @@ var installCmd = &cobra.Command{
 	Long: `Connects to the remote server and installs mosh + tmux
 using the appropriate package manager (apt / dnf / yum / brew / pacman).
 
-Use --tailscale to also install Tailscale on the remote server.`,
+A profile with multiplexer: screen or zellij gets that instead of tmux, and
+one with multiplexer: none only gets mosh. zellij comes from Homebrew or
+pacman where available, and otherwise from its static release binary,
+installed into /usr/local/bin.
+
+Use --tailscale to also install Tailscale on the remote server, and --et to
+install Eternal Terminal's etserver (done anyway for profiles with et+tmux
+in their strategies).`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		name := args[0]
@@ -32,12 +48,16 @@ Use --tailscale to also install Tailscale on the remote server.`,
 			fmt.Fprintln(os.Stderr, "→ Run 'sshtie add' to add a new server.")
 			return nil
 		}
+		if p, _, err = locate.Apply(p); err != nil {
+			return err
+		}
 		return runInstall(p)
 	},
 }
 
 func init() {
 	installCmd.Flags().BoolVar(&installTailscale, "tailscale", false, "Also install Tailscale on the remote server")
+	installCmd.Flags().BoolVar(&installET, "et", false, "Also install Eternal Terminal (etserver) on the remote server")
 }
 
 // ── Types ─────────────────────────────────────────────────────────────────────
@@ -45,27 +65,42 @@ func init() {
 type remoteOS struct {
 	display string // e.g. "Ubuntu 22.04 LTS", "macOS 14.2"
 	pkgMgr  string // apt | dnf | yum | brew | pacman | ""
+	ubuntu  bool   // Ubuntu or a derivative, which get PPAs
 }
 
 type pkgStep struct {
-	label  string // printed label, e.g. "tmux..."
-	binary string // checked with `which` on remote
-	pkg    string // package name passed to the package manager
+	label   string // printed label, e.g. "tmux..."
+	binary  string // looked up in the probe's facts
+	pkg     string // package name passed to the package manager
+	command string // install command to run instead ("" = the package manager's)
+	manual  string // where to get it by hand when neither is set
 }
 
 // ── Main install flow ─────────────────────────────────────────────────────────
 
 func runInstall(p profile.Profile) error {
+	mux, err := profile.Multiplexer(p)
+	if err != nil {
+		return err
+	}
 	port := p.Port
 	if port == 0 {
 		port = 22
 	}
 
-	fmt.Printf("\n🔧 Installing mosh-server + tmux on %s (%s)...\n\n", p.Name, p.Host)
+	withET := installET || connector.InChain(p, "et+tmux")
+	tools := []string{"mosh-server"}
+	if mux != "none" {
+		tools = append(tools, mux)
+	}
+	if withET {
+		tools = append(tools, "etserver")
+	}
+	fmt.Printf("\n🔧 Installing %s on %s (%s)...\n\n", strings.Join(tools, " + "), p.Name, p.Host)
 
 	// Step 1: OS detection
 	fmt.Printf("  %-26s", "Detecting OS...")
-	ros, err := detectRemoteOS(p, port)
+	facts, err := probeForInstall(p)
 	if err != nil {
 		fmt.Println("⚠  SSH connection failed — couldn't reach the server.")
 		fmt.Fprintf(os.Stderr, "   error: %v\n", err)
@@ -73,40 +108,45 @@ func runInstall(p profile.Profile) error {
 		fmt.Fprintf(os.Stderr, "   → Edit the profile with: sshtie edit %s\n", p.Name)
 		return nil
 	}
+	ros := detectRemoteOS(facts)
 
 	switch ros.pkgMgr {
 	case "":
 		if ros.display == "macos-no-brew" {
 			fmt.Println("⚠  macOS detected, but Homebrew isn't installed on this server.")
 			fmt.Println()
-			fmt.Println("  mosh and tmux are installed via Homebrew on macOS.")
+			fmt.Println("  mosh and the multiplexer are installed via Homebrew on macOS.")
 			fmt.Println("  Please install Homebrew on the server first, then re-run this command.")
 			fmt.Println("  → https://brew.sh")
 			return nil
 		}
 		fmt.Printf("⚠  Unsupported OS: %s — couldn't detect a known package manager.\n", ros.display)
-		printManualInstallHint()
+		printManualInstallHint(mux)
 		return nil
 	default:
 		fmt.Printf("✅ %s (%s)\n", ros.display, ros.pkgMgr)
 	}
 
-	// Steps 2–3: install tmux and mosh-server
-	steps := []pkgStep{
-		{"tmux...", "tmux", "tmux"},
-		{"mosh-server...", "mosh-server", "mosh"},
+	// Steps 2–3: install the multiplexer and mosh-server
+	var steps []pkgStep
+	if mux != "none" {
+		steps = append(steps, muxStep(mux, ros))
+	}
+	steps = append(steps, pkgStep{label: "mosh-server...", binary: "mosh-server", pkg: "mosh"})
+	if withET {
+		steps = append(steps, pkgStep{label: "etserver...", binary: "etserver", command: etInstallCmd(ros), manual: etDownloadURL})
 	}
 
 	allOK := true
 	for _, step := range steps {
-		if !runPkgStep(p, port, ros, step) {
+		if !runPkgStep(p, port, facts, ros, step) {
 			allOK = false
 		}
 	}
 
 	// Optional: install Tailscale
 	if installTailscale {
-		if !installRemoteTailscale(p, port, ros) {
+		if !installRemoteTailscale(p, port, facts, ros) {
 			allOK = false
 		}
 	}
@@ -124,19 +164,25 @@ func runInstall(p profile.Profile) error {
 	return nil
 }
 
-// runPkgStep checks if binary exists; installs it if not. Returns true on success.
-func runPkgStep(p profile.Profile, port int, ros remoteOS, step pkgStep) bool {
+// runPkgStep installs step's package unless the probe already found its
+// binary. Returns true on success.
+func runPkgStep(p profile.Profile, port int, facts probe.Facts, ros remoteOS, step pkgStep) bool {
 	fmt.Printf("  %-26s", step.label)
 
-	// Already installed?
-	out, _ := remoteCapture(p, port, "which "+step.binary+" 2>/dev/null")
-	if strings.TrimSpace(out) != "" {
+	if facts.Has(step.binary) {
 		fmt.Println("✅ Already installed")
 		return true
 	}
 
 	// Install
-	cmdStr := buildInstallCmd(ros, step.pkg)
+	cmdStr := step.command
+	if cmdStr == "" && step.pkg != "" {
+		cmdStr = buildInstallCmd(ros, step.pkg)
+	}
+	if cmdStr == "" {
+		fmt.Printf("⚠  No package for %s — see %s\n", ros.display, step.manual)
+		return false
+	}
 	fmt.Println("Installing...")
 	if err := remoteInteractive(p, port, cmdStr); err != nil {
 		fmt.Printf("  %-26s⚠  Failed\n", "")
@@ -149,73 +195,101 @@ func runPkgStep(p profile.Profile, port int, ros remoteOS, step pkgStep) bool {
 	return true
 }
 
-// ── OS Detection ──────────────────────────────────────────────────────────────
+// zellijRelease installs zellij's static Linux build, for distributions that
+// don't package it.
+const zellijRelease = "curl -fsSL https://github.com/zellij-org/zellij/releases/latest/download/zellij-$(uname -m)-unknown-linux-musl.tar.gz" +
+	" | sudo tar -xz -C /usr/local/bin zellij"
 
-func detectRemoteOS(p profile.Profile, port int) (remoteOS, error) {
-	// Single round-trip: /etc/os-release covers Linux; uname -s detects Darwin.
-	out, err := remoteCapture(p, port, "cat /etc/os-release 2>/dev/null; uname -s 2>/dev/null")
-	if err != nil {
-		return remoteOS{}, err
+// muxStep is the install step for the profile's multiplexer.
+func muxStep(mux string, ros remoteOS) pkgStep {
+	step := pkgStep{label: mux + "...", binary: mux, pkg: mux}
+	if mux == "zellij" && ros.pkgMgr != "brew" && ros.pkgMgr != "pacman" {
+		step.command = zellijRelease
 	}
+	return step
+}
 
-	kvs := make(map[string]string)
-	var uname string
-	for _, line := range strings.Split(out, "\n") {
-		line = strings.TrimSpace(line)
-		switch line {
-		case "Darwin", "Linux", "FreeBSD":
-			uname = line
-			continue
-		}
-		if parts := strings.SplitN(line, "=", 2); len(parts) == 2 {
-			k := strings.TrimSpace(parts[0])
-			v := strings.Trim(strings.TrimSpace(parts[1]), `"`)
-			kvs[k] = v
+// etDownloadURL lists Eternal Terminal's packages for every platform.
+const etDownloadURL = "https://eternalterminal.dev/download/"
+
+// etInstallCmd installs Eternal Terminal from its project's own
+// repositories and starts etserver. "" means there's no package for ros.
+func etInstallCmd(ros remoteOS) string {
+	const start = " && sudo systemctl enable --now et"
+	switch ros.pkgMgr {
+	case "apt":
+		if ros.ubuntu {
+			return "sudo add-apt-repository -y ppa:jgmath2000/et && sudo apt-get update && sudo apt-get install -y et" + start
 		}
+		return "sudo mkdir -p /etc/apt/keyrings" +
+			" && curl -fsSL https://github.com/MisterTea/debian-et/raw/master/et.gpg | sudo tee /etc/apt/keyrings/et.gpg >/dev/null" +
+			` && echo "deb [signed-by=/etc/apt/keyrings/et.gpg] https://mistertea.github.io/debian-et/debian-source/ $(. /etc/os-release && echo $VERSION_CODENAME) main"` +
+			" | sudo tee /etc/apt/sources.list.d/et.list >/dev/null" +
+			" && sudo apt-get update && sudo apt-get install -y et" + start
+	case "dnf":
+		return "sudo dnf install -y et" + start
+	case "yum":
+		return "sudo yum install -y epel-release && sudo yum install -y et" + start
+	case "brew":
+		return "brew install MisterTea/et/et && sudo brew services start MisterTea/et/et"
 	}
+	return ""
+}
 
-	id := strings.ToLower(kvs["ID"])
-	idLike := strings.ToLower(kvs["ID_LIKE"])
-	pretty := kvs["PRETTY_NAME"]
-	if pretty == "" {
-		pretty = id
+// ── OS Detection ──────────────────────────────────────────────────────────────
+
+// probeForInstall logs in once and collects the server's facts. Unlike the
+// background checks it may ask for a password, since install is run by hand
+// on servers that often don't have a key set up yet.
+func probeForInstall(p profile.Profile) (probe.Facts, error) {
+	opts := probe.Options{}
+	if term.IsTerminal(os.Stdin.Fd()) {
+		opts.Password = func(prompt string) (string, error) {
+			fmt.Printf("\n  %s", prompt)
+			pw, err := term.ReadPassword(os.Stdin.Fd())
+			fmt.Printf("\n  %-26s", "")
+			return string(pw), err
+		}
 	}
+	return probe.RunWith(p, opts)
+}
+
+// detectRemoteOS picks the package manager from the probe's facts.
+func detectRemoteOS(f probe.Facts) remoteOS {
+	id := strings.ToLower(f.OSRelease["ID"])
+	idLike := strings.ToLower(f.OSRelease["ID_LIKE"])
+	pretty := f.OSName()
 
 	// macOS: /etc/os-release doesn't exist, so id is empty and uname is Darwin.
-	if uname == "Darwin" && id == "" {
-		brewPath, _ := remoteCapture(p, port, "which brew 2>/dev/null")
-		if strings.TrimSpace(brewPath) == "" {
-			return remoteOS{display: "macos-no-brew"}, nil
+	if f.OS == "Darwin" && id == "" {
+		if !f.Has("brew") {
+			return remoteOS{display: "macos-no-brew"}
 		}
-		macVer, _ := remoteCapture(p, port, "sw_vers -productVersion 2>/dev/null")
-		return remoteOS{
-			display: fmt.Sprintf("macOS %s", strings.TrimSpace(macVer)),
-			pkgMgr:  "brew",
-		}, nil
+		return remoteOS{display: pretty, pkgMgr: "brew"}
 	}
 
 	switch {
 	case id == "ubuntu" || id == "debian" ||
 		strings.Contains(idLike, "ubuntu") || strings.Contains(idLike, "debian"):
-		return remoteOS{display: pretty, pkgMgr: "apt"}, nil
+		ubuntu := id == "ubuntu" || strings.Contains(idLike, "ubuntu")
+		return remoteOS{display: pretty, pkgMgr: "apt", ubuntu: ubuntu}
 
 	case id == "fedora" || strings.Contains(idLike, "fedora"):
-		return remoteOS{display: pretty, pkgMgr: "dnf"}, nil
+		return remoteOS{display: pretty, pkgMgr: "dnf"}
 
 	case id == "centos" || id == "rhel" || id == "almalinux" || id == "rocky" ||
 		strings.Contains(idLike, "rhel") || strings.Contains(idLike, "centos"):
 		// Prefer dnf (el8+) over yum (el7).
-		dnfPath, _ := remoteCapture(p, port, "which dnf 2>/dev/null")
-		if strings.TrimSpace(dnfPath) != "" {
-			return remoteOS{display: pretty, pkgMgr: "dnf"}, nil
+		if f.Has("dnf") {
+			return remoteOS{display: pretty, pkgMgr: "dnf"}
 		}
-		return remoteOS{display: pretty, pkgMgr: "yum"}, nil
+		return remoteOS{display: pretty, pkgMgr: "yum"}
 
 	case id == "arch" || id == "manjaro" || strings.Contains(idLike, "arch"):
-		return remoteOS{display: pretty, pkgMgr: "pacman"}, nil
+		return remoteOS{display: pretty, pkgMgr: "pacman"}
 
 	default:
-		return remoteOS{display: pretty, pkgMgr: ""}, nil
+		return remoteOS{display: pretty, pkgMgr: ""}
 	}
 }
 
@@ -238,26 +312,36 @@ func buildInstallCmd(ros remoteOS, pkg string) string {
 	}
 }
 
-func printManualInstallHint() {
+func printManualInstallHint(mux string) {
+	// pkgs is for distributions that package the multiplexer; native for
+	// pacman and Homebrew, which also carry zellij.
+	pkgs, native := mux+" mosh", mux+" mosh"
+	switch mux {
+	case "none":
+		pkgs, native = "mosh", "mosh"
+	case "zellij":
+		pkgs = "mosh"
+	}
 	fmt.Println()
 	fmt.Println("  → Install manually:")
-	fmt.Println("    Ubuntu/Debian : sudo apt-get install -y tmux mosh")
-	fmt.Println("    CentOS/RHEL   : sudo yum install -y tmux mosh")
-	fmt.Println("    Fedora        : sudo dnf install -y tmux mosh")
-	fmt.Println("    Arch          : sudo pacman -S --noconfirm tmux mosh")
-	fmt.Println("    macOS         : brew install tmux mosh")
+	fmt.Printf("    Ubuntu/Debian : sudo apt-get install -y %s\n", pkgs)
+	fmt.Printf("    CentOS/RHEL   : sudo yum install -y %s\n", pkgs)
+	fmt.Printf("    Fedora        : sudo dnf install -y %s\n", pkgs)
+	fmt.Printf("    Arch          : sudo pacman -S --noconfirm %s\n", native)
+	fmt.Printf("    macOS         : brew install %s\n", native)
+	if mux == "zellij" {
+		fmt.Println("    zellij (Linux): " + zellijRelease)
+	}
 }
 
 // ── Tailscale installer ───────────────────────────────────────────────────────
 
 // installRemoteTailscale installs Tailscale on the remote server and prints
 // instructions for authenticating. Returns true on success.
-func installRemoteTailscale(p profile.Profile, port int, ros remoteOS) bool {
+func installRemoteTailscale(p profile.Profile, port int, facts probe.Facts, ros remoteOS) bool {
 	fmt.Printf("  %-26s", "tailscale...")
 
-	// Already installed?
-	out, _ := remoteCapture(p, port, "which tailscale 2>/dev/null")
-	if strings.TrimSpace(out) != "" {
+	if facts.Has("tailscale") {
 		fmt.Println("✅ Already installed")
 		printTailscaleAuthHint(p.Name)
 		return true
@@ -294,18 +378,6 @@ func printTailscaleAuthHint(profileName string) {
 
 // ── SSH helpers ───────────────────────────────────────────────────────────────
 
-// remoteCapture runs a command non-interactively and returns stdout.
-// Stdin and Stderr are forwarded so password prompts appear on the terminal.
-func remoteCapture(p profile.Profile, port int, command string) (string, error) {
-	args := installSSHArgs(p, port, false)
-	args = append(args, command)
-	cmd := exec.Command("ssh", args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stderr = os.Stderr
-	out, err := cmd.Output()
-	return strings.TrimSpace(string(out)), err
-}
-
 // remoteInteractive runs a command with a PTY allocated, allowing sudo prompts.
 func remoteInteractive(p profile.Profile, port int, command string) error {
 	args := installSSHArgs(p, port, true)
@@ -318,15 +390,18 @@ func remoteInteractive(p profile.Profile, port int, command string) error {
 }
 
 func installSSHArgs(p profile.Profile, port int, interactive bool) []string {
-	args := []string{
-		"-p", strconv.Itoa(port),
-		"-o", "StrictHostKeyChecking=accept-new",
+	args := append([]string{"-p", strconv.Itoa(port)}, hostkey.SSHOptions(p)...)
+	args = append(args,
 		"-o", "ConnectTimeout=10",
 		// No BatchMode — allows password authentication on servers without SSH keys.
-	}
+	)
 	if interactive {
 		args = append(args, "-t") // PTY for sudo / password prompts
 	}
+	args = append(args, connector.JumpArgs(p)...)
+	if p.Transport.Enabled() {
+		args = append(args, "-o", "ProxyCommand="+cloud.ProxyCommand(p.Transport))
+	}
 	key := p.DefaultKey()
 	if _, err := os.Stat(key); err == nil {
 		args = append(args, "-i", key)
@@ -0,0 +1,301 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+
+	"github.com/ainsuotain/sshtie/internal/connector"
+	"github.com/ainsuotain/sshtie/internal/probe"
+	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/sshkey"
+)
+
+var keyCmd = &cobra.Command{
+	Use:   "key",
+	Short: "Generate, install and rotate a profile's SSH key",
+	Long: `Give every profile its own ed25519 key instead of sharing one everywhere.
+
+  sshtie key gen homeserver      # new key at ~/.ssh/sshtie_homeserver, set on the profile
+  sshtie key push homeserver     # add it to the server's authorized_keys (asks for the password once)
+  sshtie key rotate homeserver   # new key in, old key out, profile updated
+
+push is safe to repeat: a key that is already authorized isn't added twice.
+rotate only removes the old key from the server after a login with the new
+one has worked.`,
+	Args: cobra.NoArgs,
+}
+
+var keyGenCmd = &cobra.Command{
+	Use:   "gen <name>",
+	Short: "Generate an ed25519 key for a profile and point the profile at it",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := profile.Get(args[0])
+		if err != nil {
+			return err
+		}
+		path, err := sshkey.Path(p.Name)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists — use: sshtie key rotate %s", sshkey.Display(path), p.Name)
+		}
+		if err := genKey(p.Name, path); err != nil {
+			return err
+		}
+		fmt.Printf("→ Next: sshtie key push %s\n", p.Name)
+		return nil
+	},
+}
+
+var keyPushCmd = &cobra.Command{
+	Use:   "push <name>",
+	Short: "Add a profile's public key to the server's authorized_keys",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := profile.Get(args[0])
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		return pushKey(p)
+	},
+}
+
+var keyRotateCmd = &cobra.Command{
+	Use:   "rotate <name>",
+	Short: "Replace a profile's key with a new one, on the server too",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := profile.Get(args[0])
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		return rotateKey(p)
+	},
+}
+
+// genKey generates a key at path for the named profile and sets it as the
+// profile's key.
+func genKey(name, path string) error {
+	k, err := sshkey.Generate(path, sshkey.Comment(name))
+	if err != nil {
+		return err
+	}
+	if err := setProfileKey(name, path); err != nil {
+		return err
+	}
+	fmt.Printf("✅ New ed25519 key for %s: %s\n", name, sshkey.Display(path))
+	fmt.Printf("   %s\n", sshkey.AuthorizedLine(k, sshkey.Comment(name)))
+	return nil
+}
+
+// pushKey installs p's public key on the server, logging in with whatever
+// works (a password is asked for when nothing else does), then checks that
+// the key alone now logs in.
+func pushKey(p profile.Profile) error {
+	p, _, err := connector.Resolve(p)
+	if err != nil {
+		return err
+	}
+	path := p.DefaultKey()
+	k, comment, err := sshkey.PublicKey(path)
+	if err != nil {
+		return fmt.Errorf("no key to push (%w) — create one with: sshtie key gen %s", err, p.Name)
+	}
+	if comment == "" {
+		comment = sshkey.Comment(p.Name)
+	}
+
+	fmt.Printf("→ Adding %s to %s@%s:~/.ssh/authorized_keys\n", sshkey.Display(path), p.User, p.Host)
+	added, err := sshkey.Push(p, passwordOptions(), k, comment)
+	if err != nil {
+		return fmt.Errorf("push key: %w", err)
+	}
+	if added {
+		fmt.Println("✅ Key added.")
+	} else {
+		fmt.Println("✅ Key was already authorized — nothing changed.")
+	}
+
+	if err := sshkey.Works(p, path); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠  The key is on the server but logging in with it failed: %v\n", err)
+		fmt.Fprintln(os.Stderr, "   Check the server's sshd_config (PubkeyAuthentication) and the permissions of ~/.ssh.")
+		return nil
+	}
+	fmt.Printf("✅ Login with the key works — try: sshtie connect %s\n", p.Name)
+	return nil
+}
+
+// rotateKey puts a fresh key on the server, proves it logs in, takes the
+// old key off the server and points the profile at the new one. The old
+// key stays in authorized_keys when other profiles for the same account
+// still use it.
+func rotateKey(p profile.Profile) error {
+	p, _, err := connector.Resolve(p)
+	if err != nil {
+		return err
+	}
+	oldPath := p.DefaultKey()
+	oldKey, _, oldErr := sshkey.PublicKey(oldPath)
+
+	target, err := sshkey.Path(p.Name)
+	if err != nil {
+		return err
+	}
+	tmp := target + ".new"
+	sshkey.Delete(tmp) // left over from an interrupted rotate
+	k, err := sshkey.Generate(tmp, sshkey.Comment(p.Name))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ New ed25519 key generated.\n")
+
+	// 1. Install the new key, logging in the usual way.
+	fmt.Printf("→ Adding it to %s@%s:~/.ssh/authorized_keys\n", p.User, p.Host)
+	if _, err := sshkey.Push(p, passwordOptions(), k, sshkey.Comment(p.Name)); err != nil {
+		sshkey.Delete(tmp)
+		return fmt.Errorf("push new key: %w — nothing changed", err)
+	}
+
+	// 2. Prove it works on its own before touching the old one.
+	if err := sshkey.Works(p, tmp); err != nil {
+		_, _ = sshkey.Revoke(p, passwordOptions(), k)
+		sshkey.Delete(tmp)
+		return fmt.Errorf("login with the new key failed (%w) — kept the old key", err)
+	}
+	fmt.Println("✅ Login with the new key works.")
+
+	// 3. Take the old key off the server, logged in with the new one.
+	switch sharing := sharedKeyUsers(p, oldPath); {
+	case oldErr != nil:
+		fmt.Printf("⚠  Couldn't read the old key's public half (%v) — remove it from authorized_keys by hand.\n", oldErr)
+	case sshkey.AuthorizedLine(oldKey, "") == sshkey.AuthorizedLine(k, ""):
+	case len(sharing) > 0:
+		fmt.Printf("→ Old key kept on the server: %s log in with it too.\n", strings.Join(sharing, ", "))
+	default:
+		n, err := sshkey.Revoke(p, probe.Options{Identity: tmp}, oldKey)
+		if err != nil {
+			fmt.Printf("⚠  Couldn't remove the old key from the server: %v\n", err)
+		} else if n > 0 {
+			fmt.Println("✅ Old key removed from the server.")
+		} else {
+			fmt.Println("→ The old key wasn't in authorized_keys (agent or other login).")
+		}
+	}
+
+	// 4. Swap the files and point the profile at the new key.
+	if _, err := os.Stat(target); err == nil {
+		if err := sshkey.Move(target, target+".old"); err != nil {
+			return err
+		}
+		fmt.Printf("→ Previous key kept locally as %s\n", sshkey.Display(target+".old"))
+	}
+	if err := sshkey.Move(tmp, target); err != nil {
+		return err
+	}
+	if err := setProfileKey(p.Name, target); err != nil {
+		return err
+	}
+	fmt.Printf("✅ %s now uses %s\n", p.Name, sshkey.Display(target))
+	syncSSHConfig()
+	return nil
+}
+
+// offerKeySetup runs after sshtie add: when the new profile's key file
+// doesn't exist, it offers to generate a key and push it. It reports
+// whether it took over the closing hint.
+func offerKeySetup(name string) bool {
+	p, err := profile.Get(name)
+	if err != nil || !term.IsTerminal(os.Stdin.Fd()) {
+		return false
+	}
+	if _, err := os.Stat(p.DefaultKey()); err == nil {
+		return false
+	}
+	fmt.Printf("\n⚠  %s doesn't exist.\n", sshkey.Display(p.DefaultKey()))
+	if !confirm("Generate a key for this profile and install it on the server now?") {
+		fmt.Printf("→ Later: sshtie key gen %s && sshtie key push %s\n", p.Name, p.Name)
+		return true
+	}
+	path, err := sshkey.Path(p.Name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠  %v\n", err)
+		return true
+	}
+	if _, err := os.Stat(path); err == nil {
+		// A key from an earlier profile of the same name: reuse it.
+		if err := setProfileKey(p.Name, path); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠  %v\n", err)
+			return true
+		}
+		fmt.Printf("→ Using existing %s\n", sshkey.Display(path))
+	} else if err := genKey(p.Name, path); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠  %v\n", err)
+		return true
+	}
+	syncSSHConfig()
+	if p, err = profile.Get(p.Name); err == nil {
+		err = pushKey(p)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠  %v\n", err)
+		fmt.Printf("→ Retry with: sshtie key push %s\n", p.Name)
+	}
+	return true
+}
+
+// setProfileKey points the named profile at the key at path.
+func setProfileKey(name, path string) error {
+	return profile.Update(func(ps *[]profile.Profile) error {
+		for i := range *ps {
+			if (*ps)[i].Name == name {
+				(*ps)[i].Key = sshkey.Display(path)
+				return nil
+			}
+		}
+		return fmt.Errorf("profile %q not found", name)
+	})
+}
+
+// sharedKeyUsers lists the other profiles that log in to the same account
+// as p with the key at path.
+func sharedKeyUsers(p profile.Profile, path string) []string {
+	all, err := profile.Load()
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, q := range all {
+		if q.Name != p.Name && q.Host == p.Host && q.User == p.User && q.DefaultKey() == path {
+			out = append(out, q.Name)
+		}
+	}
+	return out
+}
+
+// passwordOptions lets a login fall back to a password typed on the
+// terminal, for servers that don't have a key installed yet.
+func passwordOptions() probe.Options {
+	opts := probe.Options{}
+	if term.IsTerminal(os.Stdin.Fd()) {
+		opts.Password = func(prompt string) (string, error) {
+			fmt.Printf("  %s", prompt)
+			pw, err := term.ReadPassword(os.Stdin.Fd())
+			fmt.Println()
+			return string(pw), err
+		}
+	}
+	return opts
+}
+
+func init() {
+	keyCmd.AddCommand(keyGenCmd, keyPushCmd, keyRotateCmd)
+	rootCmd.AddCommand(keyCmd)
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ainsuotain/sshtie/internal/connector"
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+var (
+	layoutSession string
+	layoutYes     bool
+)
+
+var layoutCmd = &cobra.Command{
+	Use:   "layout",
+	Short: "Capture or clear the tmux windows a profile's new sessions start with",
+	Long: `A profile can declare a layout: named tmux windows, each split into panes
+with a working directory and a startup command. sshtie builds it when it
+creates the profile's tmux session — with mosh+tmux or ssh+tmux — and
+leaves an existing session alone when re-attaching.
+
+Rather than writing it by hand, arrange a session the way you like and
+capture it:
+
+  sshtie layout capture homeserver             # the profile's tmux_session
+  sshtie layout capture homeserver --session api
+  sshtie layout clear homeserver
+
+Capturing records each window's name and pane geometry, every pane's
+directory, and the program running in it (without its arguments — edit
+command: in profiles.yaml to add them).`,
+	Args: cobra.NoArgs,
+}
+
+var layoutCaptureCmd = &cobra.Command{
+	Use:   "capture <name>",
+	Short: "Save a running tmux session's windows and panes as the profile's layout",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := profile.Get(args[0])
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		session := layoutSession
+		if session == "" {
+			session = p.TmuxSession
+		}
+		if session == "" {
+			session = "main"
+		}
+		resolved, _, err := connector.Resolve(p)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("→ Reading tmux session %s on %s…\n", session, p.Name)
+		layout, err := connector.CaptureLayout(resolved, session)
+		if err != nil {
+			return err
+		}
+		fmt.Println()
+		printLayout(layout)
+		fmt.Println()
+
+		if len(p.Layout) > 0 && !layoutYes && !confirm(fmt.Sprintf("Replace %s's current layout (%d windows)?", p.Name, len(p.Layout))) {
+			fmt.Println("→ Cancelled. Layout unchanged.")
+			return nil
+		}
+		if err := setProfileLayout(p.Name, layout); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Saved. New %s sessions on %s start with these windows.\n", session, p.Name)
+		return nil
+	},
+}
+
+var layoutClearCmd = &cobra.Command{
+	Use:   "clear <name>",
+	Short: "Remove a profile's layout (new sessions start with one shell)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := profile.Get(args[0])
+		if err != nil {
+			return err
+		}
+		if len(p.Layout) == 0 {
+			fmt.Printf("%s has no layout.\n", p.Name)
+			return nil
+		}
+		if err := setProfileLayout(p.Name, nil); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Layout of %s removed.\n", p.Name)
+		return nil
+	},
+}
+
+// setProfileLayout replaces the named profile's layout.
+func setProfileLayout(name string, layout []profile.Window) error {
+	return profile.Update(func(ps *[]profile.Profile) error {
+		for i := range *ps {
+			if (*ps)[i].Name == name {
+				(*ps)[i].Layout = layout
+				return nil
+			}
+		}
+		return fmt.Errorf("profile %q not found", name)
+	})
+}
+
+// printLayout lists a layout's windows with their directory and what runs
+// in each pane.
+func printLayout(layout []profile.Window) {
+	for i, w := range layout {
+		name := w.Name
+		if name == "" {
+			name = "(unnamed)"
+		}
+		var panes []string
+		for _, p := range w.Panes {
+			what := p.Command
+			if what == "" {
+				what = "shell"
+			}
+			if p.Dir != "" {
+				what += " in " + p.Dir
+			}
+			panes = append(panes, what)
+		}
+		if len(panes) == 0 {
+			panes = []string{"shell"}
+		}
+		dir := w.Dir
+		if dir == "" {
+			dir = "—"
+		}
+		fmt.Printf("  %d. %-14s %-20s %s\n", i+1, name, dir, strings.Join(panes, " │ "))
+	}
+}
+
+func init() {
+	layoutCaptureCmd.Flags().StringVar(&layoutSession, "session", "", "tmux session to capture (default: the profile's tmux_session)")
+	layoutCaptureCmd.Flags().BoolVarP(&layoutYes, "yes", "y", false, "replace an existing layout without asking")
+	layoutCmd.AddCommand(layoutCaptureCmd, layoutClearCmd)
+	rootCmd.AddCommand(layoutCmd)
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ainsuotain/sshtie/internal/learned"
+	"github.com/ainsuotain/sshtie/internal/netenv"
+)
+
+var learnedHere bool
+
+var learnedCmd = &cobra.Command{
+	Use:   "learned",
+	Short: "Show or clear strategies sshtie skips on particular networks",
+	Long: `When a strategy fails on a network and a later one works (mosh on an
+office network that drops UDP, say), sshtie remembers it and skips that
+strategy the next time you connect from the same network. Networks are
+told apart by default gateway, local subnet and whether Tailscale is
+running.
+
+Failures are forgotten after remember_failures (default 12h, set per profile
+or at the top of profiles.yaml; negative turns this off), as soon as the
+strategy works again, or with sshtie learned clear. A single connect can
+ignore them with sshtie connect --retry-all.
+
+Example:
+  sshtie learned list
+  sshtie learned clear homeserver --here`,
+	Args: cobra.NoArgs,
+}
+
+var learnedListCmd = &cobra.Command{
+	Use:   "list [name]",
+	Short: "List remembered failures",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		}
+		entries, err := learned.List(name)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			fmt.Println("Nothing learned yet.")
+			return nil
+		}
+
+		here := netenv.Current().ID()
+		fmt.Println()
+		fmt.Printf("  %-16s %-10s %-10s %-36s %s\n", "NAME", "STRATEGY", "AGE", "NETWORK", "REASON")
+		fmt.Println("  " + strings.Repeat("─", 96))
+		for _, e := range entries {
+			network := e.Describe
+			if e.Network == here {
+				network += " (here)"
+			}
+			age := time.Since(e.Last).Round(time.Minute)
+			fmt.Printf("  %-16s %-10s %-10s %-36s %s\n", e.Profile, e.Strategy, age, network, e.Reason)
+		}
+		fmt.Println()
+		return nil
+	},
+}
+
+var learnedClearCmd = &cobra.Command{
+	Use:   "clear [name]",
+	Short: "Forget remembered failures (all profiles, or one)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		}
+		var n *netenv.Network
+		if learnedHere {
+			cur := netenv.Current()
+			n = &cur
+		}
+		removed, err := learned.Clear(name, n)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ Forgot %d remembered failure(s).\n", removed)
+		return nil
+	},
+}
+
+func init() {
+	learnedClearCmd.Flags().BoolVar(&learnedHere, "here", false, "only forget failures on the current network")
+	learnedCmd.AddCommand(learnedListCmd, learnedClearCmd)
+	rootCmd.AddCommand(learnedCmd)
+}
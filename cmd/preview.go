@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ainsuotain/sshtie/internal/connector"
+	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/shell"
+)
+
+var (
+	cmdAll      bool
+	cmdStrategy string
+)
+
+var cmdCmd = &cobra.Command{
+	Use:   "cmd <name>",
+	Short: "Print the ssh/mosh command sshtie would run",
+	Long: `Print the exact command line sshtie would execute for a profile,
+shell-quoted so it can be pasted into a terminal.
+
+By default this is the first strategy that passes its reachability probe.
+--all prints every strategy in the fallback order; --strategy picks one
+without probing, by its profiles.yaml name or as shown (ssh+screen).
+
+Example:
+  sshtie cmd homeserver
+  sshtie cmd homeserver --strategy ssh
+  sshtie cmd homeserver --all`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := profile.Get(args[0])
+		if err != nil {
+			return err
+		}
+		pv, err := connector.NewPreview(p, cmdStrategy == "" && !cmdAll)
+		if err != nil {
+			return err
+		}
+
+		if cmdAll {
+			for _, st := range pv.Steps {
+				printStepCommand(st, true)
+			}
+			return nil
+		}
+		for _, st := range pv.Steps {
+			if cmdStrategy != "" && st.Name != cmdStrategy && st.Label != cmdStrategy {
+				continue
+			}
+			if cmdStrategy == "" && !st.Viable() {
+				continue
+			}
+			if st.Err != nil {
+				return fmt.Errorf("%s: %w", st.Label, st.Err)
+			}
+			printStepCommand(st, false)
+			return nil
+		}
+		if cmdStrategy != "" {
+			return fmt.Errorf("strategy %q is not in %s's chain (%s)", cmdStrategy, p.Name, stepNames(pv))
+		}
+		return fmt.Errorf("no strategy is viable for %q right now — see: sshtie connect --dry-run %s", p.Name, p.Name)
+	},
+}
+
+// printStepCommand prints a step's helper processes and session command,
+// one shell line each. With header set, a "# name" comment precedes them.
+func printStepCommand(st connector.PreviewStep, header bool) {
+	if header {
+		fmt.Printf("# %s\n", st.Label)
+	}
+	if st.Err != nil {
+		fmt.Printf("# unavailable: %v\n", st.Err)
+		return
+	}
+	for _, aux := range st.Auxiliary {
+		fmt.Println(shell.Join(aux) + " &")
+	}
+	fmt.Println(shell.Join(st.Argv))
+}
+
+// printDryRun prints the full connect plan for `connect --dry-run`.
+func printDryRun(p profile.Profile, pv *connector.Preview) {
+	port := p.Port
+	if port == 0 {
+		port = 22
+	}
+	fmt.Printf("\nDry run for '%s' (%s@%s · port %d)\n\n", p.Name, p.User, p.Host, port)
+
+	network := pv.Network
+	switch {
+	case pv.RouteErr != nil:
+		network += " — ⚠ " + pv.RouteErr.Error()
+	case pv.Route != "":
+		network += " — " + pv.Route
+	}
+	if pv.Address != "" {
+		fmt.Printf("  address:  %s\n", pv.Address)
+	}
+	fmt.Printf("  network:  %s\n", network)
+	if pv.Jump != "" {
+		fmt.Printf("  jump:     %s\n", pv.Jump)
+	}
+	fmt.Println()
+
+	chosen := false
+	for i, st := range pv.Steps {
+		status := ""
+		switch {
+		case st.Skip != "":
+			status = "skipped: " + st.Skip
+		case st.Err != nil:
+			status = "skipped: " + st.Err.Error()
+		case !chosen:
+			status = "← would connect with this"
+			chosen = true
+		default:
+			status = "fallback if the above fails at startup"
+		}
+		fmt.Printf("  %d. %-11s %s\n", i+1, st.Label, status)
+		if st.Err == nil {
+			for _, aux := range st.Auxiliary {
+				fmt.Printf("       $ %s &\n", shell.Join(aux))
+			}
+			fmt.Printf("       $ %s\n", shell.Join(st.Argv))
+		}
+	}
+	fmt.Println()
+	if pv.RouteErr != nil {
+		fmt.Println("⚠  Connect would stop before trying any strategy.")
+	} else if !chosen {
+		fmt.Println("⚠  No strategy is viable right now.")
+	}
+}
+
+func stepNames(pv *connector.Preview) string {
+	names := make([]string, len(pv.Steps))
+	for i, st := range pv.Steps {
+		names[i] = st.Label
+	}
+	return strings.Join(names, " → ")
+}
+
+func init() {
+	cmdCmd.Flags().BoolVar(&cmdAll, "all", false, "print the command of every strategy in fallback order")
+	cmdCmd.Flags().StringVar(&cmdStrategy, "strategy", "", "print the command of this strategy")
+	rootCmd.AddCommand(cmdCmd)
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/ainsuotain/sshtie/internal/hostkey"
 	"github.com/ainsuotain/sshtie/internal/profile"
 )
 
@@ -18,6 +19,7 @@ var removeCmd = &cobra.Command{
 		if err := profile.Remove(name); err != nil {
 			return err
 		}
+		_ = hostkey.Forget(name)
 		fmt.Printf("✅ Profile '%s' removed.\n", name)
 		syncSSHConfig()
 		return nil
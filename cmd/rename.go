@@ -8,6 +8,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/ainsuotain/sshtie/internal/hostkey"
 	"github.com/ainsuotain/sshtie/internal/profile"
 )
 
@@ -40,6 +41,7 @@ var renameCmd = &cobra.Command{
 		if err := profile.Rename(oldName, newName); err != nil {
 			return err
 		}
+		_ = hostkey.Rename(oldName, newName)
 		fmt.Printf("✅ Renamed '%s' → '%s'\n", oldName, newName)
 		syncSSHConfig()
 		return nil
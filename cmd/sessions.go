@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+
+	"github.com/ainsuotain/sshtie/internal/connector"
+	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/tmux"
+	"github.com/ainsuotain/sshtie/internal/tui"
+)
+
+var sessionsList bool
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions <name>",
+	Short: "List, attach, create, rename or kill a server's tmux sessions",
+	Long: `Show the tmux sessions running on a profile's server — windows, attached
+clients and last activity — and manage them:
+
+  enter  attach (connects with the profile's usual strategies)
+  n      start a new detached session
+  r      rename the selected session
+  x      kill the selected session
+
+The profile's own tmux_session is marked ★. sshtie connect offers the same
+list when a server has more than one session; sshtie connect --session NAME
+goes straight to one.
+
+The list is read over a native SSH login (agent or the profile's key), so
+password-only servers aren't supported here.
+
+Example:
+  sshtie sessions homeserver
+  sshtie sessions homeserver --list`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := profile.Get(args[0])
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		saved := p
+		if p, _, err = connector.Resolve(p); err != nil {
+			return err
+		}
+
+		if sessionsList || !term.IsTerminal(os.Stdout.Fd()) {
+			ss, err := connector.TmuxSessions(p)
+			if err != nil {
+				return err
+			}
+			printSessions(p, ss)
+			return nil
+		}
+
+		name, err := tui.RunSessions(p)
+		if err != nil || name == "" {
+			return err
+		}
+		p.TmuxSession = name
+		fmt.Printf("→ Attaching to %s on %s (%s@%s)…\n", name, p.Name, p.User, p.Host)
+		return connector.ConnectWith(p, connector.ConnectOptions{Unresolved: saved})
+	},
+}
+
+// printSessions writes ss as a table.
+func printSessions(p profile.Profile, ss []tmux.Session) {
+	if len(ss) == 0 {
+		fmt.Printf("No tmux sessions running on %s.\n", p.Name)
+		return
+	}
+	own := p.TmuxSession
+	if own == "" {
+		own = "main"
+	}
+	fmt.Println()
+	fmt.Printf("  %-2s%-20s %-8s %-10s %s\n", "", "SESSION", "WINDOWS", "ATTACHED", "LAST ACTIVITY")
+	fmt.Println("  " + strings.Repeat("─", 58))
+	for _, s := range ss {
+		mark := ""
+		if s.Name == own {
+			mark = "★"
+		}
+		activity := "—"
+		if !s.Activity.IsZero() {
+			activity = tmux.Ago(s.Activity)
+		}
+		fmt.Printf("  %-2s%-20s %-8d %-10d %s\n", mark, s.Name, s.Windows, s.Attached, activity)
+	}
+	fmt.Println()
+}
+
+func init() {
+	sessionsCmd.Flags().BoolVar(&sessionsList, "list", false, "print the sessions and exit")
+	rootCmd.AddCommand(sessionsCmd)
+}
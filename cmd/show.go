@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+var showCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show a profile's resolved settings and where each one comes from",
+	Long: `Print every setting of a profile after group inheritance is applied,
+together with the layer that supplied it: the profile itself, one of the
+groups it extends, or the built-in default.
+
+Example:
+  sshtie show web1`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := profile.Get(args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Println()
+		fmt.Printf("  %-24s %-32s %s\n", "FIELD", "VALUE", "FROM")
+		fmt.Println("  " + strings.Repeat("─", 72))
+		for _, f := range p.Sources() {
+			from := f.Source
+			switch from {
+			case "":
+				from = "(default)"
+			case p.Name:
+				from = "profile"
+			default:
+				from = "group " + from
+			}
+			fmt.Printf("  %-24s %-32s %s\n", f.Field, f.Value, from)
+		}
+		fmt.Println()
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(showCmd)
+}
@@ -5,15 +5,21 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/spf13/cobra"
 
+	"github.com/ainsuotain/sshtie/internal/cloud"
+	"github.com/ainsuotain/sshtie/internal/hostkey"
+	"github.com/ainsuotain/sshtie/internal/locate"
+	"github.com/ainsuotain/sshtie/internal/netenv"
 	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/sshconfig"
 )
 
 const (
-	sshtieBegin = "# BEGIN sshtie managed — do not edit this block manually"
-	sshtieEnd   = "# END sshtie managed"
+	sshtieBegin = sshconfig.ManagedBegin
+	sshtieEnd   = sshconfig.ManagedEnd
 )
 
 var sshConfigCmd = &cobra.Command{
@@ -52,10 +58,11 @@ in their server picker automatically.`,
 		existing = stripBlock(existing)
 
 		// Build the new managed block.
+		profiles, picked := pickAddresses(profiles)
 		var block strings.Builder
 		block.WriteString("\n" + sshtieBegin + "\n")
-		for _, p := range profiles {
-			block.WriteString(buildHostEntry(p))
+		for i, p := range profiles {
+			block.WriteString(buildHostEntry(p, picked[i]))
 		}
 		block.WriteString(sshtieEnd + "\n")
 
@@ -78,6 +85,13 @@ in their server picker automatically.`,
 			}
 			fmt.Printf("   Host %-20s → %s@%s:%d\n", p.Name, p.User, p.Host, port)
 		}
+		for i, p := range profiles {
+			if picked[i].Multiple() {
+				fmt.Printf("\n→ %s has several addresses; the one that answered from here was written.\n", p.Name)
+				fmt.Println("  Run sshtie ssh-config again after switching networks.")
+				break
+			}
+		}
 		fmt.Println("\n→ Restart Cursor / VS Code to refresh the SSH target list.")
 		return nil
 	},
@@ -99,10 +113,11 @@ func syncSSHConfig() {
 		existing = string(data)
 	}
 	existing = stripBlock(existing)
+	profiles, picked := pickAddresses(profiles)
 	var block strings.Builder
 	block.WriteString("\n" + sshtieBegin + "\n")
-	for _, p := range profiles {
-		block.WriteString(buildHostEntry(p))
+	for i, p := range profiles {
+		block.WriteString(buildHostEntry(p, picked[i]))
 	}
 	block.WriteString(sshtieEnd + "\n")
 	output := strings.TrimRight(existing, "\n") + block.String()
@@ -116,8 +131,42 @@ func syncSSHConfig() {
 	fmt.Printf("✅ ~/.ssh/config updated (%d profile(s))\n", len(profiles))
 }
 
-// buildHostEntry generates an SSH config Host block for a profile.
-func buildHostEntry(p profile.Profile) string {
+// pickAddresses points every profile with several addresses at the one
+// that answers from here, racing all of them at once. ssh_config can't
+// express "whichever answers", so the file holds a snapshot of this network.
+func pickAddresses(profiles []profile.Profile) ([]profile.Profile, []locate.Choice) {
+	out := make([]profile.Profile, len(profiles))
+	picked := make([]locate.Choice, len(profiles))
+	var here netenv.Network
+	for _, p := range profiles {
+		if len(p.Addresses) > 0 {
+			here = netenv.Current()
+			break
+		}
+	}
+	var wg sync.WaitGroup
+	for i, p := range profiles {
+		out[i] = p
+		if len(p.Addresses) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, p profile.Profile) {
+			defer wg.Done()
+			choice, err := locate.Pick(p, here, locate.DefaultTimeout)
+			if err != nil {
+				return // keep the primary host
+			}
+			out[i], picked[i] = locate.Use(p, choice), choice
+		}(i, p)
+	}
+	wg.Wait()
+	return out, picked
+}
+
+// buildHostEntry generates an SSH config Host block for a profile, noting
+// which address was picked when it had several.
+func buildHostEntry(p profile.Profile, picked locate.Choice) string {
 	var sb strings.Builder
 
 	port := p.Port
@@ -126,17 +175,51 @@ func buildHostEntry(p profile.Profile) string {
 	}
 
 	fmt.Fprintf(&sb, "\nHost %s\n", p.Name)
+	if picked.Multiple() {
+		fmt.Fprintf(&sb, "  # sshtie picked %s\n", picked)
+	}
 	fmt.Fprintf(&sb, "  HostName %s\n", p.Host)
 	fmt.Fprintf(&sb, "  User %s\n", p.User)
 	if port != 22 {
 		fmt.Fprintf(&sb, "  Port %d\n", port)
 	}
 
+	// Jump chain — profile names resolve to their own Host blocks above/below.
+	if len(p.Jump) > 0 {
+		fmt.Fprintf(&sb, "  ProxyJump %s\n", strings.Join(p.Jump, ","))
+	}
+
+	// Cloud transport — the provider's CLI carries the connection. An
+	// invalid one is left out: its settings would reach a shell unchecked.
+	if p.Transport.Enabled() {
+		if err := p.Transport.Validate(); err != nil {
+			fmt.Fprintf(&sb, "  # transport left out: %v\n", err)
+		} else {
+			fmt.Fprintf(&sb, "  ProxyCommand %s\n", cloud.ProxyCommand(p.Transport))
+		}
+	}
+
+	// Port forwards, in ssh_config syntax.
+	for _, f := range p.Forwards {
+		fmt.Fprintf(&sb, "  %s\n", f.ConfigLine())
+	}
+
 	// Key file — only add if explicitly set (don't write the default).
 	if p.Key != "" {
 		fmt.Fprintf(&sb, "  IdentityFile %s\n", p.Key)
 	}
 
+	// Pinned host keys — checked strictly against sshtie's known_hosts.
+	if len(hostkey.TrustedKeys(p)) > 0 {
+		path, _ := hostkey.Path()
+		if strings.ContainsAny(path, " \t") {
+			path = `"` + path + `"`
+		}
+		fmt.Fprintf(&sb, "  HostKeyAlias %s\n", hostkey.Alias(p.Name))
+		fmt.Fprintf(&sb, "  UserKnownHostsFile %s\n", path)
+		sb.WriteString("  StrictHostKeyChecking yes\n")
+	}
+
 	// Advanced SSH options — only emit non-default values.
 	if p.ForwardAgent {
 		sb.WriteString("  ForwardAgent yes\n")
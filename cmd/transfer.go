@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ainsuotain/sshtie/internal/connector"
+	"github.com/ainsuotain/sshtie/internal/locate"
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+var (
+	xferRecursive  bool
+	xferRsync      bool
+	xferSCP        bool
+	xferNoProgress bool
+)
+
+var pushCmd = &cobra.Command{
+	Use:   "push <local>... <name>:<path>",
+	Short: "Copy local files to a profile",
+	Long: `Copy local files to a server, using the profile's user, port, key and
+jump chain. rsync is used when it is installed (interrupted copies resume
+when rerun), scp otherwise.
+
+An empty path after the colon is the remote home directory.
+
+Example:
+  sshtie push ./build.tar.gz web1:/tmp/
+  sshtie push -r ./site web1:www/
+  sshtie push --scp notes.txt web1:`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dst := args[len(args)-1]
+		name, path, ok := connector.SplitRemote(dst)
+		if !ok {
+			return fmt.Errorf("destination %q is not <name>:<path>", dst)
+		}
+		for _, src := range args[:len(args)-1] {
+			if _, _, remote := connector.SplitRemote(src); remote {
+				if _, err := os.Stat(src); err != nil {
+					return fmt.Errorf("%q looks remote — push copies local files; use: sshtie pull", src)
+				}
+			}
+		}
+		p, err := profile.Get(name)
+		if err != nil {
+			return err
+		}
+		return runTransfer(connector.Transfer{Profile: p, Push: true, Sources: args[:len(args)-1], Dest: path})
+	},
+}
+
+var pullCmd = &cobra.Command{
+	Use:   "pull <name>:<path>... <local>",
+	Short: "Copy files from a profile to this machine",
+	Long: `Copy files from a server, using the profile's user, port, key and jump
+chain. rsync is used when it is installed (interrupted copies resume when
+rerun), scp otherwise. All sources must be on the same profile.
+
+Example:
+  sshtie pull web1:/var/log/app.log .
+  sshtie pull -r web1:backups/ ./backups/`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var name string
+		var paths []string
+		for _, src := range args[:len(args)-1] {
+			n, path, ok := connector.SplitRemote(src)
+			if !ok {
+				return fmt.Errorf("source %q is not <name>:<path>", src)
+			}
+			if name != "" && n != name {
+				return fmt.Errorf("all sources must be on the same profile (%s, %s)", name, n)
+			}
+			name = n
+			paths = append(paths, path)
+		}
+		p, err := profile.Get(name)
+		if err != nil {
+			return err
+		}
+		return runTransfer(connector.Transfer{Profile: p, Sources: paths, Dest: args[len(args)-1]})
+	},
+}
+
+// runTransfer applies the shared flags to t and runs it attached to the
+// terminal. In auto mode a failed rsync that looks like "rsync missing on
+// the server" is retried with scp.
+func runTransfer(t connector.Transfer) error {
+	if xferRsync && xferSCP {
+		return fmt.Errorf("--rsync and --scp are mutually exclusive")
+	}
+	switch {
+	case xferRsync:
+		t.Tool = connector.ToolRsync
+	case xferSCP:
+		t.Tool = connector.ToolSCP
+	}
+	t.Recursive = xferRecursive
+	t.Progress = !xferNoProgress && isTerminal(os.Stdout)
+	p, _, err := locate.Apply(t.Profile)
+	if err != nil {
+		return err
+	}
+	if p, err = connector.VerifyHostKey(p); err != nil {
+		return err
+	}
+	t.Profile = p
+
+	tool := t.ResolveTool()
+	err = runTransferWith(t, tool)
+	var exitErr *exec.ExitError
+	if err != nil && t.Tool == connector.ToolAuto && tool == connector.ToolRsync &&
+		errors.As(err, &exitErr) && (exitErr.ExitCode() == 12 || exitErr.ExitCode() == 127) {
+		fmt.Fprintf(os.Stderr, "⚠  rsync failed (exit %d) — is rsync installed on the server? Retrying with scp.\n", exitErr.ExitCode())
+		err = runTransferWith(t, connector.ToolSCP)
+	}
+	return err
+}
+
+func runTransferWith(t connector.Transfer, tool string) error {
+	c, err := t.Command(tool)
+	if err != nil {
+		return err
+	}
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("%s: %w", tool, err)
+	}
+	return nil
+}
+
+// isTerminal reports whether f is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func init() {
+	for _, c := range []*cobra.Command{pushCmd, pullCmd} {
+		c.Flags().BoolVarP(&xferRecursive, "recursive", "r", false, "copy directories recursively")
+		c.Flags().BoolVar(&xferRsync, "rsync", false, "always use rsync")
+		c.Flags().BoolVar(&xferSCP, "scp", false, "always use scp")
+		c.Flags().BoolVar(&xferNoProgress, "no-progress", false, "don't show transfer progress")
+		rootCmd.AddCommand(c)
+	}
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ainsuotain/sshtie/internal/connector"
+	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/session"
+)
+
+var tunnelCmd = &cobra.Command{
+	Use:   "tunnel",
+	Short: "Run a profile's port forwards as a background tunnel",
+	Long: `Keep a profile's port forwards open in the background with ssh -N,
+independent of any interactive session.
+
+A tunnel reconnects on its own after network drops and laptop sleep, and
+keeps running after the terminal is closed. Its output goes to
+~/.sshtie/sessions/<name>.tunnel.log.
+
+Forwards are declared on the profile (sshtie edit, or sshtie add --forward).
+
+Example:
+  sshtie tunnel up db
+  sshtie tunnel list
+  sshtie tunnel down db`,
+	Args: cobra.NoArgs,
+}
+
+var tunnelUpCmd = &cobra.Command{
+	Use:   "up <name>",
+	Short: "Start a background tunnel",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		p, err := profile.Get(name)
+		if err != nil {
+			return err
+		}
+		if len(p.Forwards) == 0 {
+			return fmt.Errorf("profile %q has no forwards — add some with: sshtie edit %s", name, name)
+		}
+		if s, err := session.ReadTunnel(name); err == nil && session.IsAlive(s.PID) {
+			return fmt.Errorf("tunnel %q is already running (pid %d)", name, s.PID)
+		}
+
+		exe, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		logPath, err := tunnelLogPath(name)
+		if err != nil {
+			return err
+		}
+		logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("open tunnel log: %w", err)
+		}
+		defer logFile.Close()
+
+		run := exec.Command(exe, "tunnel", "run", name)
+		run.Stdout = logFile
+		run.Stderr = logFile
+		connector.DetachTunnel(run)
+		if err := run.Start(); err != nil {
+			return err
+		}
+
+		// Give ssh a moment to authenticate and bind the forwards; a tunnel
+		// that dies in that window never came up.
+		done := make(chan error, 1)
+		go func() { done <- run.Wait() }()
+		select {
+		case <-done:
+			fmt.Printf("⚠  Tunnel '%s' failed to start. Last log lines:\n", name)
+			printLogTail(logPath, 5)
+			return fmt.Errorf("tunnel %q did not come up", name)
+		case <-time.After(3 * time.Second):
+		}
+		pid := run.Process.Pid
+		_ = run.Process.Release()
+
+		fmt.Printf("✅ Tunnel '%s' up (pid %d)\n", name, pid)
+		for _, f := range p.Forwards {
+			fmt.Printf("   %s\n", f.String())
+		}
+		fmt.Printf("→ Stop with: sshtie tunnel down %s\n", name)
+		return nil
+	},
+}
+
+var tunnelDownCmd = &cobra.Command{
+	Use:   "down <name>",
+	Short: "Stop a background tunnel",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		s, err := session.ReadTunnel(name)
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no tunnel running for %q", name)
+		}
+		if err != nil {
+			return err
+		}
+		if session.IsAlive(s.PID) {
+			if err := session.Stop(s.PID); err != nil {
+				return fmt.Errorf("stop tunnel: %w", err)
+			}
+		}
+		_ = session.DeleteTunnel(name)
+		fmt.Printf("✅ Tunnel '%s' stopped.\n", name)
+		return nil
+	},
+}
+
+var tunnelListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List running tunnels",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		active, err := session.ListActive()
+		if err != nil {
+			return err
+		}
+		var tunnels []session.Session
+		for _, s := range active {
+			if s.IsTunnel() {
+				tunnels = append(tunnels, s)
+			}
+		}
+		if len(tunnels) == 0 {
+			fmt.Println("No tunnels running. Start one with: sshtie tunnel up <name>")
+			return nil
+		}
+
+		fmt.Println()
+		fmt.Printf("  %-16s %-8s %-10s %s\n", "NAME", "PID", "UPTIME", "FORWARDS")
+		fmt.Println("  " + strings.Repeat("─", 72))
+		for _, s := range tunnels {
+			up := time.Since(s.StartedAt).Round(time.Second)
+			fmt.Printf("  %-16s %-8d %-10s %s\n", s.Profile, s.PID, up, strings.Join(s.Forwards, " "))
+		}
+		fmt.Println()
+		return nil
+	},
+}
+
+// tunnelRunCmd is the detached process started by `tunnel up`.
+var tunnelRunCmd = &cobra.Command{
+	Use:    "run <name>",
+	Short:  "Run a tunnel in the foreground",
+	Hidden: true,
+	Args:   cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := profile.Get(args[0])
+		if err != nil {
+			return err
+		}
+		return connector.RunTunnel(p)
+	},
+}
+
+func tunnelLogPath(name string) (string, error) {
+	dir, err := session.SessionDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".tunnel.log"), nil
+}
+
+func printLogTail(path string, n int) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	for _, l := range lines {
+		fmt.Printf("   %s\n", l)
+	}
+}
+
+func init() {
+	tunnelCmd.AddCommand(tunnelUpCmd, tunnelDownCmd, tunnelListCmd, tunnelRunCmd)
+	rootCmd.AddCommand(tunnelCmd)
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ainsuotain/sshtie/internal/connector"
+	"github.com/ainsuotain/sshtie/internal/locate"
+	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/wol"
+)
+
+var wakeNoWait bool
+
+var wakeCmd = &cobra.Command{
+	Use:   "wake <name>",
+	Short: "Send a Wake-on-LAN packet to a profile and wait for SSH",
+	Long: `Send the Wake-on-LAN magic packet for a profile, then wait until its SSH
+port answers (up to wake.timeout, default 2m).
+
+The packet is broadcast from this machine, or sent by the profile named in
+wake.relay (over ssh, with wakeonlan or python3) when this machine isn't on
+the server's LAN:
+
+  wake:
+    mac: 3c:22:fb:12:34:56
+    broadcast: 192.168.1.255     # optional
+    relay: nas                   # optional
+
+sshtie connect does this by itself when the server doesn't answer.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := profile.Get(args[0])
+		if err != nil {
+			return err
+		}
+		if !p.Wake.Enabled() {
+			return fmt.Errorf("profile %q has no wake.mac — set wake: {mac: …} on it in ~/.sshtie/profiles.yaml", p.Name)
+		}
+		cmd.SilenceUsage = true // from here on, errors are about the network
+		if err := connector.SendWake(p); err != nil {
+			return err
+		}
+		from := "from here to " + wol.Addr(p.Wake.Broadcast)
+		if p.Wake.Relay != "" {
+			from = "via " + p.Wake.Relay
+		}
+		fmt.Printf("✅ Magic packet for %s sent %s.\n", p.Wake.MAC, from)
+
+		if wakeNoWait {
+			return nil
+		}
+		if len(p.Jump) > 0 {
+			fmt.Println("→ It sits behind a jump chain, so sshtie can't watch it wake from here.")
+			return nil
+		}
+		if p, _, err = locate.Apply(p); err != nil {
+			return err
+		}
+		return connector.WaitAwake(p)
+	},
+}
+
+func init() {
+	wakeCmd.Flags().BoolVar(&wakeNoWait, "no-wait", false, "send the packet and return without waiting for SSH")
+	rootCmd.AddCommand(wakeCmd)
+}
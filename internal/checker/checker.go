@@ -1,54 +1,121 @@
-// Package checker provides background TCP-reachability and active-session
-// tracking for profiles.
+// Package checker provides background reachability and active-session
+// tracking for profiles, plus the tmux sessions running on each server.
 package checker
 
 import (
+	"context"
 	"net"
 	"strconv"
 	"sync"
 	"time"
 
+	"github.com/ainsuotain/sshtie/internal/cloud"
+	"github.com/ainsuotain/sshtie/internal/locate"
+	"github.com/ainsuotain/sshtie/internal/netenv"
+	"github.com/ainsuotain/sshtie/internal/probe"
 	"github.com/ainsuotain/sshtie/internal/profile"
 	"github.com/ainsuotain/sshtie/internal/session"
+	"github.com/ainsuotain/sshtie/internal/tmux"
 )
 
-// Checker polls each server's SSH port and tracks whether it is reachable,
-// and also maintains a map of currently-active sessions.
+// Checker polls each server's SSH port (or, behind a cloud transport, its
+// instance state) and tracks whether it is reachable, and also maintains a
+// map of currently-active sessions.
 type Checker struct {
 	mu       sync.RWMutex
-	statuses map[string]bool    // profile name → reachable
+	statuses map[string]bool            // profile name → reachable
+	via      map[string]string          // profile name → first jump host or transport ("" = direct)
+	address  map[string]string          // profile name → address picked ("" = only one)
+	picked   map[string]locate.Choice   // profile name → the picked address itself
 	sessions map[string]session.Session // profile name → active session
+	tunnels  map[string]session.Session // profile name → running background tunnel
+	remote   map[string][]tmux.Session  // profile name → tmux sessions on the server
 }
 
+// cloudTimeout bounds one instance-state query through a cloud CLI.
+const cloudTimeout = 15 * time.Second
+
 func New() *Checker {
 	return &Checker{
 		statuses: make(map[string]bool),
+		via:      make(map[string]string),
+		address:  make(map[string]string),
+		picked:   make(map[string]locate.Choice),
 		sessions: make(map[string]session.Session),
+		tunnels:  make(map[string]session.Session),
+		remote:   make(map[string][]tmux.Session),
 	}
 }
 
 // CheckAll dials every profile concurrently (3 s timeout each) and updates
 // the internal status table. onChange is called (once) if any value changed.
+//
+// Profiles behind a jump chain are only reachable through their bastion, so
+// the first hop is dialled instead of the target. Profiles with several
+// addresses race them (see package locate) and remember the winner.
+// Profiles with a cloud transport count as reachable when the cloud CLI
+// reports their instance running (see package cloud).
 func (c *Checker) CheckAll(profiles []profile.Profile, onChange func()) {
 	type result struct {
 		name      string
 		reachable bool
+		via       string
+		address   string
+		picked    *locate.Choice
+	}
+
+	var here netenv.Network
+	for _, p := range profiles {
+		if len(p.Addresses) > 0 {
+			here = netenv.Current()
+			break
+		}
 	}
 
 	results := make(chan result, len(profiles))
 	for _, p := range profiles {
 		go func(p profile.Profile) {
-			port := p.Port
-			if port == 0 {
-				port = 22
+			if p.Transport.Enabled() {
+				ctx, cancel := context.WithTimeout(context.Background(), cloudTimeout)
+				defer cancel()
+				err := cloud.Running(ctx, p.Transport)
+				results <- result{name: p.Name, reachable: err == nil, via: p.Transport.String()}
+				return
+			}
+			hops, err := profile.ResolveJumpChain(p, profiles)
+			if err != nil {
+				results <- result{name: p.Name}
+				return
+			}
+			address := ""
+			var picked *locate.Choice
+			if len(p.Addresses) > 0 {
+				choice, err := locate.Pick(p, here, 3*time.Second)
+				if err != nil {
+					results <- result{name: p.Name}
+					return
+				}
+				address, picked = choice.String(), &choice
+				if len(hops) == 0 {
+					if !choice.Reachable {
+						address, picked = "", nil
+					}
+					results <- result{name: p.Name, reachable: choice.Reachable, address: address, picked: picked}
+					return
+				}
+			}
+			host, port := profile.EntryPoint(p, hops)
+			via := ""
+			if len(hops) > 0 {
+				via = hops[0].Label()
 			}
 			conn, err := net.DialTimeout("tcp",
-				net.JoinHostPort(p.Host, strconv.Itoa(port)),
+				net.JoinHostPort(host, strconv.Itoa(port)),
 				3*time.Second)
 			if err == nil {
 				conn.Close()
 			}
-			results <- result{name: p.Name, reachable: err == nil}
+			results <- result{name: p.Name, reachable: err == nil, via: via, address: address, picked: picked}
 		}(p)
 	}
 
@@ -61,6 +128,19 @@ func (c *Checker) CheckAll(profiles []profile.Profile, onChange func()) {
 			c.statuses[r.name] = r.reachable
 			changed = true
 		}
+		if c.via[r.name] != r.via {
+			c.via[r.name] = r.via
+			changed = true
+		}
+		if c.address[r.name] != r.address {
+			c.address[r.name] = r.address
+			changed = true
+		}
+		if r.picked != nil {
+			c.picked[r.name] = *r.picked
+		} else {
+			delete(c.picked, r.name)
+		}
 		c.mu.Unlock()
 	}
 
@@ -77,38 +157,91 @@ func (c *Checker) RefreshSessions(onChange func()) {
 		return
 	}
 
-	// Build new map.
+	// Build new maps.
 	newMap := make(map[string]session.Session, len(active))
+	newTunnels := make(map[string]session.Session)
 	for _, s := range active {
-		newMap[s.Profile] = s
+		if s.IsTunnel() {
+			newTunnels[s.Profile] = s
+		} else {
+			newMap[s.Profile] = s
+		}
 	}
 
 	c.mu.Lock()
-	changed := len(newMap) != len(c.sessions)
-	if !changed {
-		for k := range newMap {
-			if _, ok := c.sessions[k]; !ok {
-				changed = true
-				break
+	changed := !sameKeys(newMap, c.sessions) || !sameKeys(newTunnels, c.tunnels)
+	c.sessions = newMap
+	c.tunnels = newTunnels
+	c.mu.Unlock()
+
+	if changed && onChange != nil {
+		onChange()
+	}
+}
+
+// RefreshRemote lists the tmux sessions on every reachable tmux profile (see
+// CheckAll) over a native SSH login — agent or key, never a prompt. Each
+// listing is a full login, so callers should run this far less often than
+// CheckAll. A profile that can't be logged in to that way, that uses
+// another multiplexer or that sits behind a cloud transport (every login
+// would open a tunnel through the cloud CLI) has none. onChange is called
+// if any list changed.
+func (c *Checker) RefreshRemote(profiles []profile.Profile, onChange func()) {
+	type result struct {
+		name     string
+		sessions []tmux.Session
+	}
+	results := make(chan result, len(profiles))
+	for _, p := range profiles {
+		c.mu.RLock()
+		reachable := c.statuses[p.Name]
+		choice, picked := c.picked[p.Name]
+		c.mu.RUnlock()
+		go func(p profile.Profile) {
+			if mux, _ := profile.Multiplexer(p); !reachable || mux != "tmux" || p.Transport.Enabled() {
+				results <- result{name: p.Name}
+				return
 			}
-		}
-		if !changed {
-			for k := range c.sessions {
-				if _, ok := newMap[k]; !ok {
-					changed = true
-					break
-				}
+			if picked {
+				p = locate.Use(p, choice)
 			}
+			out, err := probe.Exec(p, probe.Options{Timeout: 5 * time.Second}, tmux.ListScript())
+			if err != nil {
+				results <- result{name: p.Name}
+				return
+			}
+			results <- result{name: p.Name, sessions: tmux.Parse(out)}
+		}(p)
+	}
+
+	changed := false
+	for range profiles {
+		r := <-results
+		c.mu.Lock()
+		if !sameSessions(c.remote[r.name], r.sessions) {
+			changed = true
 		}
+		if len(r.sessions) > 0 {
+			c.remote[r.name] = r.sessions
+		} else {
+			delete(c.remote, r.name)
+		}
+		c.mu.Unlock()
 	}
-	c.sessions = newMap
-	c.mu.Unlock()
 
 	if changed && onChange != nil {
 		onChange()
 	}
 }
 
+// RemoteSessions returns the tmux sessions last seen on the named
+// profile's server, most recently active first.
+func (c *Checker) RemoteSessions(name string) []tmux.Session {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]tmux.Session(nil), c.remote[name]...)
+}
+
 // Get returns (reachable, known). known is false if the profile has never
 // been checked yet (shows as 🟡 "checking" in the menu).
 func (c *Checker) Get(name string) (reachable bool, known bool) {
@@ -118,6 +251,22 @@ func (c *Checker) Get(name string) (reachable bool, known bool) {
 	return v, ok
 }
 
+// Via returns the jump host or cloud transport the profile was checked
+// through, or "" when the profile is dialled directly.
+func (c *Checker) Via(name string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.via[name]
+}
+
+// Address returns the address the profile answered on, as described by
+// locate.Choice, or "" when the profile has a single address.
+func (c *Checker) Address(name string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.address[name]
+}
+
 // ActiveSession returns the Session for the named profile, if connected.
 func (c *Checker) ActiveSession(name string) (session.Session, bool) {
 	c.mu.RLock()
@@ -136,3 +285,40 @@ func (c *Checker) ActiveSessions() []session.Session {
 	}
 	return out
 }
+
+// ActiveTunnels returns a snapshot of all running background tunnels.
+func (c *Checker) ActiveTunnels() []session.Session {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make([]session.Session, 0, len(c.tunnels))
+	for _, s := range c.tunnels {
+		out = append(out, s)
+	}
+	return out
+}
+
+// sameSessions reports whether a and b would show the same in the menu.
+func sameSessions(a, b []tmux.Session) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Windows != b[i].Windows || a[i].Attached != b[i].Attached {
+			return false
+		}
+	}
+	return true
+}
+
+// sameKeys reports whether a and b hold the same profile names.
+func sameKeys(a, b map[string]session.Session) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
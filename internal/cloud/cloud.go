@@ -0,0 +1,139 @@
+// Package cloud reaches servers that have no SSH port of their own through
+// their cloud provider's CLI: AWS Systems Manager (aws ssm start-session)
+// and Google Cloud IAP (gcloud compute start-iap-tunnel). The tunnel command
+// is ssh's ProxyCommand; this package also asks the CLI whether the instance
+// is running and opens the tunnel for sshtie's own SSH client.
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+// CLI returns the command-line tool t runs: "aws" or "gcloud".
+func CLI(t profile.Transport) string {
+	if t.Type == profile.TransportIAP {
+		return "gcloud"
+	}
+	return "aws"
+}
+
+// Check looks for the local tools t needs. aws-ssm also needs AWS's
+// session-manager-plugin, which the aws CLI runs behind the scenes.
+func Check(t profile.Transport) error {
+	if _, err := exec.LookPath(CLI(t)); err != nil {
+		return fmt.Errorf("%s CLI not found in PATH", CLI(t))
+	}
+	if t.Type == profile.TransportSSM {
+		if _, err := exec.LookPath("session-manager-plugin"); err != nil {
+			return fmt.Errorf("session-manager-plugin not found in PATH (needed by aws ssm)")
+		}
+	}
+	return nil
+}
+
+// ProxyArgs is the tunnel command for t to the instance's port, which is a
+// number or ssh's %p token.
+func ProxyArgs(t profile.Transport, port string) []string {
+	if t.Type == profile.TransportIAP {
+		args := []string{"gcloud", "compute", "start-iap-tunnel", t.Instance, port,
+			"--listen-on-stdin", "--zone=" + t.Zone}
+		if t.Project != "" {
+			args = append(args, "--project="+t.Project)
+		}
+		return append(args, "--verbosity=warning")
+	}
+	args := []string{"aws", "ssm", "start-session", "--target", t.Instance,
+		"--document-name", "AWS-StartSSHSession", "--parameters", "portNumber=" + port}
+	if t.Region != "" {
+		args = append(args, "--region", t.Region)
+	}
+	return args
+}
+
+// ProxyCommand is the ssh ProxyCommand for t, which must have passed
+// Validate: that limits the settings to letters, digits, '.', '_' and '-',
+// never leading with '-', so the words need neither shell quoting nor
+// %-escaping and none is taken for an option.
+func ProxyCommand(t profile.Transport) string {
+	return strings.Join(ProxyArgs(t, "%p"), " ")
+}
+
+// stateArgs asks the CLI for the instance's state, printed on its own:
+// "running", "stopped", … for EC2; "RUNNING", "TERMINATED", … for GCE.
+func stateArgs(t profile.Transport) []string {
+	if t.Type == profile.TransportIAP {
+		args := []string{"gcloud", "compute", "instances", "describe", t.Instance,
+			"--zone=" + t.Zone, "--format=value(status)"}
+		if t.Project != "" {
+			args = append(args, "--project="+t.Project)
+		}
+		return args
+	}
+	args := []string{"aws", "ec2", "describe-instances", "--instance-ids", t.Instance,
+		"--query", "Reservations[0].Instances[0].State.Name", "--output", "text"}
+	if t.Region != "" {
+		args = append(args, "--region", t.Region)
+	}
+	return args
+}
+
+// State returns the instance's state as the cloud CLI reports it.
+func State(ctx context.Context, t profile.Transport) (string, error) {
+	args := stateArgs(t)
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("%s didn't answer: %w", args[0], ctx.Err())
+		}
+		if msg := lastLine(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("%s: %w", args[0], err)
+	}
+	state := strings.TrimSpace(string(out))
+	if state == "" || state == "None" {
+		return "", fmt.Errorf("%s: instance %s not found", args[0], t.Instance)
+	}
+	return state, nil
+}
+
+// Running checks that the instance is running: nil when it is, otherwise
+// why not ("instance i-… is stopped", a missing CLI, an auth error).
+func Running(ctx context.Context, t profile.Transport) error {
+	if err := Check(t); err != nil {
+		return err
+	}
+	state, err := State(ctx, t)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(state, "running") {
+		return fmt.Errorf("instance %s is %s", t.Instance, strings.ToLower(state))
+	}
+	return nil
+}
+
+// lastLine returns the last non-empty line of s — where the CLIs put the
+// actual error after any warnings.
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// portArg formats port for ProxyArgs, defaulting 0 to 22.
+func portArg(port int) string {
+	if port == 0 {
+		port = 22
+	}
+	return strconv.Itoa(port)
+}
@@ -0,0 +1,112 @@
+package cloud
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ainsuotain/sshtie/internal/cloud/cloudtest"
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+var (
+	ssm = profile.Transport{Type: profile.TransportSSM, Instance: "i-0abc", Region: "eu-west-1"}
+	iap = profile.Transport{Type: profile.TransportIAP, Instance: "build-vm-1", Zone: "europe-west1-b", Project: "ci"}
+)
+
+func TestProxyCommand(t *testing.T) {
+	if got, want := ProxyCommand(ssm),
+		"aws ssm start-session --target i-0abc --document-name AWS-StartSSHSession --parameters portNumber=%p --region eu-west-1"; got != want {
+		t.Errorf("aws-ssm:\n got %s\nwant %s", got, want)
+	}
+	if got, want := ProxyCommand(iap),
+		"gcloud compute start-iap-tunnel build-vm-1 %p --listen-on-stdin --zone=europe-west1-b --project=ci --verbosity=warning"; got != want {
+		t.Errorf("gcp-iap:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestRunning(t *testing.T) {
+	cloudtest.StubCLIs(t, map[string]string{
+		"session-manager-plugin": "",
+		"aws": `case "$*" in
+  *i-0abc*) echo running;;
+  *i-0off*) echo stopped;;
+  *) echo "An error occurred (InvalidInstanceID.NotFound)" >&2; exit 254;;
+esac
+`,
+		"gcloud": `case "$*" in
+  *"describe build-vm-1 --zone=europe-west1-b --format=value(status) --project=ci"*) echo RUNNING;;
+  *) echo "ERROR: (gcloud.compute.instances.describe) not found" >&2; exit 1;;
+esac
+`,
+	})
+	ctx := context.Background()
+
+	if err := Running(ctx, ssm); err != nil {
+		t.Errorf("aws running: %v", err)
+	}
+	if err := Running(ctx, iap); err != nil {
+		t.Errorf("gcloud running: %v", err)
+	}
+
+	off := ssm
+	off.Instance = "i-0off"
+	if err := Running(ctx, off); err == nil || !strings.Contains(err.Error(), "is stopped") {
+		t.Errorf("stopped instance: %v", err)
+	}
+	gone := ssm
+	gone.Instance = "i-0gone"
+	if err := Running(ctx, gone); err == nil || !strings.Contains(err.Error(), "InvalidInstanceID.NotFound") {
+		t.Errorf("unknown instance: %v", err)
+	}
+	wrongZone := iap
+	wrongZone.Zone = "us-east1-b"
+	if err := Running(ctx, wrongZone); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("wrong zone: %v", err)
+	}
+}
+
+func TestCheck_missingPlugin(t *testing.T) {
+	cloudtest.StubCLIs(t, map[string]string{"aws": "echo running\n"})
+	if err := Check(ssm); err == nil || !strings.Contains(err.Error(), "session-manager-plugin") {
+		t.Errorf("Check = %v, want missing session-manager-plugin", err)
+	}
+	if err := Check(iap); err == nil || !strings.Contains(err.Error(), "gcloud") {
+		t.Errorf("Check = %v, want missing gcloud", err)
+	}
+}
+
+func TestDial(t *testing.T) {
+	// The stub tunnel echoes what it is sent, so bytes make a round trip
+	// through its stdin and stdout — unless the port is wrong.
+	cloudtest.StubCLIs(t, map[string]string{"gcloud": `case "$*" in
+  *" 2222 --listen-on-stdin"*) exec cat;;
+  *) echo "ERROR: failed to connect to backend" >&2; exit 1;;
+esac
+`})
+	conn, err := Dial(iap, 2222)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write([]byte("SSH-2.0-test\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 14)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "SSH-2.0-test\r\n" {
+		t.Errorf("read %q, %v", buf, err)
+	}
+	conn.Close()
+
+	conn, err = Dial(iap, 22)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, err = conn.Read(buf)
+	if err == nil || !strings.Contains(err.Error(), "failed to connect to backend") {
+		t.Errorf("tunnel that exits: read error %v", err)
+	}
+}
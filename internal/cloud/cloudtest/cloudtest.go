@@ -0,0 +1,28 @@
+// Package cloudtest stubs the cloud CLIs for tests of code that shells out
+// to them.
+package cloudtest
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// StubCLIs puts fake tools on an otherwise bare PATH, so a real aws or
+// gcloud on the machine can never answer; each maps a name to the body of
+// its shell script. Tests are skipped where there is no POSIX shell.
+func StubCLIs(t *testing.T, tools map[string]string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell stubs")
+	}
+	dir := t.TempDir()
+	for name, body := range tools {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+body), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The stubs themselves need cat, sleep and friends.
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+"/usr/bin:/bin")
+}
@@ -0,0 +1,117 @@
+package cloud
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+// Dial starts t's tunnel to the instance's port and returns it as a
+// connection: writes go to the tunnel's stdin, reads come from its stdout.
+// Closing it stops the tunnel.
+func Dial(t profile.Transport, port int) (net.Conn, error) {
+	args := ProxyArgs(t, portArg(port))
+	cmd := exec.Command(args[0], args[1:]...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	c := &pipeConn{cmd: cmd, r: stdout, w: stdin, name: args[0]}
+	cmd.Stderr = &c.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s: %w", args[0], err)
+	}
+	return c, nil
+}
+
+// pipeConn is a net.Conn over a tunnel process's stdin and stdout.
+// Deadlines close the connection when they pass, which is all the SSH
+// handshake needs them for.
+type pipeConn struct {
+	cmd    *exec.Cmd
+	r      io.ReadCloser
+	w      io.WriteCloser
+	name   string
+	stderr bytes.Buffer
+
+	mu     sync.Mutex
+	timer  *time.Timer
+	closed bool
+	waited sync.Once
+}
+
+// wait reaps the tunnel process, once.
+func (c *pipeConn) wait() {
+	c.waited.Do(func() { _ = c.cmd.Wait() })
+}
+
+func (c *pipeConn) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	if err == io.EOF && n == 0 {
+		// The tunnel ended; its stderr says why.
+		c.mu.Lock()
+		closed := c.closed
+		c.mu.Unlock()
+		if !closed {
+			c.wait()
+			if msg := lastLine(c.stderr.String()); msg != "" {
+				return 0, fmt.Errorf("%s: %s", c.name, msg)
+			}
+		}
+	}
+	return n, err
+}
+
+func (c *pipeConn) Write(b []byte) (int, error) { return c.w.Write(b) }
+
+func (c *pipeConn) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.mu.Unlock()
+	c.w.Close()
+	_ = c.cmd.Process.Kill()
+	go c.wait()
+	return nil
+}
+
+func (c *pipeConn) LocalAddr() net.Addr  { return pipeAddr(c.name) }
+func (c *pipeConn) RemoteAddr() net.Addr { return pipeAddr(c.name) }
+
+func (c *pipeConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if !t.IsZero() && !c.closed {
+		c.timer = time.AfterFunc(time.Until(t), func() { c.Close() })
+	}
+	return nil
+}
+
+func (c *pipeConn) SetReadDeadline(t time.Time) error  { return c.SetDeadline(t) }
+func (c *pipeConn) SetWriteDeadline(t time.Time) error { return c.SetDeadline(t) }
+
+// pipeAddr names the tunnel command in place of a network address.
+type pipeAddr string
+
+func (a pipeAddr) Network() string { return "pipe" }
+func (a pipeAddr) String() string  { return string(a) }
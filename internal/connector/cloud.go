@@ -0,0 +1,38 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ainsuotain/sshtie/internal/cloud"
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+// cloudTimeout bounds one instance-state query; the cloud CLIs take a
+// second or two even when all is well.
+const cloudTimeout = 20 * time.Second
+
+// reachable checks that p can be connected to right now: its instance is
+// running for a transport profile, its entry point answers on TCP
+// otherwise.
+func reachable(p profile.Profile, port int) error {
+	if p.Transport.Enabled() {
+		ctx, cancel := context.WithTimeout(context.Background(), cloudTimeout)
+		defer cancel()
+		return cloud.Running(ctx, p.Transport)
+	}
+	if host, ePort := entryPoint(p, port); !tcpReachable(host, ePort, 5*time.Second) {
+		return fmt.Errorf("TCP port %d unreachable on %s", ePort, host)
+	}
+	return nil
+}
+
+// sshOnly rules out strategies that reach the server on ports of their own
+// (mosh's UDP, etserver), which a cloud transport doesn't carry.
+func sshOnly(p profile.Profile) error {
+	if p.Transport.Enabled() {
+		return fmt.Errorf("%s only carries ssh", p.Transport)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package connector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ainsuotain/sshtie/internal/cloud/cloudtest"
+	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/shell"
+)
+
+func TestTransport_commands(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	p := profile.Profile{
+		Name: "web", Host: "i-0abc", User: "ec2-user",
+		Transport: profile.Transport{Type: profile.TransportSSM, Instance: "i-0abc", Region: "eu-west-1"},
+	}
+	proxy := "ProxyCommand=aws ssm start-session --target i-0abc --document-name AWS-StartSSHSession --parameters portNumber=%p --region eu-west-1"
+
+	steps, err := Plan(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps[0].Name != "mosh+tmux" || steps[0].Skip == "" {
+		t.Errorf("mosh+tmux step = %+v, want skipped", steps[0])
+	}
+
+	pv, err := NewPreview(p, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range pv.Steps[1:] {
+		if !strings.Contains(strings.Join(st.Argv, "\x00"), "\x00"+proxy+"\x00") {
+			t.Errorf("%s command %q lacks %q", st.Name, shell.Join(st.Argv), proxy)
+		}
+	}
+
+	cmd, err := ExecCommand(t.Context(), p, []string{"uptime"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.Join(cmd.Args, "\x00"), proxy) {
+		t.Errorf("exec command %q lacks the ProxyCommand", cmd.Args)
+	}
+	tr := Transfer{Profile: p, Push: true, Sources: []string{"a.txt"}, Dest: "/tmp"}
+	if cmd, err = tr.Command(ToolRsync); err != nil || !strings.Contains(strings.Join(cmd.Args, " "), "'"+proxy+"'") {
+		t.Errorf("rsync command %q (%v) lacks the quoted ProxyCommand", cmd.Args, err)
+	}
+
+	p.Jump = []string{"bastion"}
+	if _, err := ExecCommand(t.Context(), p, []string{"uptime"}); err == nil {
+		t.Error("transport with jump: want error")
+	}
+}
+
+func TestTransport_reachable(t *testing.T) {
+	cloudtest.StubCLIs(t, map[string]string{
+		"session-manager-plugin": "",
+		"aws":                    "case \"$*\" in *i-0up*) echo running;; *) echo stopped;; esac\n",
+	})
+
+	// An unroutable host: only the instance state may decide.
+	p := profile.Profile{Name: "web", Host: "192.0.2.1", User: "u",
+		Transport: profile.Transport{Type: profile.TransportSSM, Instance: "i-0up"}}
+	if err := reachable(p, 22); err != nil {
+		t.Errorf("running instance: %v", err)
+	}
+	p.Transport.Instance = "i-0down"
+	if err := reachable(p, 22); err == nil || !strings.Contains(err.Error(), "i-0down is stopped") {
+		t.Errorf("stopped instance: %v", err)
+	}
+	c := &Conn{Profile: p, Port: 22, Mux: "tmux"}
+	if err := (sshTmux{}).Probe(c); err == nil {
+		t.Error("ssh+tmux probe passed for a stopped instance")
+	}
+}
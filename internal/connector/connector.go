@@ -1,17 +1,21 @@
 package connector
 
 import (
+	"bytes"
 	"fmt"
 	"net"
 	"os"
 	"os/exec"
-	"runtime"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/ainsuotain/sshtie/internal/cloud"
+	"github.com/ainsuotain/sshtie/internal/history"
+	"github.com/ainsuotain/sshtie/internal/hostkey"
+	"github.com/ainsuotain/sshtie/internal/learned"
 	"github.com/ainsuotain/sshtie/internal/profile"
-	sess "github.com/ainsuotain/sshtie/internal/session"
+	"github.com/ainsuotain/sshtie/internal/shell"
 	"github.com/ainsuotain/sshtie/internal/tailscale"
 )
 
@@ -19,216 +23,203 @@ func netAddr(host string, port int) string {
 	return net.JoinHostPort(host, strconv.Itoa(port))
 }
 
-// Connect executes the best available connection strategy for the given profile.
-// Strategy: mosh+tmux → ssh+tmux → ssh only
-// On Windows, mosh is skipped entirely (not supported natively).
+// ConnectOptions adjusts ConnectWith.
+type ConnectOptions struct {
+	// RetryAll tries every strategy, including ones that recently failed
+	// on this network.
+	RetryAll bool
+	// Unresolved is the profile as saved, for a p that was already passed
+	// through Resolve: reconnects pick among its addresses again.
+	Unresolved profile.Profile
+}
+
+// Connect opens an interactive session for p, walking its strategy chain
+// (default: mosh+tmux → ssh+tmux → ssh). A strategy whose probe fails is
+// skipped, one that fails at startup falls through to the next, and one that
+// drops after running is reconnected as-is.
+//
+// Failures are remembered per local network (see package learned) once a
+// later strategy has worked — so a server that is simply down teaches
+// nothing. A strategy that failed here within the profile's
+// remember_failures window is skipped without being probed, unless it is the
+// last one left.
+//
+// A profile with several addresses is first pointed at the one that answers
+// from here (see package locate), and a sleeping one with wake set up is
+// woken (see WakeIfAsleep).
 func Connect(p profile.Profile) error {
-	port := p.Port
-	if port == 0 {
-		port = 22
+	return ConnectWith(p, ConnectOptions{})
+}
+
+// ConnectWith is Connect with options.
+func ConnectWith(p profile.Profile, opts ConnectOptions) error {
+	saved := p
+	if len(opts.Unresolved.Addresses) > 0 {
+		saved = opts.Unresolved
 	}
-	session := p.TmuxSession
-	if session == "" {
-		session = "main"
+	p, choice, err := Resolve(p)
+	if err != nil {
+		return err
 	}
-
-	// mosh is not available on Windows natively — skip straight to SSH.
-	if runtime.GOOS == "windows" {
-		fmt.Fprintln(os.Stderr, "→ Windows detected: mosh not supported, using SSH directly")
-		return connectSSH(p, port, session)
+	if choice.Multiple() {
+		fmt.Printf("→ Using %s\n", choice)
 	}
-
-	// Tailscale routing check.
-	switch p.Network {
-	case "tailscale":
-		// Profile explicitly requires Tailscale — fail fast if unavailable.
-		if !tailscale.ClientRunning() {
-			return fmt.Errorf("Tailscale is not running (profile requires network=tailscale)")
-		}
-		if !tailscale.HostInNetwork(p.Host) {
-			return fmt.Errorf("host %q is not in the Tailscale network", p.Host)
-		}
-		fmt.Println("→ Routing via Tailscale")
-	case "direct":
-		// Skip Tailscale and mosh; go straight to SSH.
-	default: // "auto"
-		if tailscale.ClientRunning() && tailscale.HostInNetwork(p.Host) {
-			fmt.Println("→ Tailscale detected: routing via Tailscale network")
-		}
+	if p, err = VerifyHostKey(p); err != nil {
+		return err
 	}
-
-	// Try mosh first (unless network mode is "direct").
-	if p.Network != "direct" {
-		if err := tryMosh(p, port, session); err == nil {
-			return nil
-		} else {
-			if strings.Contains(err.Error(), "UDP port") {
-				fmt.Fprintln(os.Stderr, "⚠  mosh: the server's firewall is blocking UDP ports (60000–61000).")
-				fmt.Fprintln(os.Stderr, "   mosh needs these ports open to maintain a stable, reconnect-friendly session.")
-				fmt.Fprintln(os.Stderr, "   To fix it, run this command on your server:")
-				fmt.Fprintln(os.Stderr, "     sudo ufw allow 60000:61000/udp")
-				fmt.Fprintln(os.Stderr, "→ Falling back to SSH for now.")
-			} else {
-				fmt.Fprintf(os.Stderr, "⚠  mosh failed (%v) — falling back to SSH + tmux.\n", err)
-			}
-		}
+	c, err := newConn(p)
+	if err != nil {
+		return err
 	}
-
-	return connectSSH(p, port, session)
-}
-
-// connectSSH tries ssh+tmux, falls back to ssh, and auto-reconnects on drops.
-// A connection that ran for less than shortConn is considered a startup failure
-// (e.g. tmux not installed) rather than a network drop — those are NOT retried.
-func connectSSH(p profile.Profile, port int, session string) error {
-	const shortConn = 2 * time.Second
-
-	// ── First attempt: ssh+tmux ────────────────────────────────────────────────
-	start := time.Now()
-	err := trySSHTmux(p, port, session)
-	dur := time.Since(start)
-	if err == nil {
-		return nil // clean exit (user quit tmux)
+	if len(saved.Addresses) > 0 {
+		c.Unresolved = saved
 	}
-
-	if dur >= shortConn {
-		// Ran a while then dropped → reconnect with ssh+tmux.
-		return doReconnect(p, port, session, true)
+	if len(c.Hops) > 0 {
+		fmt.Printf("→ Jumping via %s\n", hopLabels(c.Hops))
 	}
-
-	// ssh+tmux exited immediately → tmux likely not installed; fall back to ssh.
-	fmt.Fprintf(os.Stderr, "⚠  ssh+tmux failed (%v) — falling back to a plain SSH session.\n", err)
-	start = time.Now()
-	err = trySSH(p, port)
-	dur = time.Since(start)
-	if err == nil {
-		return nil
+	for _, f := range p.Forwards {
+		if err := f.Validate(); err != nil {
+			return fmt.Errorf("profile %q: %w", p.Name, err)
+		}
 	}
-	if dur < shortConn {
-		return err // never really connected — don't retry
+	chain, err := Chain(p)
+	if err != nil {
+		return err
 	}
-	// Plain SSH ran then dropped → reconnect with ssh-only.
-	return doReconnect(p, port, session, false)
-}
-
-// doReconnect waits for the network and re-establishes the SSH connection.
-func doReconnect(p profile.Profile, port int, session string, useTmux bool) error {
-	const (
-		maxRetries = 10
-		shortConn  = 2 * time.Second
-	)
 
-	fmt.Fprintf(os.Stderr, "\n⚠  Connection to '%s' dropped.\n", p.Name)
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		fmt.Fprint(os.Stderr, "   Waiting for network to come back (Ctrl+C to cancel).")
-		waitForNetwork(p.Host, port)
-		fmt.Fprintf(os.Stderr, "→ Reconnecting... (attempt %d/%d)\n", attempt, maxRetries)
+	// Tailscale routing check.
+	note, err := route(p)
+	if err != nil {
+		return err
+	}
+	if note != "" {
+		fmt.Println("→ " + note)
+	}
 
-		start := time.Now()
-		var err error
-		if useTmux {
-			err = trySSHTmux(p, port, session)
-		} else {
-			err = trySSH(p, port)
+	memory := profile.RememberFailures(p)
+	var failed [][2]string // strategy, reason — remembered if a later one works
+	learn := func(worked string) {
+		for _, f := range failed {
+			_ = learned.Failed(p.Name, c.Network, f[0], f[1])
+		}
+		_ = learned.Worked(p.Name, c.Network, worked)
+	}
+	var lastErr error
+	for i, s := range chain {
+		name := label(s, c)
+		if !opts.RetryAll && i < len(chain)-1 {
+			if reason := remembered(c, s, memory); reason != "" {
+				fmt.Fprintf(os.Stderr, "→ %s skipped: %s — --retry-all to try it anyway\n", name, reason)
+				c.event(history.Event{Kind: history.KindSkip, Strategy: name, Reason: reason})
+				continue
+			}
+		}
+		if err := s.Probe(c); err != nil {
+			fmt.Fprintf(os.Stderr, "→ %s skipped: %v\n", name, err)
+			c.event(history.Event{Kind: history.KindSkip, Strategy: name, Reason: err.Error()})
+			if !isPrecheckErr(s, c) {
+				failed = append(failed, [2]string{s.Name(), err.Error()})
+			}
+			if h, ok := s.(hinter); ok {
+				if hint := h.Hint(err); hint != "" {
+					fmt.Fprintln(os.Stderr, hint)
+				}
+			}
+			lastErr = err
+			continue
 		}
-		dur := time.Since(start)
 
+		start := time.Now()
+		err := s.Run(c)
+		ran := time.Since(start)
 		if err == nil {
-			return nil // clean exit after reconnect
+			c.logRun(name, 0, ran, nil, history.OutcomeClean)
+			learn(s.Name())
+			return nil // clean exit (user quit)
 		}
-		if dur < shortConn {
-			// Reconnect attempt failed immediately — not a network issue.
-			return fmt.Errorf("reconnect failed: %w", err)
+		if s.Classify(c, err, ran) == FailDrop {
+			c.logRun(name, 0, ran, err, history.OutcomeDrop)
+			learn(s.Name())
+			fmt.Fprintf(os.Stderr, "\n⚠  Connection to '%s' dropped.\n", p.Name)
+			return reconnectLoop(c, name, func() error { return s.Run(c) })
 		}
-		// Ran a while and dropped again — loop.
-		fmt.Fprintf(os.Stderr, "\n⚠  Connection dropped again.\n")
+		c.logRun(name, 0, ran, err, history.OutcomeStartup)
+		failed = append(failed, [2]string{s.Name(), err.Error()})
+		c.event(history.Event{Kind: history.KindFallback, Strategy: name, Reason: err.Error()})
+		fmt.Fprintf(os.Stderr, "⚠  %s failed (%v) — trying next strategy.\n", name, err)
+		lastErr = err
 	}
-	return fmt.Errorf("gave up reconnecting to %q after %d attempts", p.Name, maxRetries)
-}
-
-// waitForNetwork polls TCP until the server is reachable again.
-func waitForNetwork(host string, port int) {
-	for !tcpReachable(host, port, 5*time.Second) {
-		fmt.Fprint(os.Stderr, ".")
-		time.Sleep(3 * time.Second)
+	if lastErr == nil {
+		return fmt.Errorf("profile %q: no connection strategy to try", p.Name)
 	}
-	fmt.Fprintln(os.Stderr, " ✓")
+	return lastErr
 }
 
-// tryMosh launches mosh → tmux attach/new.
-func tryMosh(p profile.Profile, port int, tmuxSession string) error {
-	moshBin, err := findMosh()
-	if err != nil {
-		return fmt.Errorf("mosh not found in PATH")
-	}
-
-	// Quick UDP reachability check on default mosh port 60001.
-	if !udpReachable(p.Host, 60001, 2*time.Second) {
-		return fmt.Errorf("UDP port 60001 appears blocked")
+// remembered returns why s is skipped on c's network because it failed here
+// recently, or "" when it should be tried.
+func remembered(c *Conn, s Strategy, memory time.Duration) string {
+	e, ok := learned.Recent(c.Profile.Name, c.Network, s.Name(), memory)
+	if !ok {
+		return ""
 	}
+	return fmt.Sprintf("failed on this network %s ago (%s)", ago(e.Last), e.Reason)
+}
 
-	args := []string{
-		"--ssh=" + buildSSHFlag(p, port),
-	}
-	if p.MoshServer != "" {
-		args = append(args, "--server="+p.MoshServer)
-	}
-	args = append(args, fmt.Sprintf("%s@%s", p.User, p.Host))
-	args = append(args, "--", "tmux", "new-session", "-A", "-s", tmuxSession)
-
-	cmd := exec.Command(moshBin, args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Start(); err != nil {
-		return err
-	}
-	_ = sess.Write(sess.Session{Profile: p.Name, PID: cmd.Process.Pid, Method: "mosh", StartedAt: time.Now()})
-	defer sess.Delete(p.Name)
-	return cmd.Wait()
+// isPrecheckErr reports whether s is ruled out by its offline precheck
+// (OS, profile settings) — a fact about the setup, not the network, so it
+// isn't remembered.
+func isPrecheckErr(s Strategy, c *Conn) bool {
+	pc, ok := s.(prechecker)
+	return ok && pc.Precheck(c) != nil
 }
 
-// trySSHTmux connects via SSH and attaches/creates a tmux session.
-func trySSHTmux(p profile.Profile, port int, tmuxSession string) error {
-	if !tcpReachable(p.Host, port, 5*time.Second) {
-		return fmt.Errorf("TCP port %d unreachable", port)
+// ago formats how long ago t was, to the minute.
+func ago(t time.Time) string {
+	d := time.Since(t)
+	if d < time.Minute {
+		return "<1m"
 	}
+	return strings.TrimSuffix(d.Round(time.Minute).String(), "0s")
+}
 
-	remoteCmd := fmt.Sprintf("tmux new-session -A -s %s", tmuxSession)
-	args := buildSSHBaseArgs(p, port)
-	args = append(args, "-t", fmt.Sprintf("%s@%s", p.User, p.Host))
-	args = append(args, remoteCmd)
-
-	cmd := exec.Command("ssh", args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Start(); err != nil {
-		return err
+// route resolves p's network mode. It fails when the profile requires
+// Tailscale and the host isn't reachable through it, and otherwise returns a
+// note on how traffic is routed ("" when there's nothing to say).
+func route(p profile.Profile) (string, error) {
+	switch p.Network {
+	case "tailscale":
+		// Profile explicitly requires Tailscale — fail fast if unavailable.
+		if !tailscale.ClientRunning() {
+			return "", fmt.Errorf("Tailscale is not running (profile requires network=tailscale)")
+		}
+		if !tailscale.HostInNetwork(p.Host) {
+			return "", fmt.Errorf("host %q is not in the Tailscale network", p.Host)
+		}
+		return "Routing via Tailscale", nil
+	case "direct":
+		// Skip Tailscale; mosh strategies skip themselves.
+		return "", nil
+	default: // "auto"
+		if tailscale.ClientRunning() && tailscale.HostInNetwork(p.Host) {
+			return "Tailscale detected: routing via Tailscale network", nil
+		}
+		return "", nil
 	}
-	_ = sess.Write(sess.Session{Profile: p.Name, PID: cmd.Process.Pid, Method: "ssh+tmux", StartedAt: time.Now()})
-	defer sess.Delete(p.Name)
-	return cmd.Wait()
 }
 
-// trySSH does a plain SSH connection.
-func trySSH(p profile.Profile, port int) error {
-	args := buildSSHBaseArgs(p, port)
-	args = append(args, fmt.Sprintf("%s@%s", p.User, p.Host))
+func buildSSHBaseArgs(p profile.Profile, port int) []string {
+	return append(sshOptions(p, port), p.ForwardArgs()...)
+}
 
-	cmd := exec.Command("ssh", args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Start(); err != nil {
-		return err
-	}
-	_ = sess.Write(sess.Session{Profile: p.Name, PID: cmd.Process.Pid, Method: "ssh", StartedAt: time.Now()})
-	defer sess.Delete(p.Name)
-	return cmd.Wait()
+// sshOptions is buildSSHBaseArgs without the port forwards, for one-off
+// commands that must not compete with a session for the forwarded ports.
+func sshOptions(p profile.Profile, port int) []string {
+	return append([]string{"-p", strconv.Itoa(port)}, sshSettings(p)...)
 }
 
-func buildSSHBaseArgs(p profile.Profile, port int) []string {
+// sshSettings is sshOptions without the port, for tools that spell it
+// differently (scp -P).
+func sshSettings(p profile.Profile) []string {
 	aliveInterval := p.ServerAliveInterval
 	if aliveInterval <= 0 {
 		aliveInterval = 10
@@ -242,9 +233,7 @@ func buildSSHBaseArgs(p profile.Profile, port int) []string {
 		attempts = 3
 	}
 
-	args := []string{
-		"-p", strconv.Itoa(port),
-		"-o", "StrictHostKeyChecking=accept-new",
+	args := append(hostkey.SSHOptions(p),
 		"-o", fmt.Sprintf("ServerAliveInterval=%d", aliveInterval),
 		"-o", fmt.Sprintf("ServerAliveCountMax=%d", aliveCount),
 		"-o", "TCPKeepAlive=yes",
@@ -252,10 +241,14 @@ func buildSSHBaseArgs(p profile.Profile, port int) []string {
 		// Disable multiplexing — causes subtle issues when tmux is involved.
 		"-o", "ControlMaster=no",
 		"-o", "ControlPath=none",
-	}
+	)
 	if p.ForwardAgent {
 		args = append(args, "-o", "ForwardAgent=yes")
 	}
+	args = append(args, JumpArgs(p)...)
+	if p.Transport.Enabled() {
+		args = append(args, "-o", "ProxyCommand="+cloud.ProxyCommand(p.Transport))
+	}
 	key := p.DefaultKey()
 	if _, err := os.Stat(key); err == nil {
 		args = append(args, "-i", key)
@@ -263,9 +256,57 @@ func buildSSHBaseArgs(p profile.Profile, port int) []string {
 	return args
 }
 
+// startForwardChannel runs a background `ssh -N` that only holds p's port
+// forwards, for use next to mosh. It returns once the forwards are up (or
+// ssh has failed); the returned func tears the channel down.
+func startForwardChannel(p profile.Profile, port int) (func(), error) {
+	var stderr bytes.Buffer
+	cmd := forwardChannelCommand(p, port)
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s", msg)
+		}
+		if err == nil {
+			err = fmt.Errorf("ssh exited early")
+		}
+		return nil, err
+	case <-time.After(3 * time.Second):
+	}
+	for _, f := range p.Forwards {
+		fmt.Fprintf(os.Stderr, "→ Forwarding %s\n", f.String())
+	}
+	return func() {
+		_ = cmd.Process.Kill()
+		<-done
+	}, nil
+}
+
+// forwardChannelCommand builds the `ssh -N` that holds p's forwards.
+func forwardChannelCommand(p profile.Profile, port int) *exec.Cmd {
+	args := buildSSHBaseArgs(p, port)
+	// No terminal to prompt on, and a forward that can't bind is a failure.
+	args = append(args, "-N", "-o", "BatchMode=yes", "-o", "ExitOnForwardFailure=yes")
+	args = append(args, fmt.Sprintf("%s@%s", p.User, p.Host))
+	return exec.Command("ssh", args...)
+}
+
 // buildSSHFlag builds the --ssh= value for mosh.
 func buildSSHFlag(p profile.Profile, port int) string {
 	parts := []string{"ssh", "-p", strconv.Itoa(port)}
+	for _, o := range hostkey.SSHOptions(p) {
+		parts = append(parts, shell.Quote(o))
+	}
+	for _, a := range JumpArgs(p) {
+		parts = append(parts, shell.Quote(a))
+	}
 	key := p.DefaultKey()
 	if _, err := os.Stat(key); err == nil {
 		parts = append(parts, "-i", key)
@@ -273,6 +314,68 @@ func buildSSHFlag(p profile.Profile, port int) string {
 	return strings.Join(parts, " ")
 }
 
+// JumpArgs returns the ssh flags that route p through its jump chain, or
+// nil when it has none. Connect validates the chain up front, so resolution
+// errors are ignored here.
+//
+// ssh -J tells the hops nothing but user, host and port, so a chain with a
+// hop that has a key of its own is spelled out as nested ProxyCommands
+// instead, each hop's ssh getting its -i.
+func JumpArgs(p profile.Profile) []string {
+	hops, err := profile.JumpChain(p)
+	if err != nil || len(hops) == 0 {
+		return nil
+	}
+	for _, h := range hops {
+		if h.Key != "" {
+			return []string{"-o", "ProxyCommand=" + hopProxy(hops)}
+		}
+	}
+	return []string{"-J", profile.ProxyJump(hops)}
+}
+
+// hopProxy is the ProxyCommand that reaches the next host through the last
+// of hops, itself reached through the ones before. ssh expands % tokens in
+// a ProxyCommand before running it, so the inner one is escaped once more
+// per level.
+func hopProxy(hops []profile.Hop) string {
+	h := hops[len(hops)-1]
+	parts := []string{"ssh"}
+	if h.Port != 0 && h.Port != 22 {
+		parts = append(parts, "-p", strconv.Itoa(h.Port))
+	}
+	if h.Key != "" {
+		parts = append(parts, "-i", shell.Quote(h.Key))
+	}
+	if len(hops) > 1 {
+		inner := strings.ReplaceAll(hopProxy(hops[:len(hops)-1]), "%", "%%")
+		parts = append(parts, "-o", shell.Quote("ProxyCommand="+inner))
+	}
+	dest := h.Host
+	if h.User != "" {
+		dest = h.User + "@" + dest
+	}
+	return strings.Join(append(parts, "-W", shell.Quote("[%h]:%p"), shell.Quote(dest)), " ")
+}
+
+// entryPoint returns the address that must answer on TCP for p to be
+// reachable: the first jump host when a chain is set, otherwise the target.
+func entryPoint(p profile.Profile, port int) (string, int) {
+	hops, err := profile.JumpChain(p)
+	if err != nil || len(hops) == 0 {
+		return p.Host, port
+	}
+	return profile.EntryPoint(p, hops)
+}
+
+func hopLabels(hops []profile.Hop) string {
+	labels := make([]string, len(hops))
+	for i, h := range hops {
+		labels[i] = h.Label()
+	}
+	return strings.Join(labels, " → ")
+}
+
 func findMosh() (string, error) {
 	if path, err := exec.LookPath("mosh"); err == nil {
 		return path, nil
@@ -300,8 +403,9 @@ func tcpReachable(host string, port int, timeout time.Duration) bool {
 	return true
 }
 
-// udpReachable is a best-effort heuristic. Firewalls that silently drop UDP
-// will still return true — mosh itself will confirm reachability.
+// udpReachable is a best-effort heuristic, used only when the real mosh test
+// (probe.Mosh) can't log in. Firewalls that silently drop UDP will still
+// return true — mosh itself will confirm reachability.
 func udpReachable(host string, port int, timeout time.Duration) bool {
 	conn, err := net.DialTimeout("udp", netAddr(host, port), timeout)
 	if err != nil {
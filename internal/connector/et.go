@@ -0,0 +1,198 @@
+package connector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/shell"
+)
+
+func init() {
+	Register(etTmux{})
+}
+
+// DefaultETPort is where etserver listens unless the profile says otherwise.
+const DefaultETPort = 2022
+
+// errETBlocked is returned by the et probe when etserver's port doesn't
+// answer.
+var errETBlocked = errors.New("etserver port unreachable")
+
+// ── et + tmux ────────────────────────────────────────────────────────────────
+
+// etTmux runs the session over Eternal Terminal: like mosh it survives
+// roaming and sleep, but it is plain TCP, keeps the terminal's scrollback and
+// carries simple port forwards itself. It isn't in the default chain; add
+// it to a profile's strategies.
+type etTmux struct{}
+
+func (etTmux) Name() string       { return "et+tmux" }
+func (etTmux) Requires() []string { return []string{"etserver", "tmux"} }
+
+func (etTmux) Label(c *Conn) string {
+	if c.Mux == "none" {
+		return "et"
+	}
+	return "et+" + c.Mux
+}
+
+func (etTmux) Precheck(c *Conn) error {
+	if runtime.GOOS == "windows" {
+		return fmt.Errorf("et is not supported on Windows")
+	}
+	if _, err := FindET(); err != nil {
+		return fmt.Errorf("et not found in PATH")
+	}
+	return sshOnly(c.Profile)
+}
+
+func (s etTmux) Probe(c *Conn) error {
+	if err := s.Precheck(c); err != nil {
+		return err
+	}
+	// et bootstraps over ssh (through the jump chain if there is one), but
+	// then connects straight to etserver.
+	if host, ePort := entryPoint(c.Profile, c.Port); !tcpReachable(host, ePort, 5*time.Second) {
+		return fmt.Errorf("TCP port %d unreachable on %s", ePort, host)
+	}
+	if !tcpReachable(c.Profile.Host, ETPort(c.Profile), 3*time.Second) {
+		return fmt.Errorf("%w (%s:%d)", errETBlocked, c.Profile.Host, ETPort(c.Profile))
+	}
+	return nil
+}
+
+func (etTmux) Hint(err error) string {
+	if !errors.Is(err, errETBlocked) {
+		return ""
+	}
+	return "⚠  et: nothing answers on etserver's port.\n" +
+		"   Is etserver installed and running?  sshtie install <name> --et\n" +
+		"   Otherwise open its port (2022 unless et_port says otherwise), e.g.:\n" +
+		"     sudo ufw allow 2022/tcp"
+}
+
+func (etTmux) Command(c *Conn) (*exec.Cmd, error) {
+	etBin, err := FindET()
+	if err != nil {
+		return nil, fmt.Errorf("et not found in PATH")
+	}
+	p := c.Profile
+	var args []string
+	if attach := attachArgv(c); attach != nil {
+		args = append(args, "-c", shell.Join(attach))
+	}
+	if tunnels, reverse, ok := etTunnels(p.Forwards); ok {
+		if len(tunnels) > 0 {
+			args = append(args, "-t", strings.Join(tunnels, ","))
+		}
+		if len(reverse) > 0 {
+			args = append(args, "-r", strings.Join(reverse, ","))
+		}
+	}
+	for _, o := range etSSHOptions(p, c.Port) {
+		args = append(args, "--ssh-option", o)
+	}
+	args = append(args, fmt.Sprintf("%s:%d", c.Target(), ETPort(p)))
+	return exec.Command(etBin, args...), nil
+}
+
+func (etTmux) Auxiliary(c *Conn) []*exec.Cmd {
+	if _, _, ok := etTunnels(c.Profile.Forwards); ok {
+		return nil
+	}
+	return []*exec.Cmd{forwardChannelCommand(c.Profile, c.Port)}
+}
+
+func (s etTmux) Run(c *Conn) error {
+	// et only tunnels port to port on localhost; anything else (SOCKS,
+	// another destination host, a bind address) goes over a side-channel
+	// ssh, as with mosh.
+	if _, _, ok := etTunnels(c.Profile.Forwards); !ok {
+		fmt.Fprintln(os.Stderr, "⚠  et cannot carry these port forwards — opening them over a side-channel ssh")
+		stop, err := startForwardChannel(c.Profile, c.Port)
+		if err != nil {
+			return fmt.Errorf("side-channel ssh for port forwards failed (%v)", err)
+		}
+		defer stop()
+	}
+	cmd, err := s.Command(c)
+	if err != nil {
+		return err
+	}
+	return runSession(c, label(s, c), cmd)
+}
+
+func (etTmux) Classify(c *Conn, err error, ran time.Duration) Failure {
+	return classifyByDuration(c, err, ran)
+}
+
+// ETPort is the port etserver listens on for p.
+func ETPort(p profile.Profile) int {
+	if p.ETPort > 0 {
+		return p.ETPort
+	}
+	return DefaultETPort
+}
+
+// FindET locates the local Eternal Terminal client.
+func FindET() (string, error) {
+	if path, err := exec.LookPath("et"); err == nil {
+		return path, nil
+	}
+	for _, c := range []string{"/opt/homebrew/bin/et", "/usr/local/bin/et", "/usr/bin/et"} {
+		if _, err := os.Stat(c); err == nil {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("et not found")
+}
+
+// etTunnels translates forwards into et's -t and -r lists. et can only
+// forward a local port to the same host's port, so ok is false as soon as
+// one forward is anything else.
+func etTunnels(forwards []profile.Forward) (tunnels, reverse []string, ok bool) {
+	for _, f := range forwards {
+		var spec string
+		var list *[]string
+		switch {
+		case f.Local != "":
+			spec, list = f.Local, &tunnels
+		case f.Remote != "":
+			spec, list = f.Remote, &reverse
+		default:
+			return nil, nil, false
+		}
+		parts := strings.Split(spec, ":")
+		if len(parts) != 3 || (parts[1] != "localhost" && parts[1] != "127.0.0.1") {
+			return nil, nil, false
+		}
+		*list = append(*list, parts[0]+":"+parts[2])
+	}
+	return tunnels, reverse, true
+}
+
+// etSSHOptions turns the ssh flags sshtie uses everywhere into the
+// ssh_config options et passes to its bootstrap ssh (--ssh-option).
+func etSSHOptions(p profile.Profile, port int) []string {
+	var out []string
+	args := sshOptions(p, port)
+	for i := 0; i+1 < len(args); i += 2 {
+		switch v := args[i+1]; args[i] {
+		case "-o":
+			out = append(out, v)
+		case "-p":
+			out = append(out, "Port="+v)
+		case "-J":
+			out = append(out, "ProxyJump="+v)
+		case "-i":
+			out = append(out, "IdentityFile="+v)
+		}
+	}
+	return out
+}
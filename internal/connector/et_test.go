@@ -0,0 +1,89 @@
+package connector
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/shell"
+)
+
+func TestETCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("et is not supported on Windows")
+	}
+	t.Setenv("HOME", t.TempDir())
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "et"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	p := profile.Profile{
+		Name: "db", Host: "10.0.0.5", User: "alice", Port: 2200, ETPort: 2023,
+		Strategies: []string{"et+tmux", "ssh"},
+		Forwards:   []profile.Forward{{Local: "5432:localhost:5432"}, {Remote: "8080:127.0.0.1:3000"}},
+	}
+	steps, err := Plan(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps[0].Skip != "" || steps[0].Label != "et+tmux" || strings.Join(steps[0].Requires, ",") != "etserver,tmux" {
+		t.Fatalf("et step = %+v", steps[0])
+	}
+
+	pv, err := NewPreview(p, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := pv.Steps[0]
+	line := shell.Join(st.Argv)
+	for _, want := range []string{
+		"-c 'tmux new-session -A -s main'",
+		"-t 5432:5432", "-r 8080:3000",
+		"--ssh-option Port=2200", "--ssh-option StrictHostKeyChecking=accept-new",
+		"alice@10.0.0.5:2023",
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("et command %q lacks %q", line, want)
+		}
+	}
+	if len(st.Auxiliary) != 0 {
+		t.Errorf("localhost forwards: auxiliary = %v", st.Auxiliary)
+	}
+
+	// A SOCKS forward can't go through et: all forwards move to ssh -N.
+	p.Forwards = append(p.Forwards, profile.Forward{Dynamic: "1080"})
+	if pv, err = NewPreview(p, false); err != nil {
+		t.Fatal(err)
+	}
+	if line := shell.Join(pv.Steps[0].Argv); strings.Contains(line, "-t ") || len(pv.Steps[0].Auxiliary) != 1 {
+		t.Errorf("with SOCKS: command %q, auxiliary %v", line, pv.Steps[0].Auxiliary)
+	}
+
+	p.Multiplexer = "none"
+	if pv, err = NewPreview(p, false); err != nil {
+		t.Fatal(err)
+	}
+	if st := pv.Steps[0]; st.Label != "et" || strings.Contains(shell.Join(st.Argv), " -c ") {
+		t.Errorf("multiplexer none: %s %q", st.Label, shell.Join(st.Argv))
+	}
+}
+
+func TestETPrecheck_noClient(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("PATH", t.TempDir())
+	if _, err := FindET(); err == nil {
+		t.Skip("et installed in a standard location")
+	}
+	steps, err := Plan(profile.Profile{Name: "a", Host: "h", Strategies: []string{"et+tmux", "ssh"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps[0].Skip == "" {
+		t.Errorf("et step without a client = %+v, want skipped", steps[0])
+	}
+}
@@ -0,0 +1,177 @@
+package connector
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/ainsuotain/sshtie/internal/hostkey"
+	"github.com/ainsuotain/sshtie/internal/locate"
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+// ExecResult is the outcome of running one command on one profile.
+type ExecResult struct {
+	Profile  string        `json:"profile"`
+	Host     string        `json:"host"`
+	ExitCode int           `json:"exit_code"` // -1 when ssh never reported one
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"-"`
+	Seconds  float64       `json:"duration_s"`
+	Stdout   string        `json:"stdout"`
+	Stderr   string        `json:"stderr"`
+}
+
+// OK reports whether the command exited 0.
+func (r ExecResult) OK() bool { return r.ExitCode == 0 && r.Error == "" }
+
+// ExecOptions controls ExecAll.
+type ExecOptions struct {
+	Concurrency int           // hosts run at once; <= 0 means all
+	Timeout     time.Duration // per host; 0 = none
+	// Output, when set, receives each host's output live, one line at a
+	// time: stream is "stdout" or "stderr".
+	Output func(profile, stream, line string)
+}
+
+// ExecCommand builds a non-interactive `ssh … <remote>` for p using the
+// profile's SSH options (jump chain, key, keepalives) but not its port
+// forwards. remote is passed to ssh as-is.
+func ExecCommand(ctx context.Context, p profile.Profile, remote []string) (*exec.Cmd, error) {
+	if _, err := profile.JumpChain(p); err != nil {
+		return nil, err
+	}
+	if err := profile.ValidateTransport(p); err != nil {
+		return nil, err
+	}
+	port := p.Port
+	if port == 0 {
+		port = 22
+	}
+	args := sshOptions(p, port)
+	// Nobody is there to answer a password or host-key prompt.
+	args = append(args, "-o", "BatchMode=yes", "-T")
+	args = append(args, fmt.Sprintf("%s@%s", p.User, p.Host), "--")
+	args = append(args, remote...)
+	return exec.CommandContext(ctx, "ssh", args...), nil
+}
+
+// ExecAll runs remote on every profile in parallel and returns the results
+// in the order of profiles.
+func ExecAll(profiles []profile.Profile, remote []string, opts ExecOptions) []ExecResult {
+	limit := opts.Concurrency
+	if limit <= 0 || limit > len(profiles) {
+		limit = len(profiles)
+	}
+	results := make([]ExecResult, len(profiles))
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, p := range profiles {
+		wg.Add(1)
+		go func(i int, p profile.Profile) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = execOne(p, remote, opts)
+		}(i, p)
+	}
+	wg.Wait()
+	return results
+}
+
+func execOne(p profile.Profile, remote []string, opts ExecOptions) ExecResult {
+	p, _, err := locate.Apply(p)
+	res := ExecResult{Profile: p.Name, Host: p.Host, ExitCode: -1}
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if p, err = EnsureHostKey(p); err != nil {
+		res.Error = err.Error()
+		var changed *hostkey.ChangedError
+		if errors.As(err, &changed) {
+			res.Error += " — if that is expected, trust the new key with: sshtie hostkey pin " + p.Name
+		}
+		return res
+	}
+	ctx := context.Background()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	cmd, err := ExecCommand(ctx, p, remote)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if opts.Output != nil {
+		outLines := &lineWriter{emit: func(l string) { opts.Output(p.Name, "stdout", l) }}
+		errLines := &lineWriter{emit: func(l string) { opts.Output(p.Name, "stderr", l) }}
+		defer outLines.Flush()
+		defer errLines.Flush()
+		cmd.Stdout = io.MultiWriter(&stdout, outLines)
+		cmd.Stderr = io.MultiWriter(&stderr, errLines)
+	}
+	// Don't let a backgrounded remote process keep Wait hanging past the
+	// deadline.
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	err = cmd.Run()
+	res.Duration = time.Since(start)
+	res.Seconds = res.Duration.Seconds()
+	res.Stdout, res.Stderr = stdout.String(), stderr.String()
+
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		res.Error = fmt.Sprintf("timed out after %s", opts.Timeout)
+	case err == nil:
+		res.ExitCode = 0
+	case errors.As(err, &exitErr):
+		res.ExitCode = exitErr.ExitCode()
+		if res.ExitCode == 255 {
+			res.Error = "ssh connection failed"
+		}
+	default:
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// lineWriter splits written bytes into lines for emit, buffering a trailing
+// partial line until it is completed or flushed.
+type lineWriter struct {
+	emit func(string)
+	buf  []byte
+}
+
+func (w *lineWriter) Write(b []byte) (int, error) {
+	w.buf = append(w.buf, b...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(string(bytes.TrimRight(w.buf[:i], "\r")))
+		w.buf = w.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// Flush emits any unterminated last line.
+func (w *lineWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.emit(string(w.buf))
+		w.buf = nil
+	}
+}
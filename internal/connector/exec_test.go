@@ -0,0 +1,82 @@
+package connector
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+// stubSSH puts a fake ssh on PATH that behaves according to the target host.
+func stubSSH(t *testing.T, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell stub")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ssh"), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestExecAll(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	stubSSH(t, `case "$*" in
+  *u@ok*) printf 'line1\nline2'; exit 0;;
+  *u@bad*) echo oops >&2; exit 3;;
+  *u@slow*) exec sleep 5;;
+esac
+`)
+	profiles := []profile.Profile{
+		{Name: "ok", Host: "ok", User: "u", Forwards: []profile.Forward{{Dynamic: "1080"}}},
+		{Name: "bad", Host: "bad", User: "u"},
+		{Name: "slow", Host: "slow", User: "u"},
+	}
+	var (
+		mu    sync.Mutex
+		lines []string
+	)
+	res := ExecAll(profiles, []string{"uptime"}, ExecOptions{
+		Concurrency: 2,
+		Timeout:     500 * time.Millisecond,
+		Output: func(name, stream, line string) {
+			mu.Lock()
+			defer mu.Unlock()
+			lines = append(lines, name+"/"+stream+"/"+line)
+		},
+	})
+
+	if !res[0].OK() || res[0].Stdout != "line1\nline2" {
+		t.Errorf("ok = %+v", res[0])
+	}
+	if res[1].ExitCode != 3 || res[1].OK() || strings.TrimSpace(res[1].Stderr) != "oops" {
+		t.Errorf("bad = %+v", res[1])
+	}
+	if res[2].OK() || !strings.Contains(res[2].Error, "timed out") {
+		t.Errorf("slow = %+v", res[2])
+	}
+	joined := strings.Join(lines, "|")
+	for _, want := range []string{"ok/stdout/line1", "ok/stdout/line2", "bad/stderr/oops"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("output lines %q lack %q", joined, want)
+		}
+	}
+}
+
+func TestExecCommand_noForwards(t *testing.T) {
+	p := profile.Profile{Name: "a", Host: "h", User: "u", Forwards: []profile.Forward{{Local: "1:h:2"}}}
+	cmd, err := ExecCommand(t.Context(), p, []string{"uptime"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := strings.Join(cmd.Args, " ")
+	if strings.Contains(line, "-L") || !strings.HasSuffix(line, "BatchMode=yes -T u@h -- uptime") {
+		t.Errorf("command = %q", line)
+	}
+}
@@ -0,0 +1,152 @@
+package connector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/ainsuotain/sshtie/internal/hostkey"
+	"github.com/ainsuotain/sshtie/internal/probe"
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+// hostKeyTimeout bounds the host key check before a connect.
+const hostKeyTimeout = 8 * time.Second
+
+// HostKeyCheck is what CheckHostKey learned about a server's host keys.
+type HostKeyCheck struct {
+	Presented []ssh.PublicKey // keys the server offered
+	New       bool            // nothing pinned yet: Presented is what would be pinned
+	Missing   bool            // pinned, but this machine's managed known_hosts lacks the keys
+	Changed   *hostkey.ChangedError
+}
+
+// CheckHostKey fetches the host keys p's server presents and compares them
+// with its pins — or, for a profile without pins, with ~/.ssh/known_hosts.
+// It changes nothing. Errors mean the server couldn't be asked (offline,
+// or a jump host refused the login).
+func CheckHostKey(p profile.Profile) (HostKeyCheck, error) {
+	opts := probe.Options{Timeout: hostKeyTimeout}
+	addr := netAddr(p.Host, portOf(p))
+
+	if trusted := hostkey.TrustedKeys(p); len(trusted) > 0 {
+		// Usual case: one handshake, asking for the pinned key type.
+		k, err := probe.HostKey(p, opts)
+		if err == nil && hostkey.Matches(p, k) {
+			return HostKeyCheck{Presented: []ssh.PublicKey{k}}, nil
+		}
+		var ne *ssh.AlgorithmNegotiationError
+		if err != nil && !errors.As(err, &ne) {
+			return HostKeyCheck{}, err
+		}
+		// Different key, or none of the pinned type: see everything it has.
+	}
+
+	keys, err := probe.HostKeys(p, opts)
+	if err != nil {
+		return HostKeyCheck{}, err
+	}
+	chk := HostKeyCheck{Presented: keys}
+	if !hostkey.Pinned(p) {
+		chk.New = true
+		if err := hostkey.KnownElsewhere(p.Name, addr, keys); err != nil {
+			errors.As(err, &chk.Changed)
+		}
+		return chk, nil
+	}
+	if err := hostkey.Verify(p, addr, keys); err != nil {
+		errors.As(err, &chk.Changed)
+		return chk, nil
+	}
+	chk.Missing = len(hostkey.TrustedKeys(p)) == 0
+	return chk, nil
+}
+
+// EnsureHostKey checks p's host key before ssh runs and returns p with its
+// pins up to date:
+//
+//   - a key that contradicts a pin (or ~/.ssh/known_hosts) is a
+//     *hostkey.ChangedError, and nothing connects;
+//   - a profile without pins gets the presented keys pinned (trust on first
+//     use);
+//   - pinned keys missing from this machine's managed known_hosts are filled
+//     in from the matching presented keys.
+//
+// When the server can't be asked, p is returned as is: ssh still checks
+// what it can, and reports the connection problem itself. The exception is
+// a pinned profile whose keys aren't in the managed known_hosts yet — ssh
+// would have nothing to check them against, so that is an error.
+func EnsureHostKey(p profile.Profile) (profile.Profile, error) {
+	chk, err := CheckHostKey(p)
+	if err != nil {
+		if hostkey.Pinned(p) && len(hostkey.TrustedKeys(p)) == 0 {
+			return p, fmt.Errorf("can't check the pinned host key of %q: %w", p.Name, err)
+		}
+		return p, nil
+	}
+	return ApplyHostKeyCheck(p, chk)
+}
+
+// VerifyHostKey is EnsureHostKey for commands run from a terminal: a
+// changed key is also explained on stderr (see ChangedHelp).
+func VerifyHostKey(p profile.Profile) (profile.Profile, error) {
+	p, err := EnsureHostKey(p)
+	var changed *hostkey.ChangedError
+	if errors.As(err, &changed) {
+		fmt.Fprintln(os.Stderr, ChangedHelp(changed))
+	}
+	return p, err
+}
+
+// ApplyHostKeyCheck acts on a CheckHostKey result as EnsureHostKey does.
+func ApplyHostKeyCheck(p profile.Profile, chk HostKeyCheck) (profile.Profile, error) {
+	switch {
+	case chk.Changed != nil:
+		return p, chk.Changed
+	case chk.New:
+		if err := hostkey.Pin(p.Name, chk.Presented); err != nil {
+			return p, fmt.Errorf("pin host key: %w", err)
+		}
+		p.HostKeys = hostkey.Fingerprints(chk.Presented)
+		fmt.Fprintf(os.Stderr, "→ Pinned host key of %s: %s\n", p.Name, strings.Join(p.HostKeys, ", "))
+	case chk.Missing:
+		var keep []ssh.PublicKey
+		for _, k := range chk.Presented {
+			if hostkey.Matches(p, k) {
+				keep = append(keep, k)
+			}
+		}
+		if err := hostkey.Store(p.Name, keep); err != nil {
+			return p, fmt.Errorf("store host key: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// ChangedHelp explains a changed host key and what to do about it, for
+// command-line output.
+func ChangedHelp(e *hostkey.ChangedError) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "⚠ The host key of %s (%s) has changed.\n\n", e.Profile, e.Addr)
+	src := "pinned in profiles.yaml"
+	if e.Source != "profile" {
+		src = "in " + e.Source
+	}
+	fmt.Fprintf(&b, "  Expected (%s):\n", src)
+	for _, fp := range e.Expected {
+		fmt.Fprintf(&b, "    %s\n", fp)
+	}
+	b.WriteString("  Presented now:\n")
+	for _, fp := range e.Presented {
+		fmt.Fprintf(&b, "    %s\n", fp)
+	}
+	b.WriteString("\n  This happens when the server was reinstalled or its keys regenerated —\n" +
+		"  or when something between you and it is pretending to be the server.\n" +
+		"  Check the fingerprint on the server itself (ssh-keygen -lf /etc/ssh/ssh_host_ed25519_key.pub),\n")
+	fmt.Fprintf(&b, "  then trust the new key with:  sshtie hostkey pin %s", e.Profile)
+	return b.String()
+}
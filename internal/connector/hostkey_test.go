@@ -0,0 +1,47 @@
+package connector
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+// A pinned profile whose keys this machine's managed known_hosts lacks must
+// never fall back to accepting whatever key ssh is shown.
+func TestEnsureHostKey_pinnedWithoutManagedKeys(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	// A port nothing listens on: the server can't be asked.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	p := profile.Profile{Name: "web", Host: "127.0.0.1", Port: port, User: "u"}
+	if _, err := EnsureHostKey(p); err != nil {
+		t.Errorf("unpinned, offline: %v, want ssh left to report it", err)
+	}
+
+	p.HostKeys = []string{"SHA256:uNiVztksCsDhcc0u9e8BujQXVUpKZIDTMczCvj3tD2s"}
+	if _, err := EnsureHostKey(p); err == nil || !strings.Contains(err.Error(), "pinned host key") {
+		t.Errorf("pinned, empty known_hosts, offline: err = %v, want a refusal", err)
+	}
+
+	cmd, err := ExecCommand(t.Context(), p, []string{"true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := strings.Join(cmd.Args, " ")
+	if !strings.Contains(args, "StrictHostKeyChecking=yes") || strings.Contains(args, "accept-new") {
+		t.Errorf("pinned profile ssh args = %s, want strict checking", args)
+	}
+	if !strings.Contains(args, "HostKeyAlias=sshtie.web") || !strings.Contains(args, "-p "+strconv.Itoa(port)) {
+		t.Errorf("ssh args = %s", args)
+	}
+}
@@ -0,0 +1,87 @@
+package connector
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+// fakeStrategy counts runs and fails at startup when fail is set.
+type fakeStrategy struct {
+	name string
+	fail bool
+	runs *int
+}
+
+func (f fakeStrategy) Name() string                     { return f.name }
+func (f fakeStrategy) Requires() []string               { return nil }
+func (f fakeStrategy) Probe(*Conn) error                { return nil }
+func (f fakeStrategy) Command(*Conn) (*exec.Cmd, error) { return exec.Command("true"), nil }
+func (f fakeStrategy) Classify(*Conn, error, time.Duration) Failure {
+	return FailStartup
+}
+func (f fakeStrategy) Run(*Conn) error {
+	*f.runs++
+	if f.fail {
+		return errors.New("no route")
+	}
+	return nil
+}
+
+func TestConnect_remembersFailuresPerNetwork(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+	var flakyRuns, solidRuns int
+	Register(fakeStrategy{name: "test-flaky", fail: true, runs: &flakyRuns})
+	Register(fakeStrategy{name: "test-solid", runs: &solidRuns})
+	defer delete(registry, "test-flaky")
+	defer delete(registry, "test-solid")
+	p := profile.Profile{Name: "srv", Host: "h", Network: "direct", Strategies: []string{"test-flaky", "test-solid"}}
+
+	for i := 0; i < 2; i++ {
+		if err := Connect(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if flakyRuns != 1 || solidRuns != 2 {
+		t.Errorf("runs: flaky=%d solid=%d, want 1 and 2 (flaky skipped the second time)", flakyRuns, solidRuns)
+	}
+
+	if err := ConnectWith(p, ConnectOptions{RetryAll: true}); err != nil {
+		t.Fatal(err)
+	}
+	if flakyRuns != 2 {
+		t.Errorf("--retry-all: flaky runs = %d, want 2", flakyRuns)
+	}
+
+	p.RememberFailures = -1
+	if err := Connect(p); err != nil {
+		t.Fatal(err)
+	}
+	if flakyRuns != 3 {
+		t.Errorf("memory off: flaky runs = %d, want 3", flakyRuns)
+	}
+}
+
+func TestConnect_downServerTeachesNothing(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+	var a, b int
+	Register(fakeStrategy{name: "test-a", fail: true, runs: &a})
+	Register(fakeStrategy{name: "test-b", fail: true, runs: &b})
+	defer delete(registry, "test-a")
+	defer delete(registry, "test-b")
+	p := profile.Profile{Name: "srv", Host: "h", Network: "direct", Strategies: []string{"test-a", "test-b"}}
+
+	for i := 0; i < 2; i++ {
+		if err := Connect(p); err == nil {
+			t.Fatal("want error when every strategy fails")
+		}
+	}
+	if a != 2 {
+		t.Errorf("test-a runs = %d, want 2 (nothing worked, so nothing learned)", a)
+	}
+}
@@ -0,0 +1,104 @@
+package connector
+
+import (
+	"github.com/ainsuotain/sshtie/internal/locate"
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+// Preview is what Connect would do for a profile, worked out without
+// opening a session: the routing decision and, for every strategy in the
+// chain, the exact command it would run or the reason it would be skipped.
+type Preview struct {
+	Address  string // candidate address picked ("" = the profile has just one)
+	Network  string // auto | tailscale | direct
+	Route    string // how traffic is routed ("" = nothing special)
+	RouteErr error  // set when Connect would refuse to start
+	Jump     string // jump chain, "" when direct
+	Steps    []PreviewStep
+}
+
+// PreviewStep is one strategy of a Preview.
+type PreviewStep struct {
+	Name      string
+	Label     string     // Name as shown for this profile (mosh+screen, …)
+	Argv      []string   // the session command
+	Auxiliary [][]string // helper processes started next to it
+	Skip      string     // why Probe would skip it ("" = it would be tried)
+	Err       error      // the command could not be built
+}
+
+// Viable reports whether Connect would actually try this step.
+func (s PreviewStep) Viable() bool {
+	return s.Skip == "" && s.Err == nil
+}
+
+// NewPreview resolves p's routing and strategy chain. With probe set, each
+// strategy's Probe runs as a dry run (reachability checks and the cached
+// mosh verdict, never a fresh mosh test) and
+// remembered failures apply, so the skip reasons are the ones Connect would
+// hit right now, and a profile with several addresses is pointed at the one
+// that answers; without it only the offline prechecks apply.
+func NewPreview(p profile.Profile, probe bool) (*Preview, error) {
+	pv := &Preview{Network: p.Network}
+	if probe {
+		var choice locate.Choice
+		var err error
+		if p, choice, err = locate.Apply(p); err != nil {
+			return nil, err
+		}
+		if choice.Multiple() {
+			pv.Address = choice.String()
+		}
+	}
+	c, err := newConn(p)
+	if err != nil {
+		return nil, err
+	}
+	c.DryRun = true
+	for _, f := range p.Forwards {
+		if err := f.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	chain, err := Chain(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if pv.Network == "" {
+		pv.Network = "auto"
+	}
+	pv.Route, pv.RouteErr = route(p)
+	if len(c.Hops) > 0 {
+		pv.Jump = hopLabels(c.Hops)
+	}
+
+	memory := profile.RememberFailures(p)
+	for i, s := range chain {
+		st := PreviewStep{Name: s.Name(), Label: label(s, c)}
+		if probe {
+			if reason := remembered(c, s, memory); reason != "" && i < len(chain)-1 {
+				st.Skip = reason
+			} else if err := s.Probe(c); err != nil {
+				st.Skip = err.Error()
+			}
+		} else if pc, ok := s.(prechecker); ok {
+			if err := pc.Precheck(c); err != nil {
+				st.Skip = err.Error()
+			}
+		}
+		cmd, err := s.Command(c)
+		if err != nil {
+			st.Err = err
+		} else {
+			st.Argv = cmd.Args
+		}
+		if a, ok := s.(auxiliary); ok {
+			for _, aux := range a.Auxiliary(c) {
+				st.Auxiliary = append(st.Auxiliary, aux.Args)
+			}
+		}
+		pv.Steps = append(pv.Steps, st)
+	}
+	return pv, nil
+}
@@ -0,0 +1,134 @@
+package connector
+
+import (
+	"crypto/ed25519"
+	"encoding/pem"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/ainsuotain/sshtie/internal/netenv"
+	"github.com/ainsuotain/sshtie/internal/probe"
+	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/shell"
+)
+
+func TestNewPreview_offline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	p := profile.Profile{
+		Name: "db", Host: "10.0.0.5", User: "alice", Network: "direct",
+		Strategies: []string{"ssh+tmux", "ssh"},
+		Forwards:   []profile.Forward{{Local: "5432:localhost:5432"}},
+	}
+	pv, err := NewPreview(p, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pv.Steps) != 2 || !pv.Steps[0].Viable() {
+		t.Fatalf("steps = %+v", pv.Steps)
+	}
+	line := shell.Join(pv.Steps[0].Argv)
+	for _, want := range []string{"ssh -p 22 ", "-L 5432:localhost:5432", "-t alice@10.0.0.5 'tmux new-session -A -s main'"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("ssh+tmux command %q lacks %q", line, want)
+		}
+	}
+}
+
+func TestJumpArgs_hopKeys(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, p := range []profile.Profile{
+		{Name: "edge", Host: "edge.example.com", User: "ops", Key: "~/.ssh/edge"},
+		{Name: "inner", Host: "10.0.0.2", User: "ops", Port: 2222, Jump: []string{"edge"}},
+		{Name: "db", Host: "10.0.1.5", User: "alice", Jump: []string{"inner"}},
+		{Name: "plain", Host: "10.0.1.6", User: "alice", Jump: []string{"ops@jump.example.com"}},
+	} {
+		if err := profile.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db, _ := profile.Get("db")
+	args := JumpArgs(db)
+	if len(args) != 2 || args[0] != "-o" || !strings.HasPrefix(args[1], "ProxyCommand=ssh -p 2222 ") {
+		t.Fatalf("JumpArgs = %q, want a ProxyCommand through inner", args)
+	}
+	// edge is reached first, by the inner hop's ssh, with edge's key; its
+	// % tokens are escaped so they survive the outer expansion.
+	for _, want := range []string{"-i " + filepath.Join(home, ".ssh", "edge"), "[%%h]:%%p", "ops@edge.example.com", "'[%h]:%p' ops@10.0.0.2"} {
+		if !strings.Contains(args[1], want) {
+			t.Errorf("%s lacks %q", args[1], want)
+		}
+	}
+
+	pv, err := NewPreview(db, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range pv.Steps {
+		if st.Viable() && !strings.Contains(shell.Join(st.Argv), filepath.Join(home, ".ssh", "edge")) {
+			t.Errorf("%s command %q lacks the hop key", st.Name, shell.Join(st.Argv))
+		}
+	}
+
+	plain, _ := profile.Get("plain")
+	if got := strings.Join(JumpArgs(plain), " "); got != "-J ops@jump.example.com" {
+		t.Errorf("JumpArgs without hop keys = %q, want plain -J", got)
+	}
+}
+
+func TestMoshReachable_dryRunDoesNotLogIn(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SSH_AUTH_SOCK", "")
+	// A usable key, so only the dry run keeps the mosh test from dialling.
+	_, priv, _ := ed25519.GenerateKey(nil)
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := filepath.Join(home, "id_test")
+	if err := os.WriteFile(key, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	var logins atomic.Int32
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			logins.Add(1)
+			nc.Close()
+		}
+	}()
+	port := ln.Addr().(*net.TCPAddr).Port
+	n := netenv.Network{Gateway: "192.168.1.1", Subnet: "192.168.1.0/24"}
+	c := &Conn{Profile: profile.Profile{Name: "db", Host: "127.0.0.1", User: "u", Port: port, Key: key},
+		Port: port, Network: n, DryRun: true}
+
+	_ = moshReachable(c) // no verdict yet: the UDP heuristic decides
+	time.Sleep(50 * time.Millisecond)
+	if logins.Load() != 0 {
+		t.Errorf("dry run connected to the server %d times", logins.Load())
+	}
+
+	if err := probe.RecordMosh("db", n, probe.MoshVerdict{Reason: "no reply"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := moshReachable(c); !errors.Is(err, errUDPBlocked) {
+		t.Errorf("cached failure: err = %v, want UDP blocked", err)
+	}
+}
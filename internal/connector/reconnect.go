@@ -0,0 +1,186 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ainsuotain/sshtie/internal/history"
+	"github.com/ainsuotain/sshtie/internal/locate"
+)
+
+// reconnectLoop waits for the server to be reachable again and re-runs attempt
+// until it exits cleanly, following c.Policy: attempts are capped at
+// MaxAttempts (unless unlimited), the whole loop is abandoned Deadline after
+// the first drop,
+// and an attempt that fails within StartupThreshold is not a network drop
+// and ends the loop — unless c is Persistent, when it is retried after a
+// backoff.
+//
+// Each attempt is recorded in the history under strategy.
+func reconnectLoop(c *Conn, strategy string, attempt func() error) error {
+	pol := c.Policy
+	quick := 0 // attempts in a row that failed straight away
+	var giveUpAt time.Time
+	if pol.Deadline > 0 {
+		giveUpAt = time.Now().Add(pol.Deadline)
+	}
+
+	for n := 1; pol.Unlimited() || n <= pol.MaxAttempts; n++ {
+		droppedAt := time.Now()
+		fmt.Fprint(os.Stderr, "   Waiting for network to come back (Ctrl+C to cancel).")
+		if err := waitForNetwork(droppedAt, giveUpAt, c); err != nil {
+			c.event(history.Event{Kind: history.KindGiveUp, Strategy: strategy, Reason: err.Error(),
+				Downtime: time.Since(droppedAt).Seconds()})
+			return err
+		}
+		c.event(history.Event{Kind: history.KindReconnect, Strategy: strategy, Attempt: n,
+			Downtime: time.Since(droppedAt).Seconds()})
+		down := time.Since(droppedAt).Round(time.Second)
+		if pol.Unlimited() {
+			fmt.Fprintf(os.Stderr, "→ Reconnecting after %s down... (attempt %d)\n", down, n)
+		} else {
+			fmt.Fprintf(os.Stderr, "→ Reconnecting after %s down... (attempt %d/%d)\n", down, n, pol.MaxAttempts)
+		}
+
+		start := time.Now()
+		err := attempt()
+		dur := time.Since(start)
+
+		if err == nil {
+			c.logRun(strategy, n, dur, nil, history.OutcomeClean)
+			return nil // clean exit after reconnect
+		}
+		if dur < pol.StartupThreshold {
+			c.logRun(strategy, n, dur, err, history.OutcomeStartup)
+			if !c.Persistent {
+				// Reconnect attempt failed immediately — not a network issue.
+				return fmt.Errorf("reconnect failed: %w", err)
+			}
+			// Right after a sleep the old session may still hold a remote
+			// -R port, or DNS isn't up yet: back off and try again.
+			wait := backoff(pol.Backoff, pol.MaxBackoff, quick)
+			if !giveUpAt.IsZero() {
+				wait = min(wait, max(time.Until(giveUpAt), 0))
+			}
+			quick++
+			fmt.Fprintf(os.Stderr, "⚠  Reconnect failed (%v) — retrying in %s.\n", err, wait.Round(time.Second))
+			if err := pause(wait); err != nil {
+				err = fmt.Errorf("reconnect to %q %w", c.Profile.Name, err)
+				c.event(history.Event{Kind: history.KindGiveUp, Strategy: strategy, Reason: err.Error()})
+				return err
+			}
+			continue // waitForNetwork gives up once giveUpAt has passed
+		}
+		quick = 0
+		// Ran a while and dropped again — loop.
+		c.logRun(strategy, n, dur, err, history.OutcomeDrop)
+		fmt.Fprintf(os.Stderr, "\n⚠  Connection dropped again.\n")
+	}
+	err := fmt.Errorf("gave up reconnecting to %q after %d attempts", c.Profile.Name, pol.MaxAttempts)
+	c.event(history.Event{Kind: history.KindGiveUp, Strategy: strategy, Reason: err.Error()})
+	return err
+}
+
+// errCancelled is returned when Ctrl+C or SIGTERM ends a wait.
+var errCancelled = errors.New("cancelled")
+
+// pause sleeps for d unless Ctrl+C or SIGTERM (`sshtie tunnel down`) comes
+// first.
+func pause(d time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return errCancelled
+	}
+}
+
+// waitForNetwork polls until the server is reachable (see reachable),
+// backing off between polls. Ctrl+C cancels the wait, and so does reaching
+// giveUpAt (unless it is zero).
+func waitForNetwork(droppedAt, giveUpAt time.Time, c *Conn) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if !giveUpAt.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, giveUpAt)
+		defer cancel()
+	}
+
+	was := netAddr(c.Profile.Host, c.Port)
+	if err := poll(ctx, c.reach, c.Policy.Backoff, c.Policy.MaxBackoff); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("gave up reconnecting to %q: deadline of %s since the first drop passed",
+				c.Profile.Name, c.Policy.Deadline)
+		}
+		return fmt.Errorf("reconnect to %q cancelled after %s down",
+			c.Profile.Name, time.Since(droppedAt).Round(time.Second))
+	}
+	fmt.Fprintf(os.Stderr, " ✓ (down %s)\n", time.Since(droppedAt).Round(time.Second))
+	if now := netAddr(c.Profile.Host, c.Port); now != was {
+		fmt.Fprintf(os.Stderr, "→ Now using %s\n", now)
+	}
+	return nil
+}
+
+// reach checks that c's server can be connected to right now (see
+// reachable). With several addresses the pick is made again on every call
+// and c is pointed at the winner: a session opened over the LAN that drops
+// after a move to another network comes back over Tailscale, say.
+func (c *Conn) reach() error {
+	if len(c.Unresolved.Addresses) == 0 {
+		return reachable(c.Profile, c.Port)
+	}
+	picked, choice, err := locate.Apply(c.Unresolved)
+	if err != nil {
+		return err
+	}
+	c.Profile.Host, c.Profile.Port, c.Port = picked.Host, picked.Port, portOf(picked)
+	if choice.Reachable {
+		return nil
+	}
+	return reachable(c.Profile, c.Port)
+}
+
+// poll runs check until it passes, printing a dot per miss and backing off
+// between checks, and returns ctx's error if it ends first.
+func poll(ctx context.Context, check func() error, base, max time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		fmt.Fprintln(os.Stderr)
+		return err
+	}
+	for n := 0; check() != nil; n++ {
+		fmt.Fprint(os.Stderr, ".")
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(os.Stderr)
+			return ctx.Err()
+		case <-time.After(backoff(base, max, n)):
+		}
+	}
+	return nil
+}
+
+// backoff returns the delay before poll n (0-based): base·2ⁿ capped at max,
+// with ±20% jitter so many clients don't retry in lockstep.
+func backoff(base, max time.Duration, n int) time.Duration {
+	d := base
+	for i := 0; i < n && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	jitter := time.Duration((rand.Float64()*0.4 - 0.2) * float64(d))
+	return d + jitter
+}
@@ -0,0 +1,160 @@
+package connector
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+func TestBackoff(t *testing.T) {
+	base, max := time.Second, 10*time.Second
+	for n, want := range []time.Duration{1, 2, 4, 8, 10, 10} {
+		want *= time.Second
+		for i := 0; i < 20; i++ {
+			got := backoff(base, max, n)
+			if lo, hi := want*8/10, want*12/10; got < lo || got > hi {
+				t.Fatalf("backoff(n=%d) = %s, want %s ±20%%", n, got, want)
+			}
+		}
+	}
+}
+
+func TestReconnectLoop_persistentRetriesQuickFailures(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	c := &Conn{
+		Profile: profile.Profile{Name: "db", Host: "127.0.0.1"},
+		Port:    port,
+		Policy: profile.Reconnect{MaxAttempts: -1, Backoff: time.Millisecond,
+			MaxBackoff: 5 * time.Millisecond, StartupThreshold: time.Minute},
+	}
+	// Fails at once twice (say, the remote -R port is still held), then
+	// comes up and ends cleanly.
+	runs := 0
+	attempt := func() error {
+		if runs++; runs < 3 {
+			return errors.New("remote port forwarding failed")
+		}
+		return nil
+	}
+
+	if err := reconnectLoop(c, "tunnel", attempt); err == nil {
+		t.Fatal("interactive session: want the first quick failure to end the loop")
+	}
+	if runs != 1 {
+		t.Errorf("interactive session: %d attempts, want 1", runs)
+	}
+
+	runs = 0
+	c.Persistent = true
+	if err := reconnectLoop(c, "tunnel", attempt); err != nil {
+		t.Fatalf("tunnel: %v", err)
+	}
+	if runs != 3 {
+		t.Errorf("tunnel: %d attempts, want 3", runs)
+	}
+}
+
+func TestReconnectLoop_persistentBackoffKeepsDeadline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// The backoff after a quick failure is far longer than the deadline:
+	// the wait must be cut short when the deadline passes.
+	c := &Conn{
+		Profile:    profile.Profile{Name: "db", Host: "127.0.0.1"},
+		Port:       ln.Addr().(*net.TCPAddr).Port,
+		Persistent: true,
+		Policy: profile.Reconnect{MaxAttempts: -1, Backoff: time.Hour, MaxBackoff: time.Hour,
+			Deadline: 200 * time.Millisecond, StartupThreshold: time.Minute},
+	}
+	start := time.Now()
+	err = reconnectLoop(c, "tunnel", func() error { return errors.New("remote port forwarding failed") })
+	if err == nil || !strings.Contains(err.Error(), "deadline") {
+		t.Errorf("err = %v, want the deadline to end the loop", err)
+	}
+	if took := time.Since(start); took > 5*time.Second {
+		t.Errorf("loop ran %s past a 200ms deadline", took)
+	}
+}
+
+func TestReconnectLoop_overallDeadline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	c := &Conn{
+		Profile: profile.Profile{Name: "db", Host: "127.0.0.1"},
+		Port:    ln.Addr().(*net.TCPAddr).Port,
+		Policy: profile.Reconnect{MaxAttempts: -1, Backoff: time.Millisecond, MaxBackoff: time.Millisecond,
+			Deadline: 100 * time.Millisecond, StartupThreshold: time.Millisecond},
+	}
+	// Every reconnect comes up and drops again a little later: no single
+	// outage is long, but together they run past the deadline.
+	runs := 0
+	err = reconnectLoop(c, "ssh", func() error {
+		runs++
+		time.Sleep(30 * time.Millisecond)
+		return errors.New("connection reset")
+	})
+	if err == nil || !strings.Contains(err.Error(), "since the first drop") {
+		t.Fatalf("err = %v, want the overall deadline", err)
+	}
+	if runs < 2 || runs > 5 {
+		t.Errorf("%d attempts before the deadline, want a handful", runs)
+	}
+}
+
+func TestReconnectLoop_picksAddressAgain(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	// The session was opened over "home", which is gone after the move;
+	// "office" answers now.
+	gone, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	home := gone.Addr().(*net.TCPAddr).Port
+	gone.Close()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	office := ln.Addr().(*net.TCPAddr).Port
+
+	saved := profile.Profile{Name: "nas", Host: "127.0.0.1", User: "u", Addresses: []profile.Address{
+		{Host: "127.0.0.1", Port: home},
+		{Host: "127.0.0.1", Port: office},
+	}}
+	c := &Conn{
+		Profile:    profile.Profile{Name: "nas", Host: "127.0.0.1", User: "u", Port: home},
+		Port:       home,
+		Unresolved: saved,
+		Policy: profile.Reconnect{MaxAttempts: 1, Backoff: time.Millisecond,
+			MaxBackoff: time.Millisecond, Deadline: 5 * time.Second, StartupThreshold: time.Millisecond},
+	}
+	var used int
+	if err := reconnectLoop(c, "ssh", func() error { used = c.Port; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if used != office || c.Profile.Port != office {
+		t.Errorf("reconnected to port %d (profile %d), want the office address %d", used, c.Profile.Port, office)
+	}
+}
@@ -0,0 +1,284 @@
+package connector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/ainsuotain/sshtie/internal/netenv"
+	"github.com/ainsuotain/sshtie/internal/probe"
+	"github.com/ainsuotain/sshtie/internal/profile"
+	sess "github.com/ainsuotain/sshtie/internal/session"
+	"github.com/ainsuotain/sshtie/internal/shell"
+	"github.com/ainsuotain/sshtie/internal/tmux"
+)
+
+func init() {
+	Register(moshTmux{})
+	Register(sshTmux{})
+	Register(sshPlain{})
+}
+
+// errUDPBlocked is returned by the mosh probe when mosh's UDP traffic
+// doesn't get through.
+var errUDPBlocked = probe.ErrUDPBlocked
+
+// ── mosh + tmux ──────────────────────────────────────────────────────────────
+
+type moshTmux struct{}
+
+func (moshTmux) Name() string       { return "mosh+tmux" }
+func (moshTmux) Requires() []string { return []string{"mosh-server", "tmux"} }
+
+// Label names the multiplexer actually used: mosh+screen, mosh+zellij, or
+// plain mosh when the profile has none.
+func (moshTmux) Label(c *Conn) string {
+	if c.Mux == "none" {
+		return "mosh"
+	}
+	return "mosh+" + c.Mux
+}
+
+func (moshTmux) Precheck(c *Conn) error {
+	if runtime.GOOS == "windows" {
+		return fmt.Errorf("mosh is not supported on Windows")
+	}
+	if c.Profile.Network == "direct" {
+		return fmt.Errorf("network is set to direct")
+	}
+	return sshOnly(c.Profile)
+}
+
+func (s moshTmux) Probe(c *Conn) error {
+	if err := s.Precheck(c); err != nil {
+		return err
+	}
+	if _, err := findMosh(); err != nil {
+		return fmt.Errorf("mosh not found in PATH")
+	}
+	// Behind a bastion, mosh only bootstraps through the jump chain — its UDP
+	// traffic still goes straight to the target.
+	if len(c.Hops) > 0 && !tcpReachable(c.Profile.Host, c.Port, 2*time.Second) {
+		return fmt.Errorf("target only reachable via jump host, mosh needs direct UDP")
+	}
+	return moshReachable(c)
+}
+
+// moshReachable runs the end-to-end mosh test (cached per profile and
+// network). When the test can't log in by itself — a password-only server,
+// say — it falls back to the UDP port heuristic. A dry run never starts the
+// test, which logs in and runs mosh-server: it takes the cached verdict or
+// the heuristic.
+func moshReachable(c *Conn) error {
+	p, n := c.Profile, c.Network
+	check := probe.CheckMosh
+	if c.DryRun {
+		check = cachedMosh
+	}
+	v, cached, err := check(p, n, true)
+	switch {
+	case err == nil && v.OK:
+		return nil
+	case err == nil && cached:
+		return fmt.Errorf("%w on %s (checked %s ago)", errUDPBlocked, n, time.Since(v.Checked).Round(time.Minute))
+	case err == nil:
+		return errUDPBlocked
+	case errors.Is(err, probe.ErrMoshNotInstalled):
+		return err
+	}
+	if !udpReachable(p.Host, 60001, 2*time.Second) {
+		return errUDPBlocked
+	}
+	return nil
+}
+
+// cachedMosh is probe.CheckMosh for dry runs: the cached verdict, or
+// errNoVerdict instead of running the test.
+func cachedMosh(p profile.Profile, n netenv.Network, _ bool) (probe.MoshVerdict, bool, error) {
+	if v, ok := probe.CachedMosh(p.Name, n); ok {
+		return v, true, nil
+	}
+	return probe.MoshVerdict{}, false, errNoVerdict
+}
+
+var errNoVerdict = errors.New("no mosh verdict yet")
+
+func (moshTmux) Hint(err error) string {
+	if !errors.Is(err, errUDPBlocked) {
+		return ""
+	}
+	return "⚠  mosh: the server's firewall is blocking UDP ports (60000–61000).\n" +
+		"   mosh needs these ports open to maintain a stable, reconnect-friendly session.\n" +
+		"   To fix it, run this command on your server:\n" +
+		"     sudo ufw allow 60000:61000/udp"
+}
+
+func (moshTmux) Command(c *Conn) (*exec.Cmd, error) {
+	moshBin, err := findMosh()
+	if err != nil {
+		return nil, fmt.Errorf("mosh not found in PATH")
+	}
+	p := c.Profile
+	args := []string{
+		"--ssh=" + buildSSHFlag(p, c.Port),
+	}
+	if len(c.Hops) > 0 {
+		// The fake-proxy IP discovery conflicts with ProxyJump; let the server
+		// report the address it saw instead.
+		args = append(args, "--experimental-remote-ip=remote")
+	}
+	if p.MoshServer != "" {
+		args = append(args, "--server="+p.MoshServer)
+	}
+	args = append(args, c.Target())
+	if attach := attachArgv(c); attach != nil {
+		args = append(args, "--")
+		args = append(args, attach...)
+	}
+	return exec.Command(moshBin, args...), nil
+}
+
+func (moshTmux) Auxiliary(c *Conn) []*exec.Cmd {
+	if len(c.Profile.Forwards) == 0 {
+		return nil
+	}
+	return []*exec.Cmd{forwardChannelCommand(c.Profile, c.Port)}
+}
+
+func (s moshTmux) Run(c *Conn) error {
+	// mosh can't carry port forwards. Open them over a side-channel ssh
+	// instead; if that doesn't come up, fail so the next strategy (ssh)
+	// carries them.
+	if len(c.Profile.Forwards) > 0 {
+		fmt.Fprintln(os.Stderr, "⚠  mosh cannot forward ports — opening them over a side-channel ssh")
+		stop, err := startForwardChannel(c.Profile, c.Port)
+		if err != nil {
+			return fmt.Errorf("side-channel ssh for port forwards failed (%v)", err)
+		}
+		defer stop()
+	}
+	cmd, err := s.Command(c)
+	if err != nil {
+		return err
+	}
+	return runSession(c, label(s, c), cmd)
+}
+
+func (moshTmux) Classify(c *Conn, err error, ran time.Duration) Failure {
+	return classifyByDuration(c, err, ran)
+}
+
+// ── ssh + tmux ───────────────────────────────────────────────────────────────
+
+type sshTmux struct{}
+
+func (sshTmux) Name() string       { return "ssh+tmux" }
+func (sshTmux) Requires() []string { return []string{"tmux"} }
+
+func (sshTmux) Label(c *Conn) string {
+	if c.Mux == "none" {
+		return "ssh+tmux"
+	}
+	return "ssh+" + c.Mux
+}
+
+// Precheck rules the strategy out on a profile without a multiplexer, where
+// it would just be plain ssh.
+func (sshTmux) Precheck(c *Conn) error {
+	if c.Mux == "none" {
+		return fmt.Errorf("multiplexer is none")
+	}
+	return nil
+}
+
+func (s sshTmux) Probe(c *Conn) error {
+	if err := s.Precheck(c); err != nil {
+		return err
+	}
+	return reachable(c.Profile, c.Port)
+}
+
+func (sshTmux) Command(c *Conn) (*exec.Cmd, error) {
+	args := buildSSHBaseArgs(c.Profile, c.Port)
+	args = append(args, "-t", c.Target())
+	args = append(args, shell.Join(attachArgv(c)))
+	return exec.Command("ssh", args...), nil
+}
+
+func (s sshTmux) Run(c *Conn) error {
+	cmd, err := s.Command(c)
+	if err != nil {
+		return err
+	}
+	return runSession(c, label(s, c), cmd)
+}
+
+func (sshTmux) Classify(c *Conn, err error, ran time.Duration) Failure {
+	return classifyByDuration(c, err, ran)
+}
+
+// ── plain ssh ────────────────────────────────────────────────────────────────
+
+type sshPlain struct{}
+
+func (sshPlain) Name() string       { return "ssh" }
+func (sshPlain) Requires() []string { return nil }
+
+// Probe always passes: plain ssh is the last resort and reports its own
+// errors.
+func (sshPlain) Probe(*Conn) error { return nil }
+
+func (sshPlain) Command(c *Conn) (*exec.Cmd, error) {
+	args := buildSSHBaseArgs(c.Profile, c.Port)
+	args = append(args, c.Target())
+	return exec.Command("ssh", args...), nil
+}
+
+func (s sshPlain) Run(c *Conn) error {
+	cmd, err := s.Command(c)
+	if err != nil {
+		return err
+	}
+	return runSession(c, label(s, c), cmd)
+}
+
+func (sshPlain) Classify(c *Conn, err error, ran time.Duration) Failure {
+	return classifyByDuration(c, err, ran)
+}
+
+// attachArgv is the remote command that attaches to c.Session in the
+// profile's multiplexer, creating the session when it doesn't exist yet —
+// with the profile's layout, if it has one. It is nil for multiplexer none.
+func attachArgv(c *Conn) []string {
+	switch c.Mux {
+	case "none":
+		return nil
+	case "screen":
+		// -x shares a session that is already attached elsewhere, like
+		// tmux's -A; -RR creates it when there's nothing to attach to.
+		return []string{"screen", "-xRR", "-S", c.Session}
+	case "zellij":
+		return []string{"zellij", "attach", "--create", c.Session}
+	}
+	if len(c.Profile.Layout) > 0 {
+		return []string{"sh", "-c", tmux.AttachScript(c.Session, c.Profile.Layout)}
+	}
+	return []string{"tmux", "new-session", "-A", "-s", c.Session}
+}
+
+// runSession attaches cmd to the terminal, records it as the profile's
+// active session and waits for it to exit.
+func runSession(c *Conn, method string, cmd *exec.Cmd) error {
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	_ = sess.Write(sess.Session{Profile: c.Profile.Name, PID: cmd.Process.Pid, Method: method, StartedAt: time.Now()})
+	defer sess.Delete(c.Profile.Name)
+	return cmd.Wait()
+}
@@ -0,0 +1,291 @@
+package connector
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ainsuotain/sshtie/internal/history"
+	"github.com/ainsuotain/sshtie/internal/netenv"
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+// Strategy is one way of opening an interactive session (mosh+tmux,
+// ssh+tmux, plain ssh, …). Connect walks a profile's strategies in order:
+// a strategy whose Probe fails is skipped, one that fails at startup hands
+// over to the next, and one that drops after running is reconnected as-is.
+//
+// New transports implement Strategy and call Register; the cascade itself
+// doesn't change.
+type Strategy interface {
+	// Name is the identifier used in profiles.yaml (`strategies:`).
+	Name() string
+	// Requires lists the tools that must be installed on the server.
+	Requires() []string
+	// Probe checks, without opening a session, whether the strategy can be
+	// tried right now. The error is the reason it is skipped.
+	Probe(c *Conn) error
+	// Command builds the process that runs the session.
+	Command(c *Conn) (*exec.Cmd, error)
+	// Run opens the session and blocks until it ends.
+	Run(c *Conn) error
+	// Classify decides what a failed Run means, given how long it ran.
+	Classify(c *Conn, err error, ran time.Duration) Failure
+}
+
+// Failure classifies why a strategy's Run returned an error.
+type Failure int
+
+const (
+	// FailStartup: the session never really came up (tool missing, auth
+	// refused, …). Connect moves on to the next strategy.
+	FailStartup Failure = iota
+	// FailDrop: the session ran and then dropped. Connect waits for the
+	// network and reconnects with the same strategy.
+	FailDrop
+)
+
+// classifyByDuration is the default Classify: exits within the policy's
+// startup threshold are startup failures, anything that ran longer is a drop.
+func classifyByDuration(c *Conn, _ error, ran time.Duration) Failure {
+	if ran < c.Policy.StartupThreshold {
+		return FailStartup
+	}
+	return FailDrop
+}
+
+// Conn is everything a strategy needs to know about one connection.
+type Conn struct {
+	Profile profile.Profile
+	Port    int               // SSH port, defaulted
+	Session string            // multiplexer session name, defaulted
+	Mux     string            // tmux | screen | zellij | none, defaulted
+	Hops    []profile.Hop     // resolved jump chain (outermost first)
+	Policy  profile.Reconnect // effective reconnect policy, defaults filled in
+	ID      string            // tags this connection's history events
+	Network netenv.Network    // the local network, for per-network memory
+
+	// Persistent retries reconnects that fail straight away too, for
+	// tunnels, which nobody is watching to retry by hand.
+	Persistent bool
+	// Unresolved is the profile as saved when it has several addresses
+	// (Profile is pointed at one of them): reconnects pick again, since the
+	// network may have changed since the drop (see reach).
+	Unresolved profile.Profile
+	// DryRun keeps probes to looking: nothing is logged in to or started
+	// on the server (see NewPreview).
+	DryRun bool
+}
+
+// newConn resolves p's defaults, jump chain and reconnect policy, and
+// checks its transport, multiplexer and layout.
+func newConn(p profile.Profile) (*Conn, error) {
+	port := p.Port
+	if port == 0 {
+		port = 22
+	}
+	session := p.TmuxSession
+	if session == "" {
+		session = "main"
+	}
+	hops, err := profile.JumpChain(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := profile.ValidateTransport(p); err != nil {
+		return nil, err
+	}
+	pol, err := profile.ReconnectPolicy(p)
+	if err != nil {
+		return nil, err
+	}
+	mux, err := profile.Multiplexer(p)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.Layout) > 0 {
+		if mux != "tmux" {
+			return nil, fmt.Errorf("profile %q: layout needs multiplexer tmux, not %s", p.Name, mux)
+		}
+		if err := profile.ValidateLayout(p.Layout); err != nil {
+			return nil, fmt.Errorf("profile %q: %w", p.Name, err)
+		}
+	}
+	return &Conn{Profile: p, Port: port, Session: session, Mux: mux, Hops: hops, Policy: pol,
+		ID: strconv.FormatInt(time.Now().UnixNano(), 36), Network: netenv.Current()}, nil
+}
+
+// event records e in the connection history.
+func (c *Conn) event(e history.Event) {
+	e.Profile = c.Profile.Name
+	e.Conn = c.ID
+	history.Log(e)
+}
+
+// logRun records one finished run of a strategy. attempt is 0 for the
+// first run and n for the n-th reconnect.
+func (c *Conn) logRun(strategy string, attempt int, ran time.Duration, err error, outcome string) {
+	e := history.Event{
+		Kind:     history.KindRun,
+		Strategy: strategy,
+		Outcome:  outcome,
+		Attempt:  attempt,
+		Duration: ran.Seconds(),
+		Exit:     "0",
+	}
+	if err != nil {
+		e.Exit = err.Error()
+	}
+	c.event(e)
+}
+
+// Target returns user@host for the profile.
+func (c *Conn) Target() string {
+	return fmt.Sprintf("%s@%s", c.Profile.User, c.Profile.Host)
+}
+
+// hinter is implemented by strategies that can explain a Probe failure in
+// more detail (e.g. how to open a firewall).
+type hinter interface {
+	Hint(err error) string
+}
+
+// prechecker is implemented by strategies with conditions that can be
+// checked without touching the network (platform, profile settings). Plan
+// uses it to show which steps will be skipped.
+type prechecker interface {
+	Precheck(c *Conn) error
+}
+
+// labeler is implemented by strategies whose displayed name depends on the
+// profile (mosh+tmux shows as mosh+screen on a screen profile). Name stays
+// the identifier used in profiles.yaml and the failure memory.
+type labeler interface {
+	Label(c *Conn) string
+}
+
+// label is how s is shown for c: in messages, previews, history and the
+// active session.
+func label(s Strategy, c *Conn) string {
+	if l, ok := s.(labeler); ok {
+		return l.Label(c)
+	}
+	return s.Name()
+}
+
+// requires is s.Requires with "tmux" standing for c's multiplexer — swapped
+// for screen or zellij, dropped for none.
+func requires(s Strategy, c *Conn) []string {
+	var out []string
+	for _, t := range s.Requires() {
+		if t == "tmux" {
+			if c.Mux == "none" {
+				continue
+			}
+			t = c.Mux
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// auxiliary is implemented by strategies that run helper processes next to
+// the session (mosh's side-channel ssh for port forwards). Previews list them.
+type auxiliary interface {
+	Auxiliary(c *Conn) []*exec.Cmd
+}
+
+// ── registry ─────────────────────────────────────────────────────────────────
+
+var registry = map[string]Strategy{}
+
+// DefaultStrategies is the order used when a profile doesn't set one.
+var DefaultStrategies = []string{"mosh+tmux", "ssh+tmux", "ssh"}
+
+// Register makes a strategy available under its Name.
+func Register(s Strategy) {
+	registry[s.Name()] = s
+}
+
+// StrategyNames lists every registered strategy, sorted.
+func StrategyNames() []string {
+	names := make([]string, 0, len(registry))
+	for n := range registry {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Chain returns the strategies Connect will try for p, in order.
+func Chain(p profile.Profile) ([]Strategy, error) {
+	names := p.Strategies
+	if len(names) == 0 {
+		names = DefaultStrategies
+	}
+	chain := make([]Strategy, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, n := range names {
+		s, ok := registry[n]
+		if !ok {
+			return nil, fmt.Errorf("profile %q: unknown strategy %q (known: %s)",
+				p.Name, n, strings.Join(StrategyNames(), ", "))
+		}
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		chain = append(chain, s)
+	}
+	return chain, nil
+}
+
+// InChain reports whether p's strategy chain includes the strategy called
+// name.
+func InChain(p profile.Profile, name string) bool {
+	chain, err := Chain(p)
+	if err != nil {
+		return false
+	}
+	for _, s := range chain {
+		if s.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Step is one entry of a connection plan.
+type Step struct {
+	Name     string
+	Label    string   // Name as shown for this profile (mosh+screen, …)
+	Requires []string // tools needed on the server
+	Skip     string   // why it will be skipped ("" = will be tried)
+}
+
+// Plan describes the chain Connect will attempt for p without touching the
+// network: every strategy in order, with the ones that can already be ruled
+// out (wrong platform, network=direct, …) marked as skipped.
+func Plan(p profile.Profile) ([]Step, error) {
+	c, err := newConn(p)
+	if err != nil {
+		return nil, err
+	}
+	chain, err := Chain(p)
+	if err != nil {
+		return nil, err
+	}
+	steps := make([]Step, len(chain))
+	for i, s := range chain {
+		steps[i] = Step{Name: s.Name(), Label: label(s, c), Requires: requires(s, c)}
+		if pc, ok := s.(prechecker); ok {
+			if err := pc.Precheck(c); err != nil {
+				steps[i].Skip = err.Error()
+			}
+		}
+	}
+	return steps, nil
+}
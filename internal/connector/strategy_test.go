@@ -0,0 +1,130 @@
+package connector
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/shell"
+)
+
+func chainNames(t *testing.T, p profile.Profile) []string {
+	t.Helper()
+	chain, err := Chain(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(chain))
+	for i, s := range chain {
+		names[i] = s.Name()
+	}
+	return names
+}
+
+func TestChain(t *testing.T) {
+	if got := chainNames(t, profile.Profile{Name: "a"}); !reflect.DeepEqual(got, DefaultStrategies) {
+		t.Errorf("default chain = %v, want %v", got, DefaultStrategies)
+	}
+	pinned := profile.Profile{Name: "a", Strategies: []string{"ssh+tmux", "ssh", "ssh+tmux"}}
+	if got := chainNames(t, pinned); !reflect.DeepEqual(got, []string{"ssh+tmux", "ssh"}) {
+		t.Errorf("pinned chain = %v", got)
+	}
+	if _, err := Chain(profile.Profile{Name: "a", Strategies: []string{"telnet"}}); err == nil {
+		t.Error("unknown strategy: want error")
+	}
+}
+
+func TestPlan_skipsMoshWhenDirect(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	steps, err := Plan(profile.Profile{Name: "a", Host: "h", Network: "direct"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps[0].Name != "mosh+tmux" || steps[0].Skip == "" {
+		t.Errorf("mosh+tmux step = %+v, want skipped", steps[0])
+	}
+	for _, st := range steps[1:] {
+		if st.Skip != "" {
+			t.Errorf("%s skipped: %s", st.Name, st.Skip)
+		}
+	}
+
+	steps, err = Plan(profile.Profile{Name: "a", Host: "h", Network: "auto"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wantSkip := runtime.GOOS == "windows"; (steps[0].Skip != "") != wantSkip {
+		t.Errorf("auto network: mosh+tmux skip = %q", steps[0].Skip)
+	}
+}
+
+func TestPlan_multiplexer(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	steps, err := Plan(profile.Profile{Name: "a", Host: "h", Multiplexer: "screen"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps[0].Label != "mosh+screen" || !reflect.DeepEqual(steps[0].Requires, []string{"mosh-server", "screen"}) {
+		t.Errorf("screen: mosh step = %+v", steps[0])
+	}
+	if steps[1].Name != "ssh+tmux" || steps[1].Label != "ssh+screen" {
+		t.Errorf("screen: ssh step = %+v", steps[1])
+	}
+
+	steps, err = Plan(profile.Profile{Name: "a", Host: "h", Multiplexer: "none"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps[0].Label != "mosh" || !reflect.DeepEqual(steps[0].Requires, []string{"mosh-server"}) {
+		t.Errorf("none: mosh step = %+v", steps[0])
+	}
+	if steps[1].Skip != "multiplexer is none" {
+		t.Errorf("none: ssh+tmux skip = %q", steps[1].Skip)
+	}
+
+	if _, err := Plan(profile.Profile{Name: "a", Host: "h", Multiplexer: "byobu"}); err == nil {
+		t.Error("unknown multiplexer: want error")
+	}
+}
+
+func TestAttachCommand(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for mux, want := range map[string]string{
+		"":       "-t u@h 'tmux new-session -A -s work'",
+		"screen": "-t u@h 'screen -xRR -S work'",
+		"zellij": "-t u@h 'zellij attach --create work'",
+	} {
+		c, err := newConn(profile.Profile{Name: "a", Host: "h", User: "u", TmuxSession: "work", Multiplexer: mux})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmd, err := sshTmux{}.Command(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line := shell.Join(cmd.Args); !strings.HasSuffix(line, want) {
+			t.Errorf("%s: command %q, want suffix %q", mux, line, want)
+		}
+	}
+}
+
+func TestAttachCommand_layout(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	layout := []profile.Window{{Name: "logs"}}
+	c, err := newConn(profile.Profile{Name: "a", Host: "h", User: "u", Layout: layout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := attachArgv(c); len(got) != 3 || got[0] != "sh" || !strings.Contains(got[2], "-n logs") {
+		t.Errorf("layout attach = %q", got)
+	}
+	if _, err := newConn(profile.Profile{Name: "a", Multiplexer: "zellij", Layout: layout}); err == nil {
+		t.Error("layout with zellij: want error")
+	}
+	bad := []profile.Window{{Panes: []profile.Pane{{}, {Split: "left"}}}}
+	if _, err := newConn(profile.Profile{Name: "a", Layout: bad}); err == nil {
+		t.Error("split left: want error")
+	}
+}
@@ -0,0 +1,78 @@
+package connector
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ainsuotain/sshtie/internal/probe"
+	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/tmux"
+)
+
+// TmuxSessions lists the tmux sessions running on p, most recently active
+// first. It logs in like the probe does — agent or key, never a prompt —
+// so p should already be resolved (see Resolve).
+func TmuxSessions(p profile.Profile) ([]tmux.Session, error) {
+	out, err := remoteTmux(p, tmux.ListScript())
+	if err != nil {
+		return nil, err
+	}
+	return tmux.Parse(out), nil
+}
+
+// NewTmuxSession starts a detached tmux session called name on p.
+func NewTmuxSession(p profile.Profile, name string) error {
+	if err := tmux.ValidName(name); err != nil {
+		return err
+	}
+	_, err := remoteTmux(p, tmux.NewScript(name))
+	return err
+}
+
+// RenameTmuxSession renames the tmux session from to to on p.
+func RenameTmuxSession(p profile.Profile, from, to string) error {
+	if err := tmux.ValidName(to); err != nil {
+		return err
+	}
+	_, err := remoteTmux(p, tmux.RenameScript(from, to))
+	return err
+}
+
+// KillTmuxSession ends the tmux session name on p, with everything running
+// in it.
+func KillTmuxSession(p profile.Profile, name string) error {
+	_, err := remoteTmux(p, tmux.KillScript(name))
+	return err
+}
+
+// CaptureLayout reads the windows and panes of the tmux session on p as a
+// profile layout (see tmux.ParseCapture).
+func CaptureLayout(p profile.Profile, session string) ([]profile.Window, error) {
+	out, err := remoteTmux(p, tmux.CaptureScript(session))
+	if err != nil {
+		return nil, err
+	}
+	layout := tmux.ParseCapture(out)
+	if len(layout) == 0 {
+		return nil, fmt.Errorf("tmux session %q has no windows", session)
+	}
+	return layout, nil
+}
+
+// remoteTmux runs a tmux script on p. The scripts send tmux's complaints to
+// stdout, so a failure reports what tmux said rather than an exit status.
+func remoteTmux(p profile.Profile, script string) ([]byte, error) {
+	if mux, err := profile.Multiplexer(p); err != nil {
+		return nil, err
+	} else if mux != "tmux" {
+		return nil, fmt.Errorf("profile %q uses %s — only tmux sessions can be managed", p.Name, mux)
+	}
+	out, err := probe.Exec(p, probe.Options{}, script)
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return nil, fmt.Errorf("%s", msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
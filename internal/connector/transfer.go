@@ -0,0 +1,126 @@
+package connector
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/shell"
+)
+
+// Transfer tools.
+const (
+	ToolAuto  = ""
+	ToolRsync = "rsync"
+	ToolSCP   = "scp"
+)
+
+// Transfer describes one copy between this machine and a profile.
+type Transfer struct {
+	Profile   profile.Profile
+	Push      bool     // true: local → remote, false: remote → local
+	Sources   []string // local paths (push) or remote paths (pull)
+	Dest      string   // remote path (push) or local path (pull)
+	Recursive bool
+	Progress  bool
+	Tool      string // ToolAuto picks rsync when it is installed
+}
+
+// ResolveTool returns the tool t will use.
+func (t Transfer) ResolveTool() string {
+	if t.Tool != ToolAuto {
+		return t.Tool
+	}
+	if _, err := exec.LookPath("rsync"); err == nil {
+		return ToolRsync
+	}
+	return ToolSCP
+}
+
+// Command builds the scp or rsync invocation for t, with the profile's key,
+// jump chain and SSH options. Port forwards are not applied.
+func (t Transfer) Command(tool string) (*exec.Cmd, error) {
+	p := t.Profile
+	if _, err := profile.JumpChain(p); err != nil {
+		return nil, err
+	}
+	if err := profile.ValidateTransport(p); err != nil {
+		return nil, err
+	}
+	if len(t.Sources) == 0 {
+		return nil, fmt.Errorf("nothing to copy")
+	}
+	port := p.Port
+	if port == 0 {
+		port = 22
+	}
+
+	var srcs []string
+	dest := t.Dest
+	if t.Push {
+		srcs = t.Sources
+		dest = remoteSpec(p, t.Dest)
+	} else {
+		for _, s := range t.Sources {
+			srcs = append(srcs, remoteSpec(p, s))
+		}
+	}
+
+	var args []string
+	switch tool {
+	case ToolRsync:
+		if t.Recursive {
+			args = append(args, "-a")
+		} else {
+			args = append(args, "-pt")
+		}
+		// --partial keeps interrupted files so rerunning the same command
+		// resumes instead of starting over.
+		args = append(args, "--partial")
+		if t.Progress {
+			args = append(args, "--progress")
+		}
+		args = append(args, "-e", shell.Join(append([]string{"ssh"}, sshOptions(p, port)...)))
+	case ToolSCP:
+		args = append(args, "-p", "-P", strconv.Itoa(port))
+		args = append(args, sshSettings(p)...)
+		if t.Recursive {
+			args = append(args, "-r")
+		}
+		if !t.Progress {
+			args = append(args, "-q")
+		}
+	default:
+		return nil, fmt.Errorf("unknown transfer tool %q (want rsync or scp)", tool)
+	}
+	args = append(args, "--")
+	args = append(args, srcs...)
+	args = append(args, dest)
+	return exec.Command(tool, args...), nil
+}
+
+// remoteSpec renders user@host:path, bracketing IPv6 hosts. An empty path
+// is the remote home directory.
+func remoteSpec(p profile.Profile, path string) string {
+	host := p.Host
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	return fmt.Sprintf("%s@%s:%s", p.User, host, path)
+}
+
+// SplitRemote splits a "name:path" argument. ok is false for plain local
+// paths, including Windows drive letters ("C:\…") and paths whose first
+// colon comes after a slash ("./a:b").
+func SplitRemote(arg string) (name, path string, ok bool) {
+	i := strings.IndexByte(arg, ':')
+	if i <= 0 || strings.ContainsAny(arg[:i], `/\`) {
+		return "", "", false
+	}
+	if i == 1 && len(arg) > 2 && (arg[2] == '\\' || arg[2] == '/') {
+		return "", "", false // drive letter
+	}
+	return arg[:i], arg[i+1:], true
+}
@@ -0,0 +1,61 @@
+package connector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/shell"
+)
+
+func TestSplitRemote(t *testing.T) {
+	cases := []struct {
+		in         string
+		name, path string
+		ok         bool
+	}{
+		{"web1:/tmp/x", "web1", "/tmp/x", true},
+		{"web1:", "web1", "", true},
+		{"./a:b", "", "", false},
+		{"plain.txt", "", "", false},
+		{`C:\Users\me`, "", "", false},
+		{":x", "", "", false},
+	}
+	for _, c := range cases {
+		name, path, ok := SplitRemote(c.in)
+		if name != c.name || path != c.path || ok != c.ok {
+			t.Errorf("SplitRemote(%q) = %q, %q, %v", c.in, name, path, ok)
+		}
+	}
+}
+
+func TestTransferCommand(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	p := profile.Profile{Name: "web", Host: "fe80::1", User: "al", Port: 2222,
+		Forwards: []profile.Forward{{Dynamic: "1080"}}}
+
+	push := Transfer{Profile: p, Push: true, Sources: []string{"a", "b"}, Dest: "/tmp/", Recursive: true}
+	cmd, err := push.Command(ToolRsync)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := shell.Join(cmd.Args)
+	for _, want := range []string{"rsync -a --partial -e 'ssh -p 2222 ", " -- a b 'al@[fe80::1]:/tmp/'"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("rsync command %q lacks %q", got, want)
+		}
+	}
+
+	pull := Transfer{Profile: p, Sources: []string{"log.txt"}, Dest: ".", Progress: true}
+	cmd, err = pull.Command(ToolSCP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = shell.Join(cmd.Args)
+	if !strings.HasPrefix(got, "scp -p -P 2222 -o ") || !strings.HasSuffix(got, "-- 'al@[fe80::1]:log.txt' .") {
+		t.Errorf("scp command = %q", got)
+	}
+	if strings.Contains(got, "1080") || strings.Contains(got, " -q") {
+		t.Errorf("scp command %q has forwards or -q", got)
+	}
+}
@@ -0,0 +1,136 @@
+package connector
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ainsuotain/sshtie/internal/history"
+	"github.com/ainsuotain/sshtie/internal/locate"
+	"github.com/ainsuotain/sshtie/internal/profile"
+	sess "github.com/ainsuotain/sshtie/internal/session"
+)
+
+// RunTunnel holds p's port forwards open with `ssh -N` until it is stopped
+// (SIGTERM / Ctrl+C). Drops — including laptop sleep — are handled like an
+// interactive session: wait for the network, then reconnect, forever.
+//
+// It runs in the foreground; `sshtie tunnel up` starts it detached.
+func RunTunnel(p profile.Profile) error {
+	if len(p.Forwards) == 0 {
+		return fmt.Errorf("profile %q has no forwards — add some with: sshtie edit %s", p.Name, p.Name)
+	}
+	for _, f := range p.Forwards {
+		if err := f.Validate(); err != nil {
+			return fmt.Errorf("profile %q: %w", p.Name, err)
+		}
+	}
+	saved := p
+	p, _, err := locate.Apply(p)
+	if err != nil {
+		return err
+	}
+	if p, err = VerifyHostKey(p); err != nil {
+		return err
+	}
+	c, err := newConn(p)
+	if err != nil {
+		return err
+	}
+	if len(saved.Addresses) > 0 {
+		c.Unresolved = saved
+	}
+	// A tunnel has no one watching it: keep retrying for as long as it runs.
+	c.Policy.MaxAttempts = -1
+	c.Policy.Deadline = 0
+	c.Persistent = true
+
+	specs := make([]string, len(p.Forwards))
+	for i, f := range p.Forwards {
+		specs[i] = f.String()
+	}
+	_ = sess.Write(sess.Session{
+		Profile:   p.Name,
+		PID:       os.Getpid(),
+		Method:    sess.MethodTunnel,
+		StartedAt: time.Now(),
+		Forwards:  specs,
+	})
+	defer sess.DeleteTunnel(p.Name)
+
+	t := &tunnel{c: c}
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		t.stop()
+		_ = sess.DeleteTunnel(p.Name)
+		os.Exit(0)
+	}()
+
+	start := time.Now()
+	err = t.run()
+	ran := time.Since(start)
+	if err == nil {
+		c.logRun(sess.MethodTunnel, 0, ran, nil, history.OutcomeClean)
+		return nil
+	}
+	if ran < c.Policy.StartupThreshold {
+		c.logRun(sess.MethodTunnel, 0, ran, err, history.OutcomeStartup)
+		return err // never came up (auth, port in use, …) — don't retry
+	}
+	c.logRun(sess.MethodTunnel, 0, ran, err, history.OutcomeDrop)
+	fmt.Fprintf(os.Stderr, "\n⚠  Tunnel '%s' dropped.\n", p.Name)
+	return reconnectLoop(c, sess.MethodTunnel, t.run)
+}
+
+// tunnel tracks the running ssh so a stop signal can take it down. Each
+// run goes to c's current address, which reconnects may move.
+type tunnel struct {
+	c *Conn
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	stopped bool
+}
+
+func (t *tunnel) run() error {
+	p := t.c.Profile
+	args := buildSSHBaseArgs(p, t.c.Port) // includes the -L/-R/-D forwards
+	// Nobody is there to answer prompts, and a forward that can't bind
+	// should fail the tunnel rather than leave it half up.
+	args = append(args, "-N", "-o", "BatchMode=yes", "-o", "ExitOnForwardFailure=yes")
+	args = append(args, fmt.Sprintf("%s@%s", p.User, p.Host))
+
+	cmd := exec.Command("ssh", args...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	t.mu.Lock()
+	if t.stopped {
+		t.mu.Unlock()
+		return nil
+	}
+	if err := cmd.Start(); err != nil {
+		t.mu.Unlock()
+		return err
+	}
+	t.cmd = cmd
+	t.mu.Unlock()
+
+	fmt.Fprintf(os.Stderr, "→ Tunnel '%s' up (%s)\n", p.Name, time.Now().Format(time.RFC3339))
+	return cmd.Wait()
+}
+
+func (t *tunnel) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopped = true
+	if t.cmd != nil && t.cmd.Process != nil {
+		_ = t.cmd.Process.Kill()
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package connector
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// DetachTunnel makes cmd (a `sshtie tunnel run`) start in its own session so
+// closing the terminal (SIGHUP) doesn't take it down.
+func DetachTunnel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package connector
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+const (
+	createNewProcessGroup = 0x00000200
+	detachedProcess       = 0x00000008
+)
+
+// DetachTunnel makes cmd (a `sshtie tunnel run`) start without a console and
+// outside the caller's process group, so closing the terminal doesn't take
+// it down.
+func DetachTunnel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CreationFlags: createNewProcessGroup | detachedProcess,
+		HideWindow:    true,
+	}
+}
@@ -0,0 +1,144 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/ainsuotain/sshtie/internal/locate"
+	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/shell"
+	"github.com/ainsuotain/sshtie/internal/wol"
+)
+
+// relayTimeout bounds the ssh to a wake relay.
+const relayTimeout = 30 * time.Second
+
+// SendWake sends p's Wake-on-LAN magic packet: broadcast from this machine,
+// or from p's wake relay over ssh when one is set.
+func SendWake(p profile.Profile) error {
+	if err := p.Wake.Validate(); err != nil {
+		return fmt.Errorf("profile %q: %w", p.Name, err)
+	}
+	if p.Wake.Relay == "" {
+		return wol.Send(p.Wake.MAC, p.Wake.Broadcast)
+	}
+
+	relay, err := profile.Get(p.Wake.Relay)
+	if err != nil {
+		return fmt.Errorf("wake relay: %w", err)
+	}
+	if relay, _, err = locate.Apply(relay); err != nil {
+		return fmt.Errorf("wake relay: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), relayTimeout)
+	defer cancel()
+	cmd, err := ExecCommand(ctx, relay, []string{relayWakeScript(p.Wake)})
+	if err != nil {
+		return fmt.Errorf("wake relay: %w", err)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("wake via %s: %s", relay.Name, msg)
+		}
+		return fmt.Errorf("wake via %s: %w", relay.Name, err)
+	}
+	return nil
+}
+
+// relayWakeScript sends the magic packet from the relay with wakeonlan, or
+// with python3 when that isn't installed. A plain shell can't broadcast.
+func relayWakeScript(w profile.Wake) string {
+	pkt, _ := wol.MagicPacket(w.MAC) // validated by SendWake
+	host, port, _ := net.SplitHostPort(wol.Addr(w.Broadcast))
+	py := `import socket,sys
+s=socket.socket(socket.AF_INET,socket.SOCK_DGRAM)
+s.setsockopt(socket.SOL_SOCKET,socket.SO_BROADCAST,1)
+s.sendto(bytes.fromhex(sys.argv[1]),(sys.argv[2],int(sys.argv[3])))`
+	return fmt.Sprintf(`if command -v wakeonlan >/dev/null 2>&1; then wakeonlan -i %s -p %s %s
+elif command -v python3 >/dev/null 2>&1; then python3 -c %s %x %s %s
+else echo "neither wakeonlan nor python3 is installed on the relay" >&2; exit 127; fi`,
+		shell.Quote(host), port, shell.Quote(w.MAC), shell.Quote(py), pkt, shell.Quote(host), port)
+}
+
+// Resolve picks p's address (see package locate) and wakes p when it is
+// asleep, picking again afterwards if the first pick was made while
+// nothing answered.
+func Resolve(p profile.Profile) (profile.Profile, locate.Choice, error) {
+	picked, choice, err := locate.Apply(p)
+	if err != nil {
+		return p, choice, err
+	}
+	woke, err := WakeIfAsleep(picked)
+	if err != nil {
+		return picked, choice, err
+	}
+	if woke && choice.Multiple() {
+		return locate.Apply(p)
+	}
+	return picked, choice, nil
+}
+
+// WakeIfAsleep wakes p when it has wake set up and doesn't answer on its
+// SSH port, then waits for it (see WaitAwake). It reports whether a packet
+// was sent. Behind a jump chain or a cloud transport there is no telling
+// from here whether p is asleep, so nothing is sent; use sshtie wake.
+func WakeIfAsleep(p profile.Profile) (bool, error) {
+	if !p.Wake.Enabled() || p.Transport.Enabled() {
+		return false, nil
+	}
+	hops, err := profile.JumpChain(p)
+	if err != nil || len(hops) > 0 {
+		return false, err
+	}
+	if tcpReachable(p.Host, portOf(p), 3*time.Second) {
+		return false, nil
+	}
+	via := ""
+	if p.Wake.Relay != "" {
+		via = " via " + p.Wake.Relay
+	}
+	fmt.Fprintf(os.Stderr, "→ %s isn't answering — sending Wake-on-LAN%s\n", p.Name, via)
+	if err := SendWake(p); err != nil {
+		return true, err
+	}
+	return true, WaitAwake(p)
+}
+
+// WaitAwake polls p's SSH port, with the profile's reconnect backoff, until
+// it answers or p's wake timeout passes. Ctrl+C cancels.
+func WaitAwake(p profile.Profile) error {
+	pol, err := profile.ReconnectPolicy(p)
+	if err != nil {
+		return err
+	}
+	limit := p.Wake.WaitFor()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, limit)
+	defer cancel()
+
+	start := time.Now()
+	fmt.Fprintf(os.Stderr, "   Waiting up to %s for %s to wake (Ctrl+C to cancel).", limit, p.Name)
+	check := func() error { return reachable(p, portOf(p)) }
+	if err := poll(ctx, check, pol.Backoff, pol.MaxBackoff); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("%q didn't answer within %s of the wake packet — is Wake-on-LAN enabled in its firmware?", p.Name, limit)
+		}
+		return fmt.Errorf("waiting for %q to wake cancelled", p.Name)
+	}
+	fmt.Fprintf(os.Stderr, " ✓ (awake after %s)\n", time.Since(start).Round(time.Second))
+	return nil
+}
+
+func portOf(p profile.Profile) int {
+	if p.Port == 0 {
+		return 22
+	}
+	return p.Port
+}
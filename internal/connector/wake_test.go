@@ -0,0 +1,134 @@
+package connector
+
+import (
+	"bytes"
+	"net"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/wol"
+)
+
+const testMAC = "3c:22:fb:12:34:56"
+
+// freePort returns a local TCP port nothing listens on.
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+	return port
+}
+
+// sleeper is a UDP listener that starts answering on sshPort once it gets
+// the magic packet for testMAC.
+func sleeper(t *testing.T, sshPort int) (wakeAddr string, woke chan struct{}) {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	want, _ := wol.MagicPacket(testMAC)
+	woke = make(chan struct{})
+	go func() {
+		buf := make([]byte, 200)
+		for {
+			n, _, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if bytes.Equal(buf[:n], want) {
+				break
+			}
+		}
+		l, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(sshPort)))
+		if err != nil {
+			return
+		}
+		t.Cleanup(func() { l.Close() })
+		close(woke)
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+	return pc.LocalAddr().String(), woke
+}
+
+func TestWakeIfAsleep(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+	port := freePort(t)
+	addr, woke := sleeper(t, port)
+	p := profile.Profile{Name: "lab1", Host: "127.0.0.1", Port: port,
+		Wake: profile.Wake{MAC: testMAC, Broadcast: addr, Timeout: 10 * time.Second}}
+
+	sent, err := WakeIfAsleep(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sent {
+		t.Fatal("no packet sent to a sleeping host")
+	}
+	select {
+	case <-woke:
+	default:
+		t.Fatal("WakeIfAsleep returned before the host woke")
+	}
+
+	// Awake now: nothing to send.
+	if sent, err := WakeIfAsleep(p); sent || err != nil {
+		t.Errorf("WakeIfAsleep(awake) = %v, %v", sent, err)
+	}
+}
+
+func TestWaitAwake_timeout(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+	p := profile.Profile{Name: "lab2", Host: "127.0.0.1", Port: freePort(t),
+		Wake: profile.Wake{MAC: testMAC, Timeout: 500 * time.Millisecond}}
+	if err := WaitAwake(p); err == nil {
+		t.Error("expected an error when the host never answers")
+	}
+}
+
+func TestRelayWakeScript(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("needs python3") // or wakeonlan, which is rarer
+	}
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	script := relayWakeScript(profile.Wake{MAC: testMAC, Broadcast: pc.LocalAddr().String()})
+	if out, err := exec.Command("sh", "-c", script).CombinedOutput(); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+
+	pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 200)
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := wol.MagicPacket(testMAC)
+	if !bytes.Equal(buf[:n], want) {
+		t.Errorf("relay sent % x", buf[:n])
+	}
+}
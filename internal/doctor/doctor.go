@@ -1,15 +1,22 @@
 package doctor
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
-	"os"
 	"os/exec"
 	"runtime"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/ainsuotain/sshtie/internal/cloud"
+	"github.com/ainsuotain/sshtie/internal/connector"
+	"github.com/ainsuotain/sshtie/internal/hostkey"
+	"github.com/ainsuotain/sshtie/internal/locate"
+	"github.com/ainsuotain/sshtie/internal/netenv"
+	"github.com/ainsuotain/sshtie/internal/probe"
 	"github.com/ainsuotain/sshtie/internal/profile"
 	"github.com/ainsuotain/sshtie/internal/tailscale"
 )
@@ -27,31 +34,53 @@ type Result struct {
 
 // Run performs all diagnostic checks for the given profile and prints results.
 func Run(p profile.Profile) {
+	p, picked, err := locate.Apply(p)
+	if err != nil {
+		fmt.Printf("\n⚠  %v\n", err)
+		return
+	}
+	mux, err := profile.Multiplexer(p)
+	if err != nil {
+		fmt.Printf("\n⚠  %v\n", err)
+		return
+	}
 	port := p.Port
 	if port == 0 {
 		port = 22
 	}
 
 	fmt.Printf("\n🔍 Checking connectivity to %s (%s)\n\n", p.Name, p.Host)
+	if picked.Multiple() {
+		fmt.Printf("   Using address %s\n\n", picked)
+	}
+
+	// One login answers every server-side question.
+	facts, probeErr := probe.Run(p)
 
 	results := []Result{
 		checkSSH(p, port),
-		checkMoshServer(p, port),
-		checkUDP(p.Host, 60001),
-		checkTmux(p, port),
+		checkLogin(facts, probeErr),
+		checkMoshServer(p, facts, probeErr),
+		checkMoshUDP(p, facts, probeErr),
+		checkMux(p, mux, facts, probeErr),
 		checkTailscaleClient(),
 		checkTailscaleServer(p.Host),
 	}
+	if connector.InChain(p, "et+tmux") {
+		results = append(results, checkETClient(), checkETServer(p, facts, probeErr))
+	}
 
 	// On Windows, mosh is not supported natively — mark checks as skipped.
 	if runtime.GOOS == "windows" {
-		results[1] = Result{"mosh-server", false, "Skipped (not supported on Windows)"}
-		results[2] = Result{fmt.Sprintf("UDP port %d", 60001), false, "Skipped (not supported on Windows)"}
+		results[2] = Result{"mosh-server", false, "Skipped (not supported on Windows)"}
+		results[3] = Result{"mosh UDP", false, "Skipped (not supported on Windows)"}
 	}
 
 	strategy := "ssh only"
 	moshOK := false
-	tmuxOK := false
+	udpOK := false
+	muxOK := false
+	etOK := true
 
 	for _, r := range results {
 		icon := "✅"
@@ -62,64 +91,146 @@ func Run(p profile.Profile) {
 		if r.Label == "mosh-server" && r.OK {
 			moshOK = true
 		}
-		if r.Label == "tmux" && r.OK {
-			tmuxOK = true
+		if r.Label == "mosh UDP" && r.OK {
+			udpOK = true
+		}
+		if r.Label == mux && r.OK {
+			muxOK = true
+		}
+		if strings.HasPrefix(r.Label, "et") && !r.OK {
+			etOK = false
 		}
 	}
 
-	if moshOK && tmuxOK {
-		strategy = "mosh + tmux"
-	} else if tmuxOK {
-		strategy = "ssh + tmux"
+	usesET := connector.InChain(p, "et+tmux")
+	switch {
+	case usesET && etOK && mux == "none":
+		strategy = "et"
+	case usesET && etOK && muxOK:
+		strategy = "et + " + mux
+	case moshOK && udpOK && mux == "none":
+		strategy = "mosh"
+	case moshOK && udpOK && muxOK:
+		strategy = "mosh + " + mux
+	case muxOK:
+		strategy = "ssh + " + mux
 	}
 
 	fmt.Printf("\n→ Recommended strategy: %s\n", strategy)
-	if results[0].OK {
+	switch {
+	case results[0].OK:
 		fmt.Printf("→ You're all set! Run: sshtie connect %s\n", p.Name)
-	} else {
+	case p.Transport.Enabled():
+		fmt.Printf("→ Not reachable through %s — check the %s CLI's login and the instance settings.\n",
+			p.Transport, cloud.CLI(p.Transport))
+	default:
 		fmt.Printf("→ SSH unreachable — double-check the host (%s), port (%d), and your SSH key.\n", p.Host, port)
 	}
 	fmt.Println()
 }
 
 func checkSSH(p profile.Profile, port int) Result {
-	conn, err := net.DialTimeout("tcp", netAddr(p.Host, port), 5*time.Second)
+	if p.Transport.Enabled() {
+		return checkTransport(p)
+	}
+	hops, err := profile.JumpChain(p)
+	if err != nil {
+		return Result{"SSH connection", false, err.Error()}
+	}
+	host, ePort := profile.EntryPoint(p, hops)
+	if len(hops) == 0 {
+		ePort = port
+	}
+	conn, err := net.DialTimeout("tcp", netAddr(host, ePort), 5*time.Second)
 	if err != nil {
+		if len(hops) > 0 {
+			return Result{"SSH connection", false, fmt.Sprintf("Jump host %s unreachable (%v)", hops[0].Label(), err)}
+		}
 		return Result{"SSH connection", false, fmt.Sprintf("Unreachable (%v)", err)}
 	}
 	conn.Close()
+	if len(hops) > 0 {
+		return Result{"SSH connection", true, fmt.Sprintf("Reachable via %s", hops[0].Label())}
+	}
 	return Result{"SSH connection", true, "Reachable"}
 }
 
-func checkMoshServer(p profile.Profile, port int) Result {
-	// Ask the remote server where mosh-server is via SSH.
-	args := buildSSHArgs(p, port)
-	args = append(args, "which mosh-server 2>/dev/null || echo ''")
-
-	out, err := exec.Command("ssh", args...).Output()
-	if err != nil || strings.TrimSpace(string(out)) == "" {
-		// Try well-known paths
-		candidates := []string{
-			"/opt/homebrew/bin/mosh-server",
-			"/usr/local/bin/mosh-server",
-			"/usr/bin/mosh-server",
+// checkTransport asks the cloud CLI whether the instance is running, in
+// place of dialling an SSH port the server doesn't expose.
+func checkTransport(p profile.Profile) Result {
+	if err := profile.ValidateTransport(p); err != nil {
+		return Result{"SSH connection", false, err.Error()}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	if err := cloud.Running(ctx, p.Transport); err != nil {
+		return Result{"SSH connection", false, fmt.Sprintf("%s: %v", p.Transport, err)}
+	}
+	return Result{"SSH connection", true, "Instance running — via " + p.Transport.String()}
+}
+
+// checkLogin reports how the probe logged in and what it found there.
+func checkLogin(f probe.Facts, err error) Result {
+	if err != nil {
+		if probe.IsAuthError(err) {
+			return Result{"SSH login", false, "No key or agent login accepted (password-only server?)"}
 		}
-		for _, c := range candidates {
-			checkArgs := buildSSHArgs(p, port)
-			checkArgs = append(checkArgs, fmt.Sprintf("test -x %s && echo %s", c, c))
-			o, e := exec.Command("ssh", checkArgs...).Output()
-			if e == nil && strings.TrimSpace(string(o)) != "" {
-				return Result{"mosh-server", true, fmt.Sprintf("Found (%s)", strings.TrimSpace(string(o)))}
-			}
+		var changed *hostkey.ChangedError
+		if errors.As(err, &changed) {
+			return Result{"SSH login", false, fmt.Sprintf(
+				"Host key CHANGED (now %s) — if the server was reinstalled, run: sshtie hostkey pin %s",
+				strings.Join(changed.Presented, ", "), changed.Profile)}
 		}
+		return Result{"SSH login", false, err.Error()}
+	}
+	detail := fmt.Sprintf("%s · %s", f.Auth, f.OSName())
+	if f.Shell != "" {
+		detail += " · " + f.Shell
+	}
+	return Result{"SSH login", true, detail}
+}
+
+func checkMoshServer(p profile.Profile, f probe.Facts, err error) Result {
+	if p.Transport.Enabled() {
+		return Result{"mosh-server", false, fmt.Sprintf("Skipped (%s only carries ssh)", p.Transport)}
+	}
+	if err != nil {
+		return Result{"mosh-server", false, "Couldn't check (no SSH login)"}
+	}
+	if !f.Has("mosh-server") {
 		return Result{"mosh-server", false, fmt.Sprintf("Not installed — run: sshtie install %s", p.Name)}
 	}
-	path := strings.TrimSpace(string(out))
-	return Result{"mosh-server", true, fmt.Sprintf("Found (%s)", path)}
+	return Result{"mosh-server", true, fmt.Sprintf("Found (%s)", f.MoshServer)}
+}
+
+// checkMoshUDP starts a throwaway mosh-server and exchanges real mosh
+// datagrams with it. The verdict is cached for connect. Without a key login
+// it falls back to poking UDP port 60001, which can only spot a closed port.
+func checkMoshUDP(p profile.Profile, f probe.Facts, err error) Result {
+	const label = "mosh UDP"
+	if p.Transport.Enabled() {
+		return Result{label, false, fmt.Sprintf("Skipped (%s only carries ssh)", p.Transport)}
+	}
+	if err == nil && !f.Has("mosh-server") {
+		return Result{label, false, "Skipped (mosh-server not installed)"}
+	}
+	if err != nil {
+		return checkUDP(p.Host, 60001)
+	}
+	n := netenv.Current()
+	v, _, err := probe.CheckMosh(p, n, false)
+	switch {
+	case err != nil:
+		return Result{label, false, fmt.Sprintf("Couldn't test (%v)", err)}
+	case !v.OK:
+		return Result{label, false, "No reply — on the server, run: sudo ufw allow 60000:61000/udp"}
+	}
+	return Result{label, true, fmt.Sprintf("Round trip OK (port %d, %s)", v.Port, v.RTT.Round(time.Millisecond))}
 }
 
+// checkUDP is the fallback heuristic: silence counts as open.
 func checkUDP(host string, port int) Result {
-	label := fmt.Sprintf("UDP port %d", port)
+	label := "mosh UDP"
 	conn, err := net.DialTimeout("udp", netAddr(host, port), 3*time.Second)
 	if err != nil {
 		return Result{label, false, err.Error()}
@@ -132,19 +243,51 @@ func checkUDP(host string, port int) Result {
 	if readErr != nil && strings.Contains(readErr.Error(), "connection refused") {
 		return Result{label, false, "Blocked — on the server, run: sudo ufw allow 60000:61000/udp"}
 	}
-	return Result{label, true, "Open (mosh will confirm on connect)"}
+	return Result{label, true, fmt.Sprintf("Port %d not refused (unverified — no key login for the full test)", port)}
+}
+
+// checkMux looks for the profile's multiplexer (tmux, screen or zellij).
+func checkMux(p profile.Profile, mux string, f probe.Facts, err error) Result {
+	if mux == "none" {
+		return Result{"multiplexer", true, "none — sessions end when you disconnect"}
+	}
+	if err != nil {
+		return Result{mux, false, "Couldn't check (no SSH login)"}
+	}
+	if !f.Has(mux) {
+		return Result{mux, false, fmt.Sprintf("Not installed — run: sshtie install %s", p.Name)}
+	}
+	if mux == "tmux" {
+		return Result{mux, true, f.Tmux + " installed"}
+	}
+	return Result{mux, true, "installed"}
 }
 
-func checkTmux(p profile.Profile, port int) Result {
-	args := buildSSHArgs(p, port)
-	args = append(args, "tmux -V 2>/dev/null || echo ''")
+func checkETClient() Result {
+	path, err := connector.FindET()
+	if err != nil {
+		return Result{"et (local)", false, "Not installed — see https://eternalterminal.dev/download/"}
+	}
+	return Result{"et (local)", true, "Found (" + path + ")"}
+}
 
-	out, err := exec.Command("ssh", args...).Output()
-	version := strings.TrimSpace(string(out))
-	if err != nil || version == "" {
-		return Result{"tmux", false, fmt.Sprintf("Not installed — run: sshtie install %s", p.Name)}
+// checkETServer looks for etserver and whether its port answers.
+func checkETServer(p profile.Profile, f probe.Facts, err error) Result {
+	port := connector.ETPort(p)
+	conn, dialErr := net.DialTimeout("tcp", netAddr(p.Host, port), 3*time.Second)
+	reachable := dialErr == nil
+	if reachable {
+		conn.Close()
+	}
+	switch {
+	case err == nil && !f.Has("etserver"):
+		return Result{"etserver", false, fmt.Sprintf("Not installed — run: sshtie install %s --et", p.Name)}
+	case !reachable:
+		return Result{"etserver", false, fmt.Sprintf("Port %d/tcp unreachable — is etserver running, is the port open?", port)}
+	case err != nil:
+		return Result{"etserver", true, fmt.Sprintf("Port %d answers (couldn't check the install)", port)}
 	}
-	return Result{"tmux", true, version + " installed"}
+	return Result{"etserver", true, fmt.Sprintf("Found (%s), port %d answers", f.Tools["etserver"], port)}
 }
 
 func checkTailscaleClient() Result {
@@ -166,18 +309,3 @@ func checkTailscaleServer(host string) Result {
 	}
 	return Result{"Tailscale (server)", false, "Not visible — check 'tailscale status' on the server"}
 }
-
-func buildSSHArgs(p profile.Profile, port int) []string {
-	args := []string{
-		"-p", fmt.Sprintf("%d", port),
-		"-o", "StrictHostKeyChecking=accept-new",
-		"-o", "ConnectTimeout=5",
-		"-o", "BatchMode=yes",
-	}
-	key := p.DefaultKey()
-	if _, err := os.Stat(key); err == nil {
-		args = append(args, "-i", key)
-	}
-	args = append(args, fmt.Sprintf("%s@%s", p.User, p.Host))
-	return args
-}
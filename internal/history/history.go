@@ -0,0 +1,259 @@
+// Package history records what the connector did — strategies tried,
+// fallbacks, drops, reconnects — as JSON lines in ~/.sshtie/history.jsonl,
+// and summarizes them per profile for `sshtie history`.
+//
+// The log rotates at MaxSize into history.jsonl.1, .2, … keeping Keep old
+// files. Logging is best-effort: a failure to write never breaks a
+// connection.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+// Event kinds.
+const (
+	KindSkip      = "skip"      // a strategy's probe failed; it was not tried
+	KindRun       = "run"       // a strategy ran and exited (see Outcome)
+	KindFallback  = "fallback"  // a strategy failed at startup; moving to the next
+	KindReconnect = "reconnect" // the network came back; reconnecting
+	KindGiveUp    = "giveup"    // reconnecting was abandoned
+)
+
+// Run outcomes.
+const (
+	OutcomeClean   = "clean"   // the user ended the session
+	OutcomeStartup = "startup" // never came up
+	OutcomeDrop    = "drop"    // ran, then dropped
+)
+
+// Event is one line of the log.
+type Event struct {
+	Time     time.Time `json:"time"`
+	Profile  string    `json:"profile"`
+	Conn     string    `json:"conn"` // groups the events of one `sshtie connect`
+	Kind     string    `json:"kind"`
+	Strategy string    `json:"strategy,omitempty"`
+	Outcome  string    `json:"outcome,omitempty"`
+	Exit     string    `json:"exit,omitempty"`   // exit status / error text
+	Reason   string    `json:"reason,omitempty"` // why a strategy was skipped or abandoned
+	Attempt  int       `json:"attempt,omitempty"`
+	Duration float64   `json:"duration_s,omitempty"` // how long the run lasted
+	Downtime float64   `json:"downtime_s,omitempty"` // outage before a reconnect
+}
+
+// Rotation limits.
+var (
+	MaxSize int64 = 1 << 20
+	Keep          = 3
+)
+
+// Path returns ~/.sshtie/history.jsonl.
+func Path() (string, error) {
+	dir, err := profile.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history.jsonl"), nil
+}
+
+// Log appends e to the log, stamping the time if unset. Errors are ignored.
+func Log(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	_ = write(e)
+}
+
+func write(e Event) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	// Several sshtie processes log at once: the lock keeps one from
+	// rotating the file while another checks its size or appends to it.
+	unlock, err := profile.LockFile("history.jsonl")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if fi, err := os.Stat(path); err == nil && fi.Size()+int64(len(line)) > MaxSize {
+		rotate(path)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(line)
+	return err
+}
+
+// rotate shifts history.jsonl → .1 → .2 …, dropping the oldest.
+func rotate(path string) {
+	_ = os.Remove(fmt.Sprintf("%s.%d", path, Keep))
+	for i := Keep - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", path, i), fmt.Sprintf("%s.%d", path, i+1))
+	}
+	_ = os.Rename(path, path+".1")
+}
+
+// Read returns every logged event, oldest first. An empty name returns all
+// profiles. Malformed lines are skipped.
+func Read(name string) ([]Event, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, Keep+1)
+	for i := Keep; i >= 1; i-- {
+		files = append(files, fmt.Sprintf("%s.%d", path, i))
+	}
+	files = append(files, path)
+
+	var out []Event
+	for _, fpath := range files {
+		f, err := os.Open(fpath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read history: %w", err)
+		}
+		sc := bufio.NewScanner(f)
+		sc.Buffer(make([]byte, 64*1024), 1<<20)
+		for sc.Scan() {
+			var e Event
+			if json.Unmarshal(sc.Bytes(), &e) != nil {
+				continue
+			}
+			if name == "" || e.Profile == name {
+				out = append(out, e)
+			}
+		}
+		err = sc.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read history: %w", err)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Time.Before(out[j].Time) })
+	return out, nil
+}
+
+// Recent returns the last n events, or none when n isn't positive.
+func Recent(events []Event, n int) []Event {
+	if n <= 0 {
+		return nil
+	}
+	if len(events) > n {
+		return events[len(events)-n:]
+	}
+	return events
+}
+
+// Summary aggregates one profile's history.
+type Summary struct {
+	Profile   string
+	Sessions  int           // connects that got a session up
+	Failed    int           // connects where nothing came up
+	Total     time.Duration // time spent connected
+	Drops     int
+	Reconnect int    // reconnect attempts
+	Method    string // most used strategy
+	Last      time.Time
+
+	clean int // runs the user ended
+}
+
+// DropRate is the share of runs that came up and then dropped.
+func (s Summary) DropRate() float64 {
+	up := s.Drops + s.clean
+	if up == 0 {
+		return 0
+	}
+	return float64(s.Drops) / float64(up)
+}
+
+// Summarize groups events by profile, sorted by name.
+func Summarize(events []Event) []Summary {
+	type conn struct {
+		up bool
+	}
+	byName := map[string]*Summary{}
+	conns := map[string]map[string]*conn{}
+	methods := map[string]map[string]int{}
+	for _, e := range events {
+		s, ok := byName[e.Profile]
+		if !ok {
+			s = &Summary{Profile: e.Profile}
+			byName[e.Profile] = s
+			conns[e.Profile] = map[string]*conn{}
+			methods[e.Profile] = map[string]int{}
+		}
+		if e.Time.After(s.Last) {
+			s.Last = e.Time
+		}
+		c, ok := conns[e.Profile][e.Conn]
+		if !ok {
+			c = &conn{}
+			conns[e.Profile][e.Conn] = c
+		}
+		switch e.Kind {
+		case KindRun:
+			if e.Outcome == OutcomeStartup {
+				continue
+			}
+			c.up = true
+			s.Total += time.Duration(e.Duration * float64(time.Second))
+			methods[e.Profile][e.Strategy]++
+			if e.Outcome == OutcomeDrop {
+				s.Drops++
+			} else {
+				s.clean++
+			}
+		case KindReconnect:
+			s.Reconnect++
+		}
+	}
+
+	out := make([]Summary, 0, len(byName))
+	for name, s := range byName {
+		for _, c := range conns[name] {
+			if c.up {
+				s.Sessions++
+			} else {
+				s.Failed++
+			}
+		}
+		s.Method = topMethod(methods[name])
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Profile < out[j].Profile })
+	return out
+}
+
+// topMethod returns the most used strategy, ties broken by name.
+func topMethod(counts map[string]int) string {
+	best, n := "", 0
+	for m, c := range counts {
+		if c > n || (c == n && m < best) {
+			best, n = m, c
+		}
+	}
+	return best
+}
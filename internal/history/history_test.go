@@ -0,0 +1,124 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLogRead_rotates(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	oldSize, oldKeep := MaxSize, Keep
+	MaxSize, Keep = 400, 2
+	t.Cleanup(func() { MaxSize, Keep = oldSize, oldKeep })
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 20; i++ {
+		Log(Event{Time: base.Add(time.Duration(i) * time.Second), Profile: "a", Conn: "c", Kind: KindSkip})
+	}
+	path, _ := Path()
+	if _, err := os.Stat(path + ".2"); err != nil {
+		t.Fatalf("expected rotated file: %v", err)
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("kept more than %d rotated files", Keep)
+	}
+
+	events, err := Read("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) == 0 || len(events) >= 20 {
+		t.Fatalf("read %d events, want some but not all after rotation", len(events))
+	}
+	for i := 1; i < len(events); i++ {
+		if events[i].Time.Before(events[i-1].Time) {
+			t.Fatal("events not in time order")
+		}
+	}
+	if last := events[len(events)-1].Time; !last.Equal(base.Add(19 * time.Second)) {
+		t.Errorf("newest event = %s, want the last one logged", last)
+	}
+}
+
+func TestLog_concurrentRotation(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+	oldSize, oldKeep := MaxSize, Keep
+	MaxSize, Keep = 2000, 50
+	t.Cleanup(func() { MaxSize, Keep = oldSize, oldKeep })
+
+	// Each writer takes the file lock on its own descriptor, like separate
+	// processes would; every event must survive the rotations.
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := write(Event{Time: time.Now(), Profile: fmt.Sprint(i), Kind: KindSkip}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	events, err := Read("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 200 {
+		t.Errorf("read %d events, want 200", len(events))
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	events := []Event{
+		// conn 1: mosh skipped, ssh+tmux runs 10m, drops, reconnects, ends cleanly.
+		{Profile: "a", Conn: "1", Kind: KindSkip, Strategy: "mosh+tmux"},
+		{Profile: "a", Conn: "1", Kind: KindRun, Strategy: "ssh+tmux", Outcome: OutcomeDrop, Duration: 600},
+		{Profile: "a", Conn: "1", Kind: KindReconnect, Strategy: "ssh+tmux", Attempt: 1, Downtime: 30},
+		{Profile: "a", Conn: "1", Kind: KindRun, Strategy: "ssh+tmux", Outcome: OutcomeClean, Duration: 60, Attempt: 1},
+		// conn 2: nothing came up.
+		{Profile: "a", Conn: "2", Kind: KindRun, Strategy: "ssh", Outcome: OutcomeStartup, Duration: 0.5},
+		// conn 3: plain ssh, clean.
+		{Profile: "a", Conn: "3", Kind: KindRun, Strategy: "ssh", Outcome: OutcomeClean, Duration: 60},
+		{Profile: "b", Conn: "4", Kind: KindRun, Strategy: "mosh+tmux", Outcome: OutcomeClean, Duration: 5},
+	}
+	got := Summarize(events)
+	if len(got) != 2 || got[0].Profile != "a" || got[1].Profile != "b" {
+		t.Fatalf("Summarize = %+v", got)
+	}
+	a := got[0]
+	if a.Sessions != 2 || a.Failed != 1 {
+		t.Errorf("sessions/failed = %d/%d, want 2/1", a.Sessions, a.Failed)
+	}
+	if a.Total != 12*time.Minute {
+		t.Errorf("total = %s, want 12m", a.Total)
+	}
+	if a.Drops != 1 || a.Reconnect != 1 {
+		t.Errorf("drops/reconnects = %d/%d, want 1/1", a.Drops, a.Reconnect)
+	}
+	if r := a.DropRate(); r < 0.33 || r > 0.34 {
+		t.Errorf("drop rate = %.2f, want 1/3", r)
+	}
+	if a.Method != "ssh+tmux" {
+		t.Errorf("method = %q, want ssh+tmux", a.Method)
+	}
+}
+
+func TestRecent(t *testing.T) {
+	events := []Event{{Conn: "1"}, {Conn: "2"}, {Conn: "3"}}
+	for _, tc := range []struct {
+		n    int
+		want int
+	}{{2, 2}, {3, 3}, {10, 3}, {0, 0}, {-1, 0}} {
+		got := Recent(events, tc.n)
+		if len(got) != tc.want {
+			t.Errorf("Recent(%d) = %d events, want %d", tc.n, len(got), tc.want)
+		}
+		if len(got) > 0 && got[len(got)-1].Conn != "3" {
+			t.Errorf("Recent(%d) dropped the newest event", tc.n)
+		}
+	}
+}
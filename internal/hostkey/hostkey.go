@@ -0,0 +1,353 @@
+// Package hostkey pins the SSH host keys of profiles.
+//
+// A profile's host_keys lists the SHA256 fingerprints it trusts. The keys
+// themselves live in a known_hosts file sshtie manages
+// (~/.sshtie/known_hosts), filed under an alias derived from the profile
+// name rather than its address. ssh is pointed at that file with
+// HostKeyAlias and StrictHostKeyChecking=yes, so every address a profile
+// has (see package locate) is checked against the same keys, and a changed
+// key stops the connection instead of being accepted or waved through.
+//
+// Because profiles.yaml carries the fingerprints, a machine that has the
+// profiles but not the managed file rebuilds it on the next connect from
+// whatever key the server presents — as long as it matches a pin.
+package hostkey
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+// ChangedError is returned when a server presents a key that doesn't match
+// what is pinned for it.
+type ChangedError struct {
+	Profile   string
+	Addr      string   // host:port that presented the key
+	Expected  []string // fingerprints trusted so far
+	Presented []string // fingerprints the server offered
+	Source    string   // where Expected comes from: "profile" or a known_hosts path
+}
+
+func (e *ChangedError) Error() string {
+	return fmt.Sprintf("host key of %q (%s) has changed: presented %s, pinned %s",
+		e.Profile, e.Addr, strings.Join(e.Presented, ", "), strings.Join(e.Expected, ", "))
+}
+
+var mu sync.Mutex
+
+// Path returns ~/.sshtie/known_hosts.
+func Path() (string, error) {
+	dir, err := profile.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "known_hosts"), nil
+}
+
+// Alias is the name p's keys are filed under in the managed known_hosts,
+// passed to ssh as HostKeyAlias.
+func Alias(name string) string { return "sshtie." + name }
+
+// Fingerprint returns k's SHA256 fingerprint, e.g. "SHA256:uNiVztksCs…".
+func Fingerprint(k ssh.PublicKey) string { return ssh.FingerprintSHA256(k) }
+
+// Fingerprints returns the fingerprints of keys.
+func Fingerprints(keys []ssh.PublicKey) []string {
+	out := make([]string, len(keys))
+	for i, k := range keys {
+		out[i] = Fingerprint(k)
+	}
+	return out
+}
+
+// Pinned reports whether p trusts specific host keys.
+func Pinned(p profile.Profile) bool { return len(p.HostKeys) > 0 }
+
+// Matches reports whether k is one of p's pinned keys.
+func Matches(p profile.Profile, k ssh.PublicKey) bool {
+	fp := Fingerprint(k)
+	for _, want := range p.HostKeys {
+		if want == fp {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify checks the keys a server presented for p against its pins. It
+// returns nil when p isn't pinned or any presented key is pinned.
+func Verify(p profile.Profile, addr string, presented []ssh.PublicKey) error {
+	if !Pinned(p) {
+		return nil
+	}
+	for _, k := range presented {
+		if Matches(p, k) {
+			return nil
+		}
+	}
+	return &ChangedError{Profile: p.Name, Addr: addr, Expected: p.HostKeys,
+		Presented: Fingerprints(presented), Source: "profile"}
+}
+
+// Callback is a HostKeyCallback that enforces p's pins. Unpinned profiles
+// fall back to fallback.
+func Callback(p profile.Profile, fallback ssh.HostKeyCallback) ssh.HostKeyCallback {
+	if !Pinned(p) {
+		return fallback
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		return Verify(p, hostname, []ssh.PublicKey{key})
+	}
+}
+
+// ── managed known_hosts ──────────────────────────────────────────────────────
+
+// Keys returns the keys filed for name in the managed known_hosts.
+func Keys(name string) ([]ssh.PublicKey, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	lines, err := readLines()
+	if err != nil {
+		return nil, err
+	}
+	var keys []ssh.PublicKey
+	for _, l := range lines {
+		if l.alias == Alias(name) {
+			keys = append(keys, l.key)
+		}
+	}
+	return keys, nil
+}
+
+// TrustedKeys returns the keys in the managed file that p also pins: the
+// ones ssh may accept for it.
+func TrustedKeys(p profile.Profile) []ssh.PublicKey {
+	if !Pinned(p) {
+		return nil
+	}
+	keys, err := Keys(p.Name)
+	if err != nil {
+		return nil
+	}
+	var out []ssh.PublicKey
+	for _, k := range keys {
+		if Matches(p, k) {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// Store replaces name's keys in the managed known_hosts.
+func Store(name string, keys []ssh.PublicKey) error {
+	return rewrite(func(lines []line) []line {
+		out := dropAlias(lines, Alias(name))
+		for _, k := range keys {
+			out = append(out, line{alias: Alias(name), key: k})
+		}
+		return out
+	})
+}
+
+// Forget removes name from the managed known_hosts.
+func Forget(name string) error {
+	return rewrite(func(lines []line) []line { return dropAlias(lines, Alias(name)) })
+}
+
+// Rename refiles oldName's keys under newName.
+func Rename(oldName, newName string) error {
+	return rewrite(func(lines []line) []line {
+		for i := range lines {
+			if lines[i].alias == Alias(oldName) {
+				lines[i].alias = Alias(newName)
+			}
+		}
+		return lines
+	})
+}
+
+// Pin trusts keys for the named profile: they go into the managed
+// known_hosts and their fingerprints replace the profile's host_keys.
+func Pin(name string, keys []ssh.PublicKey) error {
+	if len(keys) == 0 {
+		return errors.New("no host keys to pin")
+	}
+	if err := Store(name, keys); err != nil {
+		return err
+	}
+	return profile.Update(func(ps *[]profile.Profile) error {
+		for i := range *ps {
+			if (*ps)[i].Name == name {
+				(*ps)[i].HostKeys = Fingerprints(keys)
+				return nil
+			}
+		}
+		return fmt.Errorf("profile %q not found", name)
+	})
+}
+
+// SSHOptions returns the ssh -o options that check p's host key: strict
+// checking against the managed known_hosts whenever p is pinned — even if
+// the file doesn't hold the pinned keys yet, in which case ssh refuses
+// rather than accepting whatever it is shown — and ssh's accept-new for
+// unpinned profiles.
+func SSHOptions(p profile.Profile) []string {
+	if !Pinned(p) {
+		return []string{"-o", "StrictHostKeyChecking=accept-new"}
+	}
+	opts := []string{"-o", "StrictHostKeyChecking=yes"}
+	if path, err := Path(); err == nil {
+		opts = append(opts, "-o", "UserKnownHostsFile="+path)
+	}
+	return append(opts, "-o", "HostKeyAlias="+Alias(p.Name))
+}
+
+// Algorithms lists the host key algorithms to ask a server for so it
+// presents one of keys.
+func Algorithms(keys []ssh.PublicKey) []string {
+	var algos []string
+	for _, k := range keys {
+		switch t := k.Type(); t {
+		case ssh.KeyAlgoRSA:
+			algos = append(algos, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA)
+		default:
+			algos = append(algos, t)
+		}
+	}
+	return algos
+}
+
+// KnownElsewhere checks presented against the user's own ~/.ssh/known_hosts
+// for addr (host:port). It returns a ChangedError when that file knows addr
+// and none of the presented keys matches.
+func KnownElsewhere(name, addr string, presented []ssh.PublicKey) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	path := filepath.Join(home, ".ssh", "known_hosts")
+	check, err := knownhosts.New(path)
+	if err != nil {
+		return nil
+	}
+	var expected []string
+	for _, k := range presented {
+		err := check(addr, &net.TCPAddr{}, k)
+		var ke *knownhosts.KeyError
+		switch {
+		case err == nil:
+			return nil
+		case errors.As(err, &ke) && len(ke.Want) == 0:
+			return nil // not known there
+		case errors.As(err, &ke):
+			expected = expected[:0]
+			for _, w := range ke.Want {
+				expected = append(expected, Fingerprint(w.Key))
+			}
+		}
+	}
+	if len(expected) == 0 {
+		return nil
+	}
+	return &ChangedError{Profile: name, Addr: addr, Expected: expected,
+		Presented: Fingerprints(presented), Source: path}
+}
+
+// ── file handling ────────────────────────────────────────────────────────────
+
+type line struct {
+	alias string
+	key   ssh.PublicKey
+	raw   string // lines sshtie didn't write, kept verbatim
+}
+
+func dropAlias(lines []line, alias string) []line {
+	out := lines[:0]
+	for _, l := range lines {
+		if l.alias != alias {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func readLines() ([]line, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []line
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		text := sc.Text()
+		_, hosts, key, _, _, err := ssh.ParseKnownHosts([]byte(text))
+		if err != nil || len(hosts) != 1 || !strings.HasPrefix(hosts[0], "sshtie.") {
+			if strings.TrimSpace(text) != "" {
+				out = append(out, line{raw: text})
+			}
+			continue
+		}
+		out = append(out, line{alias: hosts[0], key: key})
+	}
+	return out, sc.Err()
+}
+
+func rewrite(fn func([]line) []line) error {
+	mu.Lock()
+	defer mu.Unlock()
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	lines, err := readLines()
+	if err != nil {
+		return err
+	}
+	lines = fn(lines)
+	if _, err := os.Stat(path); len(lines) == 0 && os.IsNotExist(err) {
+		return nil // no file, and nothing to put in one
+	}
+
+	var b strings.Builder
+	b.WriteString("# Managed by sshtie — host keys pinned per profile (sshtie hostkey).\n")
+	for _, l := range lines {
+		switch {
+		case l.raw != "":
+			if strings.HasPrefix(l.raw, "# Managed by sshtie") {
+				continue
+			}
+			b.WriteString(l.raw + "\n")
+		default:
+			b.WriteString(knownhosts.Line([]string{l.alias}, l.key) + "\n")
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp" + strconv.Itoa(os.Getpid())
+	if err := os.WriteFile(tmp, []byte(b.String()), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,167 @@
+package hostkey
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+func testHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	return home
+}
+
+func newKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestStoreRenameForget(t *testing.T) {
+	testHome(t)
+	a, b := newKey(t), newKey(t)
+
+	if err := Forget("nothing"); err != nil {
+		t.Fatal(err)
+	}
+	if path, _ := Path(); fileExists(path) {
+		t.Error("Forget created an empty known_hosts")
+	}
+
+	if err := Store("web", []ssh.PublicKey{a}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Store("db", []ssh.PublicKey{b}); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := Keys("web"); len(keys) != 1 || Fingerprint(keys[0]) != Fingerprint(a) {
+		t.Fatalf("Keys(web) = %v", keys)
+	}
+
+	if err := Rename("web", "www"); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := Keys("web"); len(keys) != 0 {
+		t.Errorf("web still has keys after rename")
+	}
+	if keys, _ := Keys("www"); len(keys) != 1 {
+		t.Errorf("www has %d keys after rename", len(keys))
+	}
+
+	if err := Forget("www"); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := Keys("www"); len(keys) != 0 {
+		t.Errorf("www still has keys after forget")
+	}
+	if keys, _ := Keys("db"); len(keys) != 1 {
+		t.Errorf("forget touched db")
+	}
+
+	// The file is a plain known_hosts that ssh can use with HostKeyAlias.
+	path, _ := Path()
+	check, err := knownhosts.New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := check(Alias("db")+":22", &net.TCPAddr{}, b); err != nil {
+		t.Errorf("ssh wouldn't accept db's key: %v", err)
+	}
+}
+
+func TestPinVerify(t *testing.T) {
+	testHome(t)
+	if err := profile.Add(profile.Profile{Name: "web", Host: "10.0.0.5", User: "me"}); err != nil {
+		t.Fatal(err)
+	}
+	good, bad := newKey(t), newKey(t)
+
+	p, _ := profile.Get("web")
+	if got := strings.Join(SSHOptions(p), " "); got != "-o StrictHostKeyChecking=accept-new" {
+		t.Errorf("unpinned SSHOptions = %q", got)
+	}
+
+	if err := Pin("web", []ssh.PublicKey{good}); err != nil {
+		t.Fatal(err)
+	}
+	p, _ = profile.Get("web")
+	if len(p.HostKeys) != 1 || p.HostKeys[0] != Fingerprint(good) {
+		t.Fatalf("HostKeys = %v", p.HostKeys)
+	}
+	if err := Verify(p, "10.0.0.5:22", []ssh.PublicKey{bad, good}); err != nil {
+		t.Errorf("Verify(pinned key) = %v", err)
+	}
+	err := Verify(p, "10.0.0.5:22", []ssh.PublicKey{bad})
+	var changed *ChangedError
+	if !errors.As(err, &changed) || changed.Presented[0] != Fingerprint(bad) {
+		t.Errorf("Verify(other key) = %v", err)
+	}
+	if err := Callback(p, nil)("10.0.0.5:22", nil, bad); err == nil {
+		t.Error("Callback accepted a key that isn't pinned")
+	}
+
+	opts := strings.Join(SSHOptions(p), " ")
+	for _, want := range []string{"StrictHostKeyChecking=yes", "HostKeyAlias=sshtie.web", "UserKnownHostsFile="} {
+		if !strings.Contains(opts, want) {
+			t.Errorf("pinned SSHOptions = %q, missing %s", opts, want)
+		}
+	}
+
+	// Pinned in profiles.yaml but not in this machine's known_hosts (a
+	// synced profiles.yaml): still strict, so ssh refuses any key rather
+	// than accepting a new one.
+	if err := Forget("web"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(SSHOptions(p), " "); got != opts {
+		t.Errorf("SSHOptions without stored keys = %q, want %q", got, opts)
+	}
+}
+
+func TestKnownElsewhere(t *testing.T) {
+	home := testHome(t)
+	known, other := newKey(t), newKey(t)
+	if err := KnownElsewhere("web", "10.0.0.5:22", []ssh.PublicKey{other}); err != nil {
+		t.Errorf("no ~/.ssh/known_hosts: %v", err)
+	}
+
+	os.MkdirAll(filepath.Join(home, ".ssh"), 0o700)
+	line := knownhosts.Line([]string{knownhosts.Normalize("10.0.0.5:22")}, known)
+	os.WriteFile(filepath.Join(home, ".ssh", "known_hosts"), []byte(line+"\n"), 0o600)
+
+	if err := KnownElsewhere("web", "10.0.0.5:22", []ssh.PublicKey{other, known}); err != nil {
+		t.Errorf("a matching key: %v", err)
+	}
+	if err := KnownElsewhere("web", "10.0.0.9:22", []ssh.PublicKey{other}); err != nil {
+		t.Errorf("an unknown host: %v", err)
+	}
+	var changed *ChangedError
+	if err := KnownElsewhere("web", "10.0.0.5:22", []ssh.PublicKey{other}); !errors.As(err, &changed) ||
+		changed.Expected[0] != Fingerprint(known) {
+		t.Errorf("a changed key: %v", err)
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
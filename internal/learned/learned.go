@@ -0,0 +1,182 @@
+// Package learned remembers which connection strategies failed for a
+// profile on a given local network, so the connector can skip them the next
+// time it is on that network instead of waiting for them to fail again.
+//
+// Entries live in ~/.sshtie/learned.json, keyed by profile, network
+// fingerprint (see netenv) and strategy. A strategy that works clears its
+// entry. The CLI and the tray both write the file, so changes happen under
+// its lock file and replace it atomically, the way profiles.yaml is written.
+package learned
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ainsuotain/sshtie/internal/netenv"
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+// Entry is one remembered failure.
+type Entry struct {
+	Profile  string    `json:"profile"`
+	Network  string    `json:"network"`      // netenv.Network.ID()
+	Describe string    `json:"network_desc"` // netenv.Network.String(), for humans
+	Strategy string    `json:"strategy"`
+	Reason   string    `json:"reason"`
+	Failures int       `json:"failures"` // consecutive failures on this network
+	Last     time.Time `json:"last"`
+}
+
+// errCorrupt marks a learned.json that doesn't parse.
+var errCorrupt = errors.New("learned.json is damaged — reset it with: sshtie learned clear")
+
+// Path returns ~/.sshtie/learned.json.
+func Path() (string, error) {
+	dir, err := profile.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "learned.json"), nil
+}
+
+func key(name, network, strategy string) string { return name + "|" + network + "|" + strategy }
+
+func load() (map[string]Entry, error) {
+	m := map[string]Entry{}
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%w (%v)", errCorrupt, err)
+	}
+	return m, nil
+}
+
+// update runs fn on the entries under the learned.json lock and saves them
+// when fn reports a change.
+func update(fn func(m map[string]Entry) bool) error {
+	unlock, err := profile.LockFile("learned.json")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	m, err := load()
+	if err != nil {
+		return err
+	}
+	if !fn(m) {
+		return nil
+	}
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return profile.WriteFileAtomic(path, data, 0o600)
+}
+
+// Failed records that strategy failed for name on n.
+func Failed(name string, n netenv.Network, strategy, reason string) error {
+	return update(func(m map[string]Entry) bool {
+		k := key(name, n.ID(), strategy)
+		m[k] = Entry{
+			Profile:  name,
+			Network:  n.ID(),
+			Describe: n.String(),
+			Strategy: strategy,
+			Reason:   reason,
+			Failures: m[k].Failures + 1,
+			Last:     time.Now(),
+		}
+		return true
+	})
+}
+
+// Worked forgets any failure of strategy for name on n.
+func Worked(name string, n netenv.Network, strategy string) error {
+	return update(func(m map[string]Entry) bool {
+		k := key(name, n.ID(), strategy)
+		if _, ok := m[k]; !ok {
+			return false
+		}
+		delete(m, k)
+		return true
+	})
+}
+
+// Recent returns the failure of strategy for name on n if it happened
+// within the last `within`.
+func Recent(name string, n netenv.Network, strategy string, within time.Duration) (Entry, bool) {
+	if within <= 0 {
+		return Entry{}, false
+	}
+	m, err := load()
+	if err != nil {
+		return Entry{}, false
+	}
+	e, ok := m[key(name, n.ID(), strategy)]
+	if !ok || time.Since(e.Last) > within {
+		return Entry{}, false
+	}
+	return e, true
+}
+
+// List returns every entry for name ("" = all profiles), newest first.
+func List(name string) ([]Entry, error) {
+	m, err := load()
+	if err != nil {
+		return nil, err
+	}
+	var out []Entry
+	for _, e := range m {
+		if name == "" || e.Profile == name {
+			out = append(out, e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Last.After(out[j].Last) })
+	return out, nil
+}
+
+// Clear forgets entries for name ("" = all profiles), limited to network n
+// when n is non-nil. It returns how many entries were removed. Clearing
+// everything also gets rid of a damaged file.
+func Clear(name string, n *netenv.Network) (int, error) {
+	removed := 0
+	err := update(func(m map[string]Entry) bool {
+		for k, e := range m {
+			if name != "" && e.Profile != name {
+				continue
+			}
+			if n != nil && e.Network != n.ID() {
+				continue
+			}
+			delete(m, k)
+			removed++
+		}
+		return removed > 0
+	})
+	if errors.Is(err, errCorrupt) && name == "" && n == nil {
+		path, perr := Path()
+		if perr != nil {
+			return 0, perr
+		}
+		return 0, os.Remove(path)
+	}
+	return removed, err
+}
@@ -0,0 +1,102 @@
+package learned
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ainsuotain/sshtie/internal/netenv"
+)
+
+func TestLearned(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+	office := netenv.Network{Gateway: "10.0.0.1", Subnet: "10.0.0.0/16"}
+	home := netenv.Network{Gateway: "192.168.1.1", Subnet: "192.168.1.0/24"}
+
+	if err := Failed("srv", office, "mosh+tmux", "UDP blocked"); err != nil {
+		t.Fatal(err)
+	}
+	Failed("srv", office, "mosh+tmux", "UDP blocked")
+
+	e, ok := Recent("srv", office, "mosh+tmux", time.Hour)
+	if !ok || e.Failures != 2 || e.Describe != office.String() {
+		t.Errorf("office = %+v, %v", e, ok)
+	}
+	if _, ok := Recent("srv", home, "mosh+tmux", time.Hour); ok {
+		t.Error("failure leaked to another network")
+	}
+	if _, ok := Recent("srv", office, "mosh+tmux", 0); ok {
+		t.Error("zero memory should never skip")
+	}
+
+	Worked("srv", office, "mosh+tmux")
+	if _, ok := Recent("srv", office, "mosh+tmux", time.Hour); ok {
+		t.Error("Worked should clear the failure")
+	}
+
+	Failed("srv", office, "ssh+tmux", "x")
+	Failed("srv", home, "ssh+tmux", "x")
+	Failed("other", home, "ssh", "x")
+	if n, _ := Clear("srv", &home); n != 1 {
+		t.Errorf("Clear(srv, home) removed %d", n)
+	}
+	if all, _ := List(""); len(all) != 2 {
+		t.Errorf("List = %+v", all)
+	}
+	if n, _ := Clear("", nil); n != 2 {
+		t.Errorf("Clear(all) removed %d", n)
+	}
+}
+
+func TestLearned_concurrentWriters(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+	office := netenv.Network{Gateway: "10.0.0.1", Subnet: "10.0.0.0/16"}
+
+	// Each writer takes the file lock on its own descriptor, like separate
+	// processes would; none of their updates may be lost.
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := Failed(fmt.Sprintf("srv%d", i), office, "ssh", "x"); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if all, err := List(""); err != nil || len(all) != 20 {
+		t.Errorf("List = %d entries (%v), want 20", len(all), err)
+	}
+}
+
+func TestLearned_damagedFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+	office := netenv.Network{Gateway: "10.0.0.1", Subnet: "10.0.0.0/16"}
+	path, _ := Path()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`{"srv|x|ssh": {"prof`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := List(""); err == nil {
+		t.Error("List of a damaged file: want error")
+	}
+	if err := Failed("srv", office, "ssh", "x"); err == nil {
+		t.Error("Failed on a damaged file: want error, not a silent reset")
+	}
+	if _, err := Clear("", nil); err != nil {
+		t.Fatalf("Clear(all): %v", err)
+	}
+	if err := Failed("srv", office, "ssh", "x"); err != nil {
+		t.Errorf("after clear: %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+// Package locate picks which of a profile's candidate addresses (see
+// profile.Address) to use from the network this machine is on right now.
+//
+// Candidates whose conditions don't hold here are dropped; the rest are
+// dialled happy-eyeballs style — in order of preference, each one getting a
+// short head start before the next is tried alongside it — and the first to
+// accept a TCP connection wins.
+package locate
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/ainsuotain/sshtie/internal/netenv"
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+// Stagger is the head start each candidate gets before the next one is
+// dialled alongside it.
+var Stagger = 250 * time.Millisecond
+
+// DefaultTimeout bounds the whole race.
+const DefaultTimeout = 3 * time.Second
+
+// dial opens a TCP connection; tests replace it.
+var dial = func(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", addr)
+}
+
+// Choice is the address picked for a profile.
+type Choice struct {
+	Address   profile.Address // the candidate as written in the profile
+	Index     int             // its position in Profile.Candidates
+	Of        int             // how many candidates the profile has
+	Reachable bool            // it accepted a TCP connection (never set behind a jump chain)
+}
+
+// Multiple reports whether there was anything to choose from.
+func (c Choice) Multiple() bool { return c.Of > 1 }
+
+// String describes the choice, e.g. "192.168.1.10 (subnet 192.168.1.0/24) · 1 of 3".
+func (c Choice) String() string {
+	return fmt.Sprintf("%s · %d of %d", c.Address, c.Index+1, c.Of)
+}
+
+// Matches reports whether a's conditions hold on n.
+func Matches(a profile.Address, n netenv.Network) bool {
+	if a.Tailscale && !n.Tailscale {
+		return false
+	}
+	if a.Subnet != "" {
+		_, want, err := net.ParseCIDR(a.Subnet)
+		if err != nil {
+			return false
+		}
+		local, _, err := net.ParseCIDR(n.Subnet)
+		if err != nil || !want.Contains(local) {
+			return false
+		}
+	}
+	return true
+}
+
+// Pick chooses p's address on n. Behind a jump chain the candidates can't be
+// dialled from here, so the first eligible one is taken as-is. Otherwise the
+// eligible candidates race for up to timeout; when none answers, the first
+// eligible one is returned with Reachable unset, so the caller fails the
+// way it would have with a single address. A profile whose transport can't
+// take candidate addresses is refused here, before Apply clears them and
+// hides the conflict from later checks.
+func Pick(p profile.Profile, n netenv.Network, timeout time.Duration) (Choice, error) {
+	if err := profile.ValidateTransport(p); err != nil {
+		return Choice{}, err
+	}
+	cands := p.Candidates()
+	var eligible []int
+	for i, a := range cands {
+		if err := a.Validate(); err != nil {
+			return Choice{}, fmt.Errorf("profile %q: %w", p.Name, err)
+		}
+		if Matches(a, n) {
+			eligible = append(eligible, i)
+		}
+	}
+	if len(eligible) == 0 {
+		return Choice{}, fmt.Errorf("profile %q: no address applies on this network (%s)", p.Name, n)
+	}
+
+	choice := Choice{Address: cands[eligible[0]], Index: eligible[0], Of: len(cands)}
+	if len(p.Jump) > 0 {
+		return choice, nil
+	}
+	addrs := make([]string, len(eligible))
+	for i, idx := range eligible {
+		addrs[i] = dialAddr(p, cands[idx])
+	}
+	if won, ok := race(addrs, timeout); ok {
+		choice.Address, choice.Index, choice.Reachable = cands[eligible[won]], eligible[won], true
+	}
+	return choice, nil
+}
+
+// Apply points p at the address Pick chooses on the current network, with
+// Addresses cleared so the result is an ordinary single-address profile.
+// Profiles without addresses come back untouched, without any dialling.
+func Apply(p profile.Profile) (profile.Profile, Choice, error) {
+	if len(p.Addresses) == 0 {
+		return p, Choice{Address: profile.Address{Host: p.Host}, Of: 1}, nil
+	}
+	choice, err := Pick(p, network(p), DefaultTimeout)
+	if err != nil {
+		return p, Choice{}, err
+	}
+	return Use(p, choice), choice, nil
+}
+
+// Use returns p pointed at choice's address.
+func Use(p profile.Profile, choice Choice) profile.Profile {
+	p.Host = choice.Address.Host
+	if choice.Address.Port != 0 {
+		p.Port = choice.Address.Port
+	}
+	p.Addresses = nil
+	return p
+}
+
+// network inspects the local network, but only when one of p's candidates
+// depends on it — that takes a couple of subprocesses.
+func network(p profile.Profile) netenv.Network {
+	for _, a := range p.Addresses {
+		if a.Conditional() {
+			return netenv.Current()
+		}
+	}
+	return netenv.Network{}
+}
+
+func dialAddr(p profile.Profile, a profile.Address) string {
+	port := a.Port
+	if port == 0 {
+		port = p.Port
+	}
+	if port == 0 {
+		port = 22
+	}
+	return net.JoinHostPort(a.Host, strconv.Itoa(port))
+}
+
+// race dials addrs in order, starting the next one after Stagger or as soon
+// as the previous one fails, and returns the index of the first to connect.
+func race(addrs []string, timeout time.Duration) (int, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	type result struct {
+		i   int
+		err error
+	}
+	results := make(chan result, len(addrs))
+	next, pending := 0, 0
+	start := func() {
+		i := next
+		next++
+		pending++
+		go func() {
+			conn, err := dial(ctx, addrs[i])
+			if err == nil {
+				conn.Close()
+			}
+			results <- result{i, err}
+		}()
+	}
+
+	start()
+	timer := time.NewTimer(Stagger)
+	defer timer.Stop()
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				return r.i, true
+			}
+			if next < len(addrs) {
+				start()
+				timer.Reset(Stagger)
+			}
+		case <-timer.C:
+			if next < len(addrs) {
+				start()
+				timer.Reset(Stagger)
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,160 @@
+package locate
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ainsuotain/sshtie/internal/netenv"
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+// fakeDial answers per address after a delay; a negative delay refuses.
+// It also restores Stagger, which the tests shorten.
+func fakeDial(t *testing.T, delays map[string]time.Duration) {
+	t.Helper()
+	orig, stagger := dial, Stagger
+	t.Cleanup(func() { dial, Stagger = orig, stagger })
+	dial = func(ctx context.Context, addr string) (net.Conn, error) {
+		d, ok := delays[addr]
+		if !ok || d < 0 {
+			return nil, errors.New("connection refused")
+		}
+		select {
+		case <-time.After(d):
+			c1, c2 := net.Pipe()
+			c2.Close()
+			return c1, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+var home = profile.Profile{
+	Name: "home",
+	Host: "home.example.net",
+	Addresses: []profile.Address{
+		{Host: "192.168.1.10", Subnet: "192.168.1.0/24"},
+		{Host: "home.tail-xyz.ts.net", Tailscale: true},
+	},
+}
+
+func TestMatches(t *testing.T) {
+	lan := netenv.Network{Subnet: "192.168.1.0/24"}
+	office := netenv.Network{Subnet: "10.20.0.0/16", Tailscale: true}
+	cases := []struct {
+		a    profile.Address
+		n    netenv.Network
+		want bool
+	}{
+		{home.Addresses[0], lan, true},
+		{home.Addresses[0], office, false},
+		{home.Addresses[1], lan, false},
+		{home.Addresses[1], office, true},
+		{profile.Address{Host: "x", Subnet: "10.0.0.0/8"}, office, true},
+		{profile.Address{Host: "x", Subnet: "10.0.0.0/8"}, netenv.Network{}, false},
+		{profile.Address{Host: "x"}, netenv.Network{}, true},
+	}
+	for _, c := range cases {
+		if got := Matches(c.a, c.n); got != c.want {
+			t.Errorf("Matches(%s, %s) = %v, want %v", c.a, c.n, got, c.want)
+		}
+	}
+}
+
+func TestPick_firstToAnswer(t *testing.T) {
+	fakeDial(t, map[string]time.Duration{
+		"192.168.1.10:22":         500 * time.Millisecond, // slow LAN
+		"home.tail-xyz.ts.net:22": 10 * time.Millisecond,
+		"home.example.net:22":     10 * time.Millisecond,
+	})
+	Stagger = 20 * time.Millisecond
+	c, err := Pick(home, netenv.Network{Subnet: "192.168.1.0/24", Tailscale: true}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Reachable || c.Index != 1 || c.Of != 3 {
+		t.Errorf("Pick = %+v, want the Tailscale address", c)
+	}
+}
+
+func TestPick_skipsFailuresAndConditions(t *testing.T) {
+	fakeDial(t, map[string]time.Duration{
+		"192.168.1.10:22":         -1,
+		"home.tail-xyz.ts.net:22": 0, // would win, but Tailscale is off
+		"home.example.net:22":     0,
+	})
+	Stagger = time.Second // a refused candidate hands over at once
+	start := time.Now()
+	c, err := Pick(home, netenv.Network{Subnet: "192.168.1.0/24"}, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Reachable || c.Address.Host != "home.example.net" {
+		t.Errorf("Pick = %+v, want the fallback host", c)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("Pick waited for the stagger after a refusal")
+	}
+}
+
+func TestPick_noneAnswers(t *testing.T) {
+	fakeDial(t, nil)
+	Stagger = 10 * time.Millisecond
+	c, err := Pick(home, netenv.Network{Subnet: "192.168.1.0/24"}, 100*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Reachable || c.Index != 0 {
+		t.Errorf("Pick = %+v, want the first eligible address, unreachable", c)
+	}
+}
+
+func TestPick_jumpChainDoesNotDial(t *testing.T) {
+	fakeDial(t, nil)
+	dial = func(context.Context, string) (net.Conn, error) {
+		t.Error("dialled behind a jump chain")
+		return nil, errors.New("no")
+	}
+	p := home
+	p.Jump = []string{"bastion"}
+	c, err := Pick(p, netenv.Network{Tailscale: true}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Address.Host != "home.tail-xyz.ts.net" {
+		t.Errorf("Pick = %+v, want the first eligible address", c)
+	}
+}
+
+func TestApply(t *testing.T) {
+	p := profile.Profile{Name: "plain", Host: "h", Port: 2222}
+	got, c, err := Apply(p)
+	if err != nil || got.Host != "h" || got.Port != 2222 || c.Multiple() {
+		t.Errorf("Apply(single) = %+v, %+v, %v", got, c, err)
+	}
+
+	fakeDial(t, map[string]time.Duration{"10.0.0.5:2200": 0})
+	p.Addresses = []profile.Address{{Host: "10.0.0.5", Port: 2200}}
+	got, c, err = Apply(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Host != "10.0.0.5" || got.Port != 2200 || got.Addresses != nil || !c.Multiple() {
+		t.Errorf("Apply = %+v, %+v", got, c)
+	}
+}
+
+func TestApply_transportWithAddresses(t *testing.T) {
+	fakeDial(t, map[string]time.Duration{"10.0.0.5:22": 0})
+	p := profile.Profile{Name: "web", Host: "i-0abc",
+		Addresses: []profile.Address{{Host: "10.0.0.5"}},
+		Transport: profile.Transport{Type: profile.TransportSSM, Instance: "i-0abc"}}
+	if got, _, err := Apply(p); err == nil || !strings.Contains(err.Error(), "addresses") {
+		t.Errorf("Apply = %+v, %v; want the transport refused", got, err)
+	}
+}
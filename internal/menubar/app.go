@@ -6,13 +6,18 @@ package menubar
 import (
 	"fmt"
 	"os"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"fyne.io/systray"
 
 	"github.com/ainsuotain/sshtie/internal/checker"
+	"github.com/ainsuotain/sshtie/internal/connector"
 	"github.com/ainsuotain/sshtie/internal/profile"
 	"github.com/ainsuotain/sshtie/internal/session"
+	"github.com/ainsuotain/sshtie/internal/tmux"
 )
 
 // intervalPresets cycles: 10s → 30s → 60s → 10s …
@@ -47,18 +52,23 @@ func onReady() {
 	// Load profiles and run the first check immediately.
 	profiles, _ := profile.Load()
 	buildMenu(profiles, chk, trigger)
-	go chk.CheckAll(profiles, trigger)
+	go checkServers(chk, profiles, trigger)
 	go chk.RefreshSessions(trigger)
 
-	// Background loop: TCP check every 60 s, sessions every 5 s.
+	// Background loop: TCP check every 60 s, remote tmux sessions every
+	// remoteEvery, local sessions every 5 s.
 	go func() {
-		tcpTicker  := time.NewTicker(60 * time.Second)
-		sessTicker := time.NewTicker(5 * time.Second)
+		tcpTicker    := time.NewTicker(60 * time.Second)
+		remoteTicker := time.NewTicker(remoteEvery)
+		sessTicker   := time.NewTicker(5 * time.Second)
 		for {
 			select {
 			case <-tcpTicker.C:
 				profiles, _ = profile.Load()
 				go chk.CheckAll(profiles, trigger)
+			case <-remoteTicker.C:
+				profiles, _ = profile.Load()
+				go checkServers(chk, profiles, trigger)
 			case <-sessTicker.C:
 				go chk.RefreshSessions(trigger)
 			case <-rebuildCh:
@@ -69,6 +79,18 @@ func onReady() {
 	}()
 }
 
+// remoteEvery is how often the tray lists the tmux sessions on every
+// server. Each listing is an SSH login, so it runs far less often than the
+// TCP check; Refresh Status lists them at once.
+const remoteEvery = 15 * time.Minute
+
+// checkServers checks reachability, then lists the tmux sessions on the
+// servers that answered.
+func checkServers(chk *checker.Checker, profiles []profile.Profile, trigger func()) {
+	chk.CheckAll(profiles, trigger)
+	chk.RefreshRemote(profiles, trigger)
+}
+
 // ── menu builder ───────────────────────────────────────────────────────────────
 
 func buildMenu(profiles []profile.Profile, chk *checker.Checker, trigger func()) {
@@ -108,6 +130,15 @@ func buildMenu(profiles []profile.Profile, chk *checker.Checker, trigger func())
 		}
 	}
 
+	// ── background tunnels ──
+	if tunnels := chk.ActiveTunnels(); len(tunnels) > 0 {
+		sort.Slice(tunnels, func(i, j int) bool { return tunnels[i].Profile < tunnels[j].Profile })
+		systray.AddSeparator()
+		for _, t := range tunnels {
+			addTunnelMenu(t, trigger, stop)
+		}
+	}
+
 	systray.AddSeparator()
 
 	// ── global actions ──
@@ -140,7 +171,7 @@ func buildMenu(profiles []profile.Profile, chk *checker.Checker, trigger func())
 					return
 				}
 				ps, _ := profile.Load()
-				go chk.CheckAll(ps, trigger)
+				go checkServers(chk, ps, trigger)
 				go chk.RefreshSessions(trigger)
 			case _, ok := <-loginItem.ClickedCh:
 				if !ok {
@@ -170,6 +201,12 @@ func addProfileMenu(p profile.Profile, chk *checker.Checker, trigger func(), sto
 
 	label   := menuLabel(p.Name, reachable, known, isActive)
 	tooltip := fmt.Sprintf("%s@%s · port %d", p.User, p.Host, portOf(p))
+	if addr := chk.Address(p.Name); addr != "" {
+		tooltip += " · using " + addr
+	}
+	if via := chk.Via(p.Name); via != "" {
+		tooltip += " · via " + via
+	}
 	if isActive {
 		tooltip += fmt.Sprintf(" · connected via %s", activeSess.Method)
 	}
@@ -178,6 +215,18 @@ func addProfileMenu(p profile.Profile, chk *checker.Checker, trigger func(), sto
 
 	// ── sub-items ──
 	connectItem  := item.AddSubMenuItem("Connect", "Open a terminal and connect")
+	var wakeCh <-chan struct{}
+	if p.Wake.Enabled() && known && !reachable {
+		wakeTitle := wakeLabel(p.Name)
+		wakeItem := item.AddSubMenuItem(wakeTitle, "Send a Wake-on-LAN packet")
+		if wakeTitle == "Waking…" {
+			wakeItem.Disable()
+		}
+		wakeCh = wakeItem.ClickedCh
+	} else {
+		wakeCh = make(chan struct{}) // never fires
+	}
+	addRemoteSessionsMenu(item, p, chk.RemoteSessions(p.Name), stop)
 	sep1         := item.AddSubMenuItem("──────────", "")
 	sep1.Disable()
 	intervalItem := item.AddSubMenuItem(intervalLabel(p), "Cycle: 10s → 30s → 60s")
@@ -211,6 +260,11 @@ func addProfileMenu(p profile.Profile, chk *checker.Checker, trigger func(), sto
 					return
 				}
 				OpenConnect(pCopy.Name)
+			case _, ok := <-wakeCh:
+				if !ok {
+					return
+				}
+				go wakeProfile(pCopy, chk, trigger)
 			case _, ok := <-intervalItem.ClickedCh:
 				if !ok {
 					return
@@ -253,8 +307,133 @@ func addProfileMenu(p profile.Profile, chk *checker.Checker, trigger func(), sto
 	}()
 }
 
+// addRemoteSessionsMenu adds the Sessions sub-menu: one item per tmux
+// session running on the server (click to attach) and a manager.
+func addRemoteSessionsMenu(item *systray.MenuItem, p profile.Profile, remote []tmux.Session, stop chan struct{}) {
+	sub := item.AddSubMenuItem(sessionsLabel(remote), "tmux sessions on the server")
+	for _, s := range remote {
+		si := sub.AddSubMenuItem(remoteSessionLabel(p, s), "Open a terminal attached to this session")
+		go func(name string) {
+			for {
+				select {
+				case <-stop:
+					return
+				case _, ok := <-si.ClickedCh:
+					if !ok {
+						return
+					}
+					OpenConnectSession(p.Name, name)
+				}
+			}
+		}(s.Name)
+	}
+	manage := sub.AddSubMenuItem("Manage Sessions…", "List, create, rename or kill tmux sessions")
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case _, ok := <-manage.ClickedCh:
+				if !ok {
+					return
+				}
+				OpenSessions(p.Name)
+			}
+		}
+	}()
+}
+
+// addTunnelMenu creates one item for a running background tunnel.
+func addTunnelMenu(t session.Session, trigger func(), stop chan struct{}) {
+	item := systray.AddMenuItem(tunnelLabel(t), strings.Join(t.Forwards, " · "))
+	stopItem := item.AddSubMenuItem("Stop Tunnel", "Close this tunnel's port forwards")
+
+	tCopy := t
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case _, ok := <-stopItem.ClickedCh:
+				if !ok {
+					return
+				}
+				_ = session.Stop(tCopy.PID)
+				_ = session.DeleteTunnel(tCopy.Profile)
+				trigger()
+			}
+		}
+	}()
+}
+
 // ── helpers ───────────────────────────────────────────────────────────────────
 
+// wakeStatus holds the tray's Wake item title per profile while a wake is
+// in progress or after one failed. The menu is rebuilt from it, since items
+// of a replaced menu must not be touched.
+var (
+	wakeMu     sync.Mutex
+	wakeStatus = map[string]string{}
+)
+
+func wakeLabel(name string) string {
+	wakeMu.Lock()
+	defer wakeMu.Unlock()
+	if s := wakeStatus[name]; s != "" {
+		return s
+	}
+	return "Wake"
+}
+
+func setWakeStatus(name, status string, trigger func()) {
+	wakeMu.Lock()
+	wakeStatus[name] = status
+	wakeMu.Unlock()
+	trigger()
+}
+
+// wakeProfile sends p's Wake-on-LAN packet and re-checks it while it boots,
+// so it turns 🟢 without waiting for the next 60 s poll.
+func wakeProfile(p profile.Profile, chk *checker.Checker, trigger func()) {
+	setWakeStatus(p.Name, "Waking…", trigger)
+	if err := connector.SendWake(p); err != nil {
+		setWakeStatus(p.Name, "Wake failed — try sshtie wake "+p.Name, trigger)
+		return
+	}
+	deadline := time.Now().Add(p.Wake.WaitFor())
+	for time.Now().Before(deadline) {
+		time.Sleep(10 * time.Second)
+		profiles, _ := profile.Load()
+		chk.CheckAll(profiles, nil)
+		if reachable, _ := chk.Get(p.Name); reachable {
+			setWakeStatus(p.Name, "", trigger)
+			return
+		}
+	}
+	setWakeStatus(p.Name, "Wake (no answer yet)", trigger)
+}
+
+func sessionsLabel(remote []tmux.Session) string {
+	if len(remote) == 0 {
+		return "Sessions"
+	}
+	return fmt.Sprintf("Sessions (%d)", len(remote))
+}
+
+// remoteSessionLabel shows a tmux session in the menu; the profile's own
+// session is starred.
+func remoteSessionLabel(p profile.Profile, s tmux.Session) string {
+	own := p.TmuxSession
+	if own == "" {
+		own = "main"
+	}
+	mark := "   "
+	if s.Name == own {
+		mark = "★ "
+	}
+	return mark + s.Name + " — " + s.Status()
+}
+
 func intervalLabel(p profile.Profile) string {
 	v := p.ServerAliveInterval
 	if v <= 0 {
@@ -283,17 +462,15 @@ func nextInterval(current int) int {
 
 // updateProfile loads profiles.yaml, applies fn to the named profile, and saves.
 func updateProfile(name string, fn func(*profile.Profile)) {
-	profiles, err := profile.Load()
-	if err != nil {
-		return
-	}
-	for i := range profiles {
-		if profiles[i].Name == name {
-			fn(&profiles[i])
-			break
+	_ = profile.Update(func(profiles *[]profile.Profile) error {
+		for i := range *profiles {
+			if (*profiles)[i].Name == name {
+				fn(&(*profiles)[i])
+				break
+			}
 		}
-	}
-	_ = profile.Save(profiles)
+		return nil
+	})
 }
 
 // killSession terminates the process recorded in the session file.
@@ -317,6 +494,10 @@ func menuLabel(name string, reachable, known, active bool) string {
 	return prefix + name
 }
 
+func tunnelLabel(t session.Session) string {
+	return "⇄  " + t.Profile + " [tunnel]"
+}
+
 func statusDot(reachable, known bool) string {
 	switch {
 	case !known:
@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/session"
+	"github.com/ainsuotain/sshtie/internal/tmux"
 )
 
 func TestNextInterval(t *testing.T) {
@@ -78,3 +80,20 @@ func TestPortOf(t *testing.T) {
 		t.Error("portOf custom")
 	}
 }
+
+func TestTunnelLabel(t *testing.T) {
+	s := session.Session{Profile: "db", Method: session.MethodTunnel}
+	if got := tunnelLabel(s); got != "⇄  db [tunnel]" {
+		t.Errorf("tunnelLabel: got %q", got)
+	}
+}
+
+func TestRemoteSessionLabel(t *testing.T) {
+	s := tmux.Session{Name: "main", Windows: 2}
+	if got := remoteSessionLabel(profile.Profile{}, s); got != "★ main — 2 windows" {
+		t.Errorf("own session: got %q", got)
+	}
+	if got := remoteSessionLabel(profile.Profile{TmuxSession: "work"}, s); got != "   main — 2 windows" {
+		t.Errorf("other session: got %q", got)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+
+	"github.com/ainsuotain/sshtie/internal/shell"
 )
 
 // OpenConnect opens a terminal window and runs "sshtie connect <name>".
@@ -15,6 +17,17 @@ func OpenConnect(profileName string) {
 	_ = openTerminal(fmt.Sprintf("%s connect %s", resolveBin(), profileName))
 }
 
+// OpenConnectSession opens a terminal window and runs
+// "sshtie connect <name> --session <session>".
+func OpenConnectSession(profileName, session string) {
+	_ = openTerminal(fmt.Sprintf("%s connect %s --session %s", resolveBin(), profileName, shell.Quote(session)))
+}
+
+// OpenSessions opens a terminal window and runs "sshtie sessions <name>".
+func OpenSessions(profileName string) {
+	_ = openTerminal(fmt.Sprintf("%s sessions %s", resolveBin(), profileName))
+}
+
 // OpenAdd opens a terminal window and runs "sshtie add".
 func OpenAdd() {
 	_ = openTerminal(fmt.Sprintf("%s add", resolveBin()))
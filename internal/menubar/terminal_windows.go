@@ -10,6 +10,8 @@ import (
 	"syscall"
 
 	"golang.org/x/sys/windows"
+
+	"github.com/ainsuotain/sshtie/internal/shell"
 )
 
 // OpenConnect opens a terminal and runs "sshtie connect <name>".
@@ -25,13 +27,21 @@ func OpenConnect(profileName string) {
 	_ = openWindowsConnect(profileName)
 }
 
+// OpenConnectSession is OpenConnect attached to the given tmux session.
+func OpenConnectSession(profileName, session string) {
+	if openWSL("connect " + profileName + " --session " + shell.Quote(session)) {
+		return
+	}
+	_ = openWindowsConnect(profileName, "--session", session)
+}
+
 // openWindowsConnect spawns sshtie.exe directly in a new console window.
 // Compared with the CMD-wrapper approach this:
 //   - eliminates the blank black window flash at startup
 //   - enables the window-hide-on-close behaviour via SSHTIE_KEEP_WINDOW
-func openWindowsConnect(profileName string) error {
+func openWindowsConnect(profileName string, extra ...string) error {
 	bin := resolveBin()
-	cmd := exec.Command(bin, "connect", profileName)
+	cmd := exec.Command(bin, append([]string{"connect", profileName}, extra...)...)
 	cmd.Env = append(os.Environ(), "SSHTIE_KEEP_WINDOW=1")
 	cmd.SysProcAttr = &syscall.SysProcAttr{
 		CreationFlags: windows.CREATE_NEW_CONSOLE,
@@ -47,6 +57,14 @@ func OpenAdd() {
 	_ = openWindowsTerminal(fmt.Sprintf("%s add", resolveBin()))
 }
 
+// OpenSessions opens a terminal and runs "sshtie sessions <name>".
+func OpenSessions(profileName string) {
+	if openWSL("sessions " + profileName) {
+		return
+	}
+	_ = openWindowsTerminal(fmt.Sprintf("%s sessions %s", resolveBin(), profileName))
+}
+
 // OpenEdit opens a terminal and runs "sshtie edit <name>".
 func OpenEdit(profileName string) {
 	if openWSL("edit " + profileName) {
@@ -0,0 +1,80 @@
+package netenv
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// defaultGateway returns the IPv4 default gateway, or "" when it can't be
+// determined.
+func defaultGateway() string {
+	switch runtime.GOOS {
+	case "linux":
+		f, err := os.Open("/proc/net/route")
+		if err != nil {
+			return ""
+		}
+		defer f.Close()
+		return parseProcRoute(bufio.NewScanner(f))
+	case "darwin", "freebsd", "openbsd", "netbsd":
+		out, err := exec.Command("route", "-n", "get", "default").Output()
+		if err != nil {
+			return ""
+		}
+		return parseRouteGet(string(out))
+	case "windows":
+		out, err := exec.Command("route", "print", "-4", "0.0.0.0").Output()
+		if err != nil {
+			return ""
+		}
+		return parseRoutePrint(string(out))
+	}
+	return ""
+}
+
+// parseProcRoute reads Linux's /proc/net/route: the default route has
+// destination 00000000 and a little-endian hex gateway.
+func parseProcRoute(sc *bufio.Scanner) string {
+	for sc.Scan() {
+		f := strings.Fields(sc.Text())
+		if len(f) < 3 || f[1] != "00000000" {
+			continue
+		}
+		b, err := hex.DecodeString(f[2])
+		if err != nil || len(b) != 4 {
+			continue
+		}
+		ip := make(net.IP, 4)
+		binary.LittleEndian.PutUint32(ip, binary.BigEndian.Uint32(b))
+		return ip.String()
+	}
+	return ""
+}
+
+// parseRouteGet reads the "gateway:" line of BSD `route -n get default`.
+func parseRouteGet(out string) string {
+	for _, line := range strings.Split(out, "\n") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(line), ":"); ok && k == "gateway" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// parseRoutePrint reads the first 0.0.0.0/0.0.0.0 row of Windows
+// `route print`.
+func parseRoutePrint(out string) string {
+	for _, line := range strings.Split(out, "\n") {
+		f := strings.Fields(line)
+		if len(f) >= 3 && f[0] == "0.0.0.0" && f[1] == "0.0.0.0" && net.ParseIP(f[2]) != nil {
+			return f[2]
+		}
+	}
+	return ""
+}
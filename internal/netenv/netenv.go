@@ -0,0 +1,78 @@
+// Package netenv identifies the local network this machine is on, so
+// results that depend on it (whether mosh's UDP gets through, for one) can
+// be remembered per network.
+package netenv
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"strings"
+
+	"github.com/ainsuotain/sshtie/internal/tailscale"
+)
+
+// Network describes the local network.
+type Network struct {
+	Gateway   string // default gateway; "" when it can't be read
+	Subnet    string // subnet of the interface that routes to the internet; "" = offline
+	Tailscale bool   // the local Tailscale client is running
+}
+
+// Current inspects the local network.
+func Current() Network {
+	return Network{
+		Gateway:   defaultGateway(),
+		Subnet:    outboundSubnet(),
+		Tailscale: tailscale.ClientRunning(),
+	}
+}
+
+// ID is a short stable fingerprint of n, suitable as a cache key.
+func (n Network) ID() string {
+	sum := sha256.Sum256([]byte(n.String()))
+	return hex.EncodeToString(sum[:6])
+}
+
+// String describes n, e.g. "192.168.1.0/24 via 192.168.1.1 · tailscale".
+func (n Network) String() string {
+	parts := []string{n.Subnet}
+	if n.Subnet == "" {
+		parts[0] = "offline"
+	} else if n.Gateway != "" {
+		parts[0] += " via " + n.Gateway
+	}
+	if n.Tailscale {
+		parts = append(parts, "tailscale")
+	}
+	return strings.Join(parts, " · ")
+}
+
+// outboundSubnet finds the address the OS would use to reach the internet
+// and returns its interface's subnet in CIDR form. Dialing UDP sends
+// nothing; it only picks a route.
+func outboundSubnet() string {
+	conn, err := net.Dial("udp", "192.0.2.1:9") // TEST-NET-1, never contacted
+	if err != nil {
+		return ""
+	}
+	local := conn.LocalAddr().(*net.UDPAddr).IP
+	conn.Close()
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+	for _, ifc := range ifaces {
+		addrs, err := ifc.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if ipn, ok := a.(*net.IPNet); ok && ipn.IP.Equal(local) {
+				return (&net.IPNet{IP: ipn.IP.Mask(ipn.Mask), Mask: ipn.Mask}).String()
+			}
+		}
+	}
+	return local.String() + "/32"
+}
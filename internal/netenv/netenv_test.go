@@ -0,0 +1,42 @@
+package netenv
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestParseGateway(t *testing.T) {
+	proc := "Iface\tDestination\tGateway \tFlags\n" +
+		"eth0\t0002A8C0\t00000000\t0001\n" +
+		"eth0\t00000000\t0102A8C0\t0003\n"
+	if got := parseProcRoute(bufio.NewScanner(strings.NewReader(proc))); got != "192.168.2.1" {
+		t.Errorf("parseProcRoute = %q", got)
+	}
+
+	bsd := "   route to: default\ndestination: default\n       mask: default\n    gateway: 10.0.0.1\n  interface: en0\n"
+	if got := parseRouteGet(bsd); got != "10.0.0.1" {
+		t.Errorf("parseRouteGet = %q", got)
+	}
+
+	win := "Network Destination        Netmask          Gateway       Interface  Metric\n" +
+		"          0.0.0.0          0.0.0.0      172.16.0.1    172.16.0.23     25\n"
+	if got := parseRoutePrint(win); got != "172.16.0.1" {
+		t.Errorf("parseRoutePrint = %q", got)
+	}
+}
+
+func TestNetworkID(t *testing.T) {
+	a := Network{Gateway: "192.168.1.1", Subnet: "192.168.1.0/24"}
+	b := a
+	b.Tailscale = true
+	if a.ID() == b.ID() {
+		t.Error("Tailscale state should change the fingerprint")
+	}
+	if a.ID() != (Network{Gateway: "192.168.1.1", Subnet: "192.168.1.0/24"}).ID() {
+		t.Error("fingerprint is not stable")
+	}
+	if got := b.String(); got != "192.168.1.0/24 via 192.168.1.1 · tailscale" {
+		t.Errorf("String() = %q", got)
+	}
+}
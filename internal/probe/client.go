@@ -0,0 +1,396 @@
+package probe
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/ainsuotain/sshtie/internal/cloud"
+	"github.com/ainsuotain/sshtie/internal/hostkey"
+	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/shell"
+)
+
+// client is an authenticated connection to the target, plus the jump
+// connections it runs over.
+type client struct {
+	*ssh.Client
+	hops []*ssh.Client
+	auth string
+}
+
+func (c *client) Close() error {
+	err := c.Client.Close()
+	for i := len(c.hops) - 1; i >= 0; i-- {
+		c.hops[i].Close()
+	}
+	return err
+}
+
+// run executes cmd under sh -c and returns its stdout.
+func (c *client) run(cmd string) ([]byte, error) {
+	s, err := c.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	var out bytes.Buffer
+	s.Stdout = &out
+	err = s.Run("sh -c " + shell.Quote(cmd))
+	return out.Bytes(), err
+}
+
+// dial connects to p through its jump chain and logs in to every hop with
+// the same credentials: the SSH agent, then the profile's key (and the keys
+// of jump hosts that are themselves profiles), then a password if allowed.
+// p's host key must match its pins, when it has some (see package hostkey).
+func dial(p profile.Profile, opts Options) (*client, error) {
+	r, err := route(p, opts, true)
+	if err != nil {
+		return nil, err
+	}
+	defer r.closeAgent()
+
+	cfg := r.config(p.User, r.addr)
+	if opts.Identity != "" {
+		s, err := loadKey(opts.Identity)
+		if err != nil {
+			r.c.closeHops()
+			return nil, err
+		}
+		cfg.Auth = []ssh.AuthMethod{ssh.PublicKeys(track(s, "key "+keyLabel(opts.Identity), &r.used))}
+	}
+	cfg.HostKeyCallback = hostkey.Callback(p, cfg.HostKeyCallback)
+	if keys := hostkey.TrustedKeys(p); len(keys) > 0 {
+		cfg.HostKeyAlgorithms = hostkey.Algorithms(keys)
+	} else if hostkey.Pinned(p) {
+		cfg.HostKeyAlgorithms = nil
+	}
+	sc, err := r.handshake(r.addr, cfg)
+	if err != nil {
+		r.c.closeHops()
+		return nil, err
+	}
+	r.c.Client = sc
+	r.c.auth = r.used
+	return r.c, nil
+}
+
+// path is a logged-in jump chain leading to a profile's address, ready to
+// open connections to the target.
+type path struct {
+	c          *client // hops only until the target is reached
+	addr       string  // target host:port
+	deadline   time.Time
+	tunnel     func() (net.Conn, error) // cloud transport to the target; nil = TCP
+	used       string                   // login the last handshake used
+	config     func(userName, addr string) *ssh.ClientConfig
+	closeAgent func()
+}
+
+// route logs in to p's jump hosts, or sets up its cloud transport. auth
+// says whether p itself will be logged in to as well; without it,
+// credentials are only needed for hops.
+func route(p profile.Profile, opts Options, auth bool) (*path, error) {
+	hops, err := profile.JumpChain(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := profile.ValidateTransport(p); err != nil {
+		return nil, err
+	}
+	r := &path{c: &client{}, deadline: time.Now().Add(opts.Timeout)}
+	if p.Transport.Enabled() {
+		t, port := p.Transport, p.Port
+		r.tunnel = func() (net.Conn, error) { return cloud.Dial(t, port) }
+	}
+
+	signers, closeAgent := loadSigners(p, hops, &r.used)
+	r.closeAgent = closeAgent
+	needed := len(hops) > 0 || (auth && opts.Identity == "")
+	if len(signers) == 0 && opts.Password == nil && needed {
+		closeAgent()
+		return nil, fmt.Errorf("%w (%s)", ErrNoCredentials, p.DefaultKey())
+	}
+	hostKeys, err := hostKeyChecker()
+	if err != nil {
+		closeAgent()
+		return nil, err
+	}
+
+	r.config = func(userName, addr string) *ssh.ClientConfig {
+		cfg := &ssh.ClientConfig{
+			User:              userName,
+			HostKeyCallback:   hostKeys,
+			HostKeyAlgorithms: knownAlgorithms(addr),
+			Timeout:           time.Until(r.deadline),
+		}
+		if len(signers) > 0 {
+			cfg.Auth = append(cfg.Auth, ssh.PublicKeys(signers...))
+		}
+		if opts.Password != nil {
+			prompt := fmt.Sprintf("%s@%s's password: ", userName, addr)
+			cfg.Auth = append(cfg.Auth,
+				ssh.PasswordCallback(func() (string, error) {
+					r.used = "password"
+					return opts.Password(prompt)
+				}),
+				ssh.KeyboardInteractive(func(_, _ string, questions []string, echos []bool) ([]string, error) {
+					r.used = "password"
+					answers := make([]string, len(questions))
+					for i, q := range questions {
+						a, err := opts.Password(q)
+						if err != nil {
+							return nil, err
+						}
+						answers[i] = a
+					}
+					return answers, nil
+				}))
+		}
+		return cfg
+	}
+
+	localUser := ""
+	if u, err := user.Current(); err == nil {
+		localUser = u.Username
+		if i := strings.LastIndex(localUser, `\`); i >= 0 {
+			localUser = localUser[i+1:] // Windows DOMAIN\user
+		}
+	}
+
+	for _, h := range hops {
+		userName := h.User
+		if userName == "" {
+			userName = localUser
+		}
+		addr := hostPort(h.Host, h.Port)
+		sc, err := r.handshake(addr, r.config(userName, addr))
+		if err != nil {
+			r.c.closeHops()
+			closeAgent()
+			return nil, err
+		}
+		r.c.hops = append(r.c.hops, sc)
+	}
+	r.addr = hostPort(p.Host, p.Port)
+	return r, nil
+}
+
+// handshake opens a connection to addr — directly, through the last hop
+// reached so far, or through the cloud transport — and runs the SSH
+// handshake and login with cfg.
+func (r *path) handshake(addr string, cfg *ssh.ClientConfig) (*ssh.Client, error) {
+	var conn net.Conn
+	var err error
+	switch {
+	case len(r.c.hops) > 0:
+		conn, err = r.c.hops[len(r.c.hops)-1].Dial("tcp", addr)
+	case r.tunnel != nil:
+		conn, err = r.tunnel()
+	default:
+		conn, err = net.DialTimeout("tcp", addr, time.Until(r.deadline))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", addr, err)
+	}
+	// Past the first hop conn is an SSH channel, which has no deadlines:
+	// closing it is the only way to stop a handshake that hangs there.
+	conn.SetDeadline(r.deadline)
+	timer := time.AfterFunc(time.Until(r.deadline), func() { conn.Close() })
+	r.used = ""
+	sc, chans, reqs, err := ssh.NewClientConn(conn, addr, cfg)
+	if !timer.Stop() {
+		if err == nil {
+			sc.Close()
+		}
+		return nil, fmt.Errorf("ssh %s@%s: %w", cfg.User, addr, os.ErrDeadlineExceeded)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ssh %s@%s: %w", cfg.User, addr, err)
+	}
+	conn.SetDeadline(time.Time{})
+	return ssh.NewClient(sc, chans, reqs), nil
+}
+
+func hostPort(host string, port int) string {
+	if port == 0 {
+		port = 22
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+func (c *client) closeHops() {
+	for i := len(c.hops) - 1; i >= 0; i-- {
+		c.hops[i].Close()
+	}
+}
+
+// loadSigners collects agent keys and the profile's key files. Every signer
+// records its label in *used when it signs, so the caller learns which one
+// the server accepted. Passphrase-protected keys are left to the agent.
+func loadSigners(p profile.Profile, hops []profile.Hop, used *string) ([]ssh.Signer, func()) {
+	var signers []ssh.Signer
+	closer := func() {}
+
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			closer = func() { conn.Close() }
+			if ss, err := agent.NewClient(conn).Signers(); err == nil {
+				for _, s := range ss {
+					signers = append(signers, track(s, "agent", used))
+				}
+			}
+		}
+	}
+
+	paths := []string{p.DefaultKey()}
+	if p.Key == "" {
+		home, _ := os.UserHomeDir()
+		paths = append(paths, filepath.Join(home, ".ssh", "id_ecdsa"), filepath.Join(home, ".ssh", "id_rsa"))
+	}
+	for _, h := range hops {
+		if h.Key != "" {
+			paths = append(paths, h.Key)
+		}
+	}
+	seen := map[string]bool{}
+	for _, path := range paths {
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		s, err := loadKey(path)
+		if err != nil {
+			continue
+		}
+		signers = append(signers, track(s, "key "+keyLabel(path), used))
+	}
+	return signers, closer
+}
+
+// loadKey reads an unencrypted private key file.
+func loadKey(path string) (ssh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// keyLabel shortens a key path under the home directory to ~/….
+func keyLabel(path string) string {
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(path, home+string(filepath.Separator)) {
+		return "~" + path[len(home):]
+	}
+	return path
+}
+
+// trackedSigner records label in *used whenever it signs.
+type trackedSigner struct {
+	ssh.AlgorithmSigner
+	label string
+	used  *string
+}
+
+func (s trackedSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	*s.used = s.label
+	return s.AlgorithmSigner.Sign(rand, data)
+}
+
+func (s trackedSigner) SignWithAlgorithm(rand io.Reader, data []byte, algorithm string) (*ssh.Signature, error) {
+	*s.used = s.label
+	return s.AlgorithmSigner.SignWithAlgorithm(rand, data, algorithm)
+}
+
+type trackedPlainSigner struct {
+	ssh.Signer
+	label string
+	used  *string
+}
+
+func (s trackedPlainSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	*s.used = s.label
+	return s.Signer.Sign(rand, data)
+}
+
+func track(s ssh.Signer, label string, used *string) ssh.Signer {
+	if as, ok := s.(ssh.AlgorithmSigner); ok {
+		return trackedSigner{as, label, used}
+	}
+	return trackedPlainSigner{s, label, used}
+}
+
+func knownHostsPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ssh", "known_hosts")
+}
+
+// hostKeyChecker verifies against ~/.ssh/known_hosts like ssh's
+// StrictHostKeyChecking=accept-new: unknown hosts are accepted (but not
+// written), a changed key is an error. Only a missing known_hosts means no
+// checks: one that can't be read or parsed is an error, not a free pass.
+func hostKeyChecker() (ssh.HostKeyCallback, error) {
+	path := knownHostsPath()
+	check, err := knownhosts.New(path)
+	if os.IsNotExist(err) {
+		return ssh.InsecureIgnoreHostKey(), nil // no known_hosts yet
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := check(hostname, remote, key)
+		var ke *knownhosts.KeyError
+		if errors.As(err, &ke) {
+			if len(ke.Want) == 0 {
+				return nil // first contact
+			}
+			return fmt.Errorf("host key for %s has changed (known_hosts line %d) — refusing to connect",
+				hostname, ke.Want[0].Line)
+		}
+		return err
+	}, nil
+}
+
+// knownAlgorithms returns the host key algorithms known_hosts has for addr,
+// so the server is asked for a key type we can actually verify. nil lets
+// the library choose.
+func knownAlgorithms(addr string) []string {
+	check, err := knownhosts.New(knownHostsPath())
+	if err != nil {
+		return nil
+	}
+	pub, _, _ := ed25519.GenerateKey(nil)
+	probe, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return nil
+	}
+	var ke *knownhosts.KeyError
+	if !errors.As(check(addr, &net.TCPAddr{}, probe), &ke) {
+		return nil
+	}
+	keys := make([]ssh.PublicKey, len(ke.Want))
+	for i, k := range ke.Want {
+		keys[i] = k.Key
+	}
+	return hostkey.Algorithms(keys)
+}
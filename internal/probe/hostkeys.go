@@ -0,0 +1,98 @@
+package probe
+
+import (
+	"errors"
+	"net"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/ainsuotain/sshtie/internal/hostkey"
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+// errScanned stops a handshake once the host key has been seen.
+var errScanned = errors.New("host key scanned")
+
+// scanFamilies are the host key types HostKeys asks for, one handshake each.
+var scanFamilies = [][]string{
+	{ssh.KeyAlgoED25519},
+	{ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521},
+	{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA},
+}
+
+// HostKey connects to p (through its jump chain) and returns the host key
+// its server presents, preferring the types already pinned for it. p itself
+// is never logged in to; only jump hosts need credentials.
+func HostKey(p profile.Profile, opts Options) (ssh.PublicKey, error) {
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	r, err := route(p, opts, false)
+	if err != nil {
+		return nil, err
+	}
+	defer r.closeAgent()
+	defer r.c.closeHops()
+	return r.scan(p.User, hostkey.Algorithms(hostkey.TrustedKeys(p)))
+}
+
+// HostKeys is HostKey for every key type the server has: ed25519, ECDSA
+// and RSA.
+func HostKeys(p profile.Profile, opts Options) ([]ssh.PublicKey, error) {
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	r, err := route(p, opts, false)
+	if err != nil {
+		return nil, err
+	}
+	defer r.closeAgent()
+	defer r.c.closeHops()
+
+	var keys []ssh.PublicKey
+	var firstErr error
+	for _, algos := range scanFamilies {
+		k, err := r.scan(p.User, algos)
+		var ne *ssh.AlgorithmNegotiationError
+		switch {
+		case err == nil:
+			keys = append(keys, k)
+		case errors.As(err, &ne):
+			// The server has no key of this type.
+		case len(keys) == 0:
+			return nil, err // unreachable, most likely
+		default:
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if len(keys) == 0 {
+		if firstErr == nil {
+			firstErr = errors.New("the server offered no host key sshtie understands")
+		}
+		return nil, firstErr
+	}
+	return keys, nil
+}
+
+// scan runs a handshake with the target that stops as soon as its host key
+// arrives. algos nil lets the library choose.
+func (r *path) scan(userName string, algos []string) (ssh.PublicKey, error) {
+	var got ssh.PublicKey
+	cfg := r.config(userName, r.addr)
+	cfg.Auth = nil
+	cfg.HostKeyAlgorithms = algos
+	cfg.HostKeyCallback = func(_ string, _ net.Addr, key ssh.PublicKey) error {
+		got = key
+		return errScanned
+	}
+	c, err := r.handshake(r.addr, cfg)
+	if err == nil {
+		c.Close() // can't happen: the callback always refuses
+	}
+	if got != nil {
+		return got, nil
+	}
+	return nil, err
+}
@@ -0,0 +1,67 @@
+package probe
+
+import (
+	"crypto/ed25519"
+	"encoding/pem"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/ainsuotain/sshtie/internal/hostkey"
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+func TestHostKeys(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	_, clientPriv, _ := ed25519.GenerateKey(nil)
+	block, _ := ssh.MarshalPrivateKey(clientPriv, "")
+	keyPath := filepath.Join(home, "id_test")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	clientSigner, _ := ssh.NewSignerFromKey(clientPriv)
+
+	addr := serveSSH(t, clientSigner.PublicKey())
+	host, port, _ := net.SplitHostPort(addr)
+	p := profile.Profile{Name: "t", Host: host, User: "me", Key: filepath.Join(home, "missing")}
+	p.Port, _ = strconv.Atoi(port)
+	opts := Options{Timeout: 5 * time.Second}
+
+	// Scanning needs no credentials for the target itself.
+	keys, err := HostKeys(p, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].Type() != ssh.KeyAlgoED25519 {
+		t.Fatalf("HostKeys = %v", keys)
+	}
+	one, err := HostKey(p, opts)
+	if err != nil || hostkey.Fingerprint(one) != hostkey.Fingerprint(keys[0]) {
+		t.Errorf("HostKey = %v, %v", one, err)
+	}
+
+	// Logging in honours the pins.
+	p.Key = keyPath
+	p.HostKeys = hostkey.Fingerprints(keys)
+	if _, err := RunWith(p, opts); err != nil {
+		t.Errorf("RunWith(pinned) = %v", err)
+	}
+	p.HostKeys = []string{"SHA256:somethingelse"}
+	var changed *hostkey.ChangedError
+	if _, err := RunWith(p, opts); !errors.As(err, &changed) {
+		t.Errorf("RunWith(changed key) = %v, want a ChangedError", err)
+	}
+}
@@ -0,0 +1,293 @@
+package probe
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ainsuotain/sshtie/internal/netenv"
+	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/shell"
+)
+
+// Mosh test outcomes.
+var (
+	ErrMoshNotInstalled = errors.New("mosh-server is not installed on the server")
+	ErrUDPBlocked       = errors.New("mosh's UDP packets get no reply")
+)
+
+// MoshUDPTimeout is how long the handshake waits for mosh-server to answer.
+var MoshUDPTimeout = 3 * time.Second
+
+// MoshResult is a successful mosh round trip.
+type MoshResult struct {
+	Port int           // UDP port mosh-server listened on
+	RTT  time.Duration // first datagram sent → first reply decrypted
+}
+
+// Mosh checks end to end that mosh can work: it logs in over SSH, starts a
+// throwaway mosh-server, and exchanges encrypted datagrams with it over UDP
+// exactly like the mosh client's first packets. The server exits on its own
+// a few seconds later.
+//
+// ErrMoshNotInstalled and ErrUDPBlocked are verdicts; any other error means
+// the test itself couldn't run (no key login, SSH unreachable, …).
+func Mosh(p profile.Profile, opts Options) (MoshResult, error) {
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	c, err := dial(p, opts)
+	if err != nil {
+		return MoshResult{}, err
+	}
+	out, err := c.run(moshStartScript(p.MoshServer))
+	c.Close()
+	if err != nil {
+		return MoshResult{}, fmt.Errorf("start mosh-server: %w", err)
+	}
+	if strings.Contains(string(out), "sshtie-no-mosh") {
+		return MoshResult{}, ErrMoshNotInstalled
+	}
+	port, key, err := parseMoshConnect(out)
+	if err != nil {
+		return MoshResult{}, err
+	}
+	rtt, err := moshHandshake(p.Host, port, key, MoshUDPTimeout)
+	if err != nil {
+		return MoshResult{Port: port}, err
+	}
+	return MoshResult{Port: port, RTT: rtt}, nil
+}
+
+// moshStartScript starts mosh-server running `sleep` under a UTF-8 locale
+// (mosh-server refuses to start without one). MOSH_SERVER_NETWORK_TMOUT
+// makes it exit soon after we stop talking to it.
+func moshStartScript(server string) string {
+	find := `m=$(command -v mosh-server 2>/dev/null)
+for d in /opt/homebrew/bin /usr/local/bin /usr/bin /snap/bin; do
+  [ -z "$m" ] && [ -x "$d/mosh-server" ] && m="$d/mosh-server"
+done`
+	if server != "" {
+		find = "m=" + shell.Quote(server)
+	}
+	return find + `
+[ -z "$m" ] && { echo sshtie-no-mosh; exit 0; }
+for l in "$LANG" C.UTF-8 en_US.UTF-8; do
+  case "$l" in *UTF-8*|*utf8*) ;; *) continue ;; esac
+  out=$(LANG=$l LC_ALL=$l MOSH_SERVER_NETWORK_TMOUT=5 "$m" new -s -- sleep 20 2>&1 </dev/null)
+  case "$out" in *"MOSH CONNECT"*) break ;; esac
+done
+echo "$out"`
+}
+
+var moshConnectRE = regexp.MustCompile(`MOSH CONNECT (\d+) ([A-Za-z0-9/+]{22})`)
+
+// parseMoshConnect reads mosh-server's "MOSH CONNECT <port> <key>" line.
+func parseMoshConnect(out []byte) (port int, key []byte, err error) {
+	m := moshConnectRE.FindSubmatch(out)
+	if m == nil {
+		msg := strings.TrimSpace(string(out))
+		if i := strings.IndexByte(msg, '\n'); i >= 0 {
+			msg = msg[:i]
+		}
+		return 0, nil, fmt.Errorf("mosh-server didn't start: %s", msg)
+	}
+	port, _ = strconv.Atoi(string(m[1]))
+	key, err = base64.StdEncoding.DecodeString(string(m[2]) + "==")
+	if err != nil {
+		return 0, nil, fmt.Errorf("mosh-server key: %w", err)
+	}
+	return port, key, nil
+}
+
+// moshHandshake sends mosh datagrams to host:port every 250 ms and waits
+// for one the server encrypted back to us.
+func moshHandshake(host string, port int, key []byte, timeout time.Duration) (time.Duration, error) {
+	o, err := newOCB(key)
+	if err != nil {
+		return 0, err
+	}
+	conn, err := net.Dial("udp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	deadline := start.Add(timeout)
+	buf := make([]byte, 4096)
+	for seq := uint64(0); time.Now().Before(deadline); seq++ {
+		if _, err := conn.Write(moshPacket(o, seq)); err != nil {
+			return 0, fmt.Errorf("%w (%v)", ErrUDPBlocked, err)
+		}
+		wait := time.Now().Add(250 * time.Millisecond)
+		if wait.After(deadline) {
+			wait = deadline
+		}
+		conn.SetReadDeadline(wait)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				var ne net.Error
+				if errors.As(err, &ne) && ne.Timeout() {
+					break // resend
+				}
+				return 0, fmt.Errorf("%w (%v)", ErrUDPBlocked, err) // e.g. ICMP port unreachable
+			}
+			if moshReply(o, buf[:n]) {
+				return time.Since(start), nil
+			}
+		}
+	}
+	return 0, ErrUDPBlocked
+}
+
+// moshPacket builds a client→server datagram: the low 8 bytes of the nonce
+// (direction bit clear, then seq), followed by the OCB-sealed timestamps and
+// one final fragment carrying a zlib-compressed, empty Instruction.
+func moshPacket(o *ocb, seq uint64) []byte {
+	// TransportBuffers.Instruction{protocol_version: 2, old_num: 0,
+	// new_num: 0, ack_num: 0, throwaway_num: 0} — a pure ack.
+	inst := []byte{0x08, 0x02, 0x10, 0x00, 0x18, 0x00, 0x20, 0x00, 0x28, 0x00}
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	zw.Write(inst)
+	zw.Close()
+
+	plain := make([]byte, 4+10, 4+10+z.Len())
+	binary.BigEndian.PutUint16(plain[0:], uint16(time.Now().UnixMilli())) // timestamp
+	binary.BigEndian.PutUint16(plain[2:], 0xffff)                         // no timestamp reply
+	binary.BigEndian.PutUint64(plain[4:], 0)                              // fragment id
+	binary.BigEndian.PutUint16(plain[12:], 0x8000)                        // fragment 0, final
+	plain = append(plain, z.Bytes()...)
+
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[4:], seq)
+	return append(nonce[4:], o.seal(nonce, plain)...)
+}
+
+// moshReply reports whether pkt is a server→client datagram sealed with our
+// key.
+func moshReply(o *ocb, pkt []byte) bool {
+	if len(pkt) < 8+16 || pkt[0]&0x80 == 0 {
+		return false
+	}
+	nonce := make([]byte, 12)
+	copy(nonce[4:], pkt[:8])
+	_, err := o.open(nonce, pkt[8:])
+	return err == nil
+}
+
+// ── Cache ────────────────────────────────────────────────────────────────────
+
+// MoshCacheTTL is how long a mosh verdict for a profile on a network is
+// trusted before the test is run again.
+var MoshCacheTTL = 6 * time.Hour
+
+// MoshVerdict is a remembered mosh test result.
+type MoshVerdict struct {
+	OK      bool          `json:"ok"`
+	Reason  string        `json:"reason,omitempty"` // why it failed
+	Port    int           `json:"port,omitempty"`
+	RTT     time.Duration `json:"rtt,omitempty"`
+	Network string        `json:"network"` // netenv description, for humans
+	Checked time.Time     `json:"checked"`
+}
+
+func moshCachePath() (string, error) {
+	dir, err := profile.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mosh-check.json"), nil
+}
+
+func moshCacheKey(name string, n netenv.Network) string { return name + "@" + n.ID() }
+
+func loadMoshCache() map[string]MoshVerdict {
+	m := map[string]MoshVerdict{}
+	path, err := moshCachePath()
+	if err != nil {
+		return m
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &m)
+	}
+	return m
+}
+
+// CachedMosh returns the verdict for name on n if one is younger than
+// MoshCacheTTL.
+func CachedMosh(name string, n netenv.Network) (MoshVerdict, bool) {
+	v, ok := loadMoshCache()[moshCacheKey(name, n)]
+	if !ok || time.Since(v.Checked) > MoshCacheTTL {
+		return MoshVerdict{}, false
+	}
+	return v, true
+}
+
+// RecordMosh stores v for name on n, dropping expired entries. The tray,
+// tunnels and the CLI all write the cache, so the update runs under a file
+// lock and replaces the file atomically; readers need no lock.
+func RecordMosh(name string, n netenv.Network, v MoshVerdict) error {
+	path, err := moshCachePath()
+	if err != nil {
+		return err
+	}
+	unlock, err := profile.LockFile("mosh-check.json")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	m := loadMoshCache()
+	for k, old := range m {
+		if time.Since(old.Checked) > MoshCacheTTL {
+			delete(m, k)
+		}
+	}
+	if v.Checked.IsZero() {
+		v.Checked = time.Now()
+	}
+	v.Network = n.String()
+	m[moshCacheKey(name, n)] = v
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return profile.WriteFileAtomic(path, data, 0o600)
+}
+
+// CheckMosh returns p's mosh verdict on n: from the cache when useCache is
+// set and a fresh entry exists, otherwise by running Mosh and recording the
+// result. err is set, and nothing is cached, when no verdict could be
+// reached — including ErrMoshNotInstalled, which an install can change at
+// any time.
+func CheckMosh(p profile.Profile, n netenv.Network, useCache bool) (v MoshVerdict, cached bool, err error) {
+	if useCache {
+		if v, ok := CachedMosh(p.Name, n); ok {
+			return v, true, nil
+		}
+	}
+	res, err := Mosh(p, Options{})
+	switch {
+	case err == nil:
+		v = MoshVerdict{OK: true, Port: res.Port, RTT: res.RTT}
+	case errors.Is(err, ErrUDPBlocked):
+		v = MoshVerdict{Reason: err.Error(), Port: res.Port}
+	default:
+		return MoshVerdict{}, false, err
+	}
+	_ = RecordMosh(p.Name, n, v)
+	return v, false, nil
+}
@@ -0,0 +1,150 @@
+package probe
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ainsuotain/sshtie/internal/netenv"
+)
+
+func TestParseMoshConnect(t *testing.T) {
+	out := []byte("\r\nMOSH CONNECT 60004 4NeCCgvZFe2RnPgrcU1PQw\r\n\r\nmosh-server (mosh 1.4.0) [build mosh 1.4.0]\n")
+	port, key, err := parseMoshConnect(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port != 60004 || len(key) != 16 {
+		t.Errorf("port=%d len(key)=%d", port, len(key))
+	}
+	if _, _, err := parseMoshConnect([]byte("mosh-server needs a UTF-8 native locale to run.\n")); err == nil {
+		t.Error("expected an error without a MOSH CONNECT line")
+	}
+}
+
+// fakeMoshServer answers every valid client datagram with a sealed
+// server→client datagram, like mosh-server's first ack.
+func fakeMoshServer(t *testing.T, key []byte, answer bool) int {
+	t.Helper()
+	o, _ := newOCB(key)
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	go func() {
+		buf := make([]byte, 4096)
+		for seq := uint64(0); ; seq++ {
+			n, from, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			pkt := buf[:n]
+			nonce := make([]byte, 12)
+			copy(nonce[4:], pkt[:8])
+			plain, err := o.open(nonce, pkt[8:])
+			if err != nil || pkt[0]&0x80 != 0 {
+				t.Errorf("server could not read client datagram: %v", err)
+				return
+			}
+			// timestamps (4) + fragment header (10) + zlib instruction.
+			if binary.BigEndian.Uint16(plain[12:]) != 0x8000 {
+				t.Errorf("fragment header = %x", plain[4:14])
+			}
+			zr, err := zlib.NewReader(bytes.NewReader(plain[14:]))
+			if err != nil {
+				t.Errorf("zlib: %v", err)
+				return
+			}
+			inst, _ := io.ReadAll(zr)
+			if !bytes.HasPrefix(inst, []byte{0x08, 0x02}) {
+				t.Errorf("instruction = %x", inst)
+			}
+			if !answer {
+				continue
+			}
+			binary.BigEndian.PutUint64(nonce[4:], 1<<63|seq)
+			reply := append(append([]byte{}, nonce[4:]...), o.seal(nonce, []byte{0, 0, 0xff, 0xff})...)
+			pc.WriteTo(reply, from)
+		}
+	}()
+	return pc.LocalAddr().(*net.UDPAddr).Port
+}
+
+func TestMoshHandshake(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	port := fakeMoshServer(t, key, true)
+	rtt, err := moshHandshake("127.0.0.1", port, key, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rtt <= 0 {
+		t.Errorf("rtt = %v", rtt)
+	}
+}
+
+func TestMoshHandshake_noReply(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	port := fakeMoshServer(t, key, false)
+	_, err := moshHandshake("127.0.0.1", port, key, 600*time.Millisecond)
+	if !errors.Is(err, ErrUDPBlocked) {
+		t.Errorf("err = %v, want ErrUDPBlocked", err)
+	}
+}
+
+func TestMoshCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+	office := netenv.Network{Subnet: "10.1.0.0/16"}
+	home := netenv.Network{Subnet: "192.168.1.0/24", Tailscale: true}
+
+	if err := RecordMosh("srv", office, MoshVerdict{Reason: "blocked"}); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := CachedMosh("srv", office); !ok || v.OK || v.Network != office.String() {
+		t.Errorf("office = %+v, %v", v, ok)
+	}
+	if _, ok := CachedMosh("srv", home); ok {
+		t.Error("verdict leaked to another network")
+	}
+
+	old := MoshVerdict{OK: true, Checked: time.Now().Add(-MoshCacheTTL - time.Minute)}
+	if err := RecordMosh("srv", home, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := CachedMosh("srv", home); ok {
+		t.Error("expired verdict was returned")
+	}
+}
+
+func TestMoshCache_concurrentWriters(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", t.TempDir())
+	office := netenv.Network{Subnet: "10.1.0.0/16"}
+
+	// The tray, tunnels and the CLI record verdicts from separate
+	// processes; only the file lock keeps their updates from being lost.
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := RecordMosh(fmt.Sprintf("srv%d", i), office, MoshVerdict{OK: true}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < 20; i++ {
+		if _, ok := CachedMosh(fmt.Sprintf("srv%d", i), office); !ok {
+			t.Errorf("verdict for srv%d was lost", i)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package probe
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"math/bits"
+)
+
+// ocb is AES-OCB (RFC 7253) with a 96-bit nonce, 128-bit tag and no
+// associated data — exactly what mosh uses for its datagrams.
+type ocb struct {
+	block   cipher.Block
+	lStar   [16]byte
+	lDollar [16]byte
+	l       [][16]byte // L_0, L_1, … grown on demand
+}
+
+var errOCBAuth = errors.New("ocb: message authentication failed")
+
+func newOCB(key []byte) (*ocb, error) {
+	b, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	o := &ocb{block: b}
+	b.Encrypt(o.lStar[:], o.lStar[:])
+	o.lDollar = double(o.lStar)
+	o.l = [][16]byte{double(o.lDollar)}
+	return o, nil
+}
+
+// double multiplies by x in GF(2^128).
+func double(s [16]byte) [16]byte {
+	var out [16]byte
+	carry := s[0] >> 7
+	for i := 0; i < 15; i++ {
+		out[i] = s[i]<<1 | s[i+1]>>7
+	}
+	out[15] = s[15] << 1
+	if carry == 1 {
+		out[15] ^= 0x87
+	}
+	return out
+}
+
+func (o *ocb) lAt(i int) [16]byte {
+	for len(o.l) <= i {
+		o.l = append(o.l, double(o.l[len(o.l)-1]))
+	}
+	return o.l[i]
+}
+
+func xor16(dst *[16]byte, a []byte) {
+	for i := range dst {
+		dst[i] ^= a[i]
+	}
+}
+
+// initialOffset is Offset_0 for a 12-byte nonce.
+func (o *ocb) initialOffset(nonce []byte) [16]byte {
+	var full [16]byte
+	full[3] = 0x01 // 7-bit tag length (0 for 128) ‖ zero padding ‖ 1
+	copy(full[4:], nonce)
+	bottom := uint(full[15] & 0x3f)
+	full[15] &^= 0x3f
+
+	var ktop [16]byte
+	o.block.Encrypt(ktop[:], full[:])
+	var stretch [24]byte
+	copy(stretch[:], ktop[:])
+	for i := 0; i < 8; i++ {
+		stretch[16+i] = ktop[i] ^ ktop[i+1]
+	}
+
+	var off [16]byte
+	byteShift, bitShift := bottom/8, bottom%8
+	for i := range off {
+		off[i] = stretch[uint(i)+byteShift] << bitShift
+		if bitShift != 0 {
+			off[i] |= stretch[uint(i)+byteShift+1] >> (8 - bitShift)
+		}
+	}
+	return off
+}
+
+// crypt runs OCB over in, writing to out, and returns the tag. The checksum
+// is always taken over the plaintext.
+func (o *ocb) crypt(nonce, in, out []byte, encrypt bool) [16]byte {
+	offset := o.initialOffset(nonce)
+	var sum, tmp [16]byte
+	i := 1
+	for ; len(in) >= 16; i++ {
+		l := o.lAt(bits.TrailingZeros(uint(i)))
+		xor16(&offset, l[:])
+		copy(tmp[:], in[:16])
+		if encrypt {
+			xor16(&sum, tmp[:])
+		}
+		xor16(&tmp, offset[:])
+		if encrypt {
+			o.block.Encrypt(tmp[:], tmp[:])
+		} else {
+			o.block.Decrypt(tmp[:], tmp[:])
+		}
+		xor16(&tmp, offset[:])
+		if !encrypt {
+			xor16(&sum, tmp[:])
+		}
+		copy(out[:16], tmp[:])
+		in, out = in[16:], out[16:]
+	}
+	if len(in) > 0 {
+		xor16(&offset, o.lStar[:])
+		var pad [16]byte
+		o.block.Encrypt(pad[:], offset[:])
+		var last [16]byte
+		for j := range in {
+			out[j] = in[j] ^ pad[j]
+		}
+		if encrypt {
+			copy(last[:], in)
+		} else {
+			copy(last[:], out[:len(in)])
+		}
+		last[len(in)] = 0x80
+		xor16(&sum, last[:])
+	}
+	xor16(&sum, offset[:])
+	xor16(&sum, o.lDollar[:])
+	var tag [16]byte
+	o.block.Encrypt(tag[:], sum[:])
+	return tag
+}
+
+// seal encrypts plaintext and appends the 16-byte tag.
+func (o *ocb) seal(nonce, plaintext []byte) []byte {
+	out := make([]byte, len(plaintext)+16)
+	tag := o.crypt(nonce, plaintext, out, true)
+	copy(out[len(plaintext):], tag[:])
+	return out
+}
+
+// open checks the tag and decrypts.
+func (o *ocb) open(nonce, sealed []byte) ([]byte, error) {
+	if len(sealed) < 16 {
+		return nil, errOCBAuth
+	}
+	ct, want := sealed[:len(sealed)-16], sealed[len(sealed)-16:]
+	out := make([]byte, len(ct))
+	tag := o.crypt(nonce, ct, out, false)
+	if subtle.ConstantTimeCompare(tag[:], want) != 1 {
+		return nil, errOCBAuth
+	}
+	return out, nil
+}
@@ -0,0 +1,42 @@
+package probe
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// Vectors from RFC 7253 Appendix A (empty associated data).
+func TestOCB(t *testing.T) {
+	key, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F")
+	o, err := newOCB(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct{ nonce, plain, sealed string }{
+		{"BBAA99887766554433221100", "", "785407BFFFC8AD9EDCC5520AC9111EE6"},
+		{"BBAA99887766554433221103", "0001020304050607", "45DD69F8F5AAE72414054CD1F35D82760B2CD00D2F99BFA9"},
+		{"BBAA99887766554433221106", "000102030405060708090A0B0C0D0E0F",
+			"5CE88EC2E0692706A915C00AEB8B2396F40E1C743F52436BDF06D8FA1ECA343D"},
+		{"BBAA99887766554433221109", "000102030405060708090A0B0C0D0E0F1011121314151617",
+			"221BD0DE7FA6FE993ECCD769460A0AF2D6CDED0C395B1C3CE725F32494B9F914D85C0B1EB38357FF"},
+	}
+	for _, tt := range tests {
+		nonce, _ := hex.DecodeString(tt.nonce)
+		plain, _ := hex.DecodeString(tt.plain)
+		want, _ := hex.DecodeString(tt.sealed)
+
+		got := o.seal(nonce, plain)
+		if !bytes.Equal(got, want) {
+			t.Errorf("seal(%s) = %X, want %X", tt.nonce, got, want)
+		}
+		back, err := o.open(nonce, want)
+		if err != nil || !bytes.Equal(back, plain) {
+			t.Errorf("open(%s) = %X, %v", tt.nonce, back, err)
+		}
+		want[0] ^= 1
+		if _, err := o.open(nonce, want); err == nil {
+			t.Errorf("open(%s) accepted a tampered message", tt.nonce)
+		}
+	}
+}
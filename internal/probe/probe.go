@@ -0,0 +1,187 @@
+// Package probe logs in to a server once over a native Go SSH client and
+// collects everything sshtie wants to know about it — which login worked,
+// where mosh-server lives, the tmux version, the OS and the login shell —
+// from a single batched remote script.
+package probe
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/tmux"
+)
+
+// DefaultTimeout bounds the whole probe: dial, login and script.
+const DefaultTimeout = 10 * time.Second
+
+// Facts is what one probe learned about a server.
+type Facts struct {
+	Auth       string            // login that worked: "agent", "key <path>" or "password"
+	OS         string            // uname -s: "Linux", "Darwin", "FreeBSD", …
+	OSRelease  map[string]string // /etc/os-release (Linux only)
+	MacVersion string            // sw_vers -productVersion (macOS only)
+	Shell      string            // login shell ($SHELL)
+	MoshServer string            // path to mosh-server; "" = not installed
+	Tmux       string            // `tmux -V` output; "" = not installed
+	Tools      map[string]string // tool name → path; "" = not installed
+	Sessions   []tmux.Session    // running tmux sessions, most recent first
+}
+
+// Tools whose location the probe script reports in Facts.Tools.
+var Tools = []string{"brew", "dnf", "apt-get", "yum", "pacman", "apk", "tailscale", "screen", "zellij", "etserver"}
+
+// Has reports whether tool was found on the server. mosh-server and tmux
+// are answered from their own fields.
+func (f Facts) Has(tool string) bool {
+	switch tool {
+	case "mosh-server", "mosh":
+		return f.MoshServer != ""
+	case "tmux":
+		return f.Tmux != ""
+	}
+	return f.Tools[tool] != ""
+}
+
+// OSName is a human-readable OS description, e.g. "Ubuntu 22.04.4 LTS" or
+// "macOS 14.5".
+func (f Facts) OSName() string {
+	switch {
+	case f.OS == "Darwin" && f.MacVersion != "":
+		return "macOS " + f.MacVersion
+	case f.OS == "Darwin":
+		return "macOS"
+	case f.OSRelease["PRETTY_NAME"] != "":
+		return f.OSRelease["PRETTY_NAME"]
+	case f.OS != "":
+		return f.OS
+	}
+	return "unknown OS"
+}
+
+// ErrNoCredentials means there was nothing to log in with: no agent, no
+// readable key and no password prompt.
+var ErrNoCredentials = errors.New("no SSH agent keys and no usable key file")
+
+// IsAuthError reports whether err means the server refused every login we
+// offered (typically a password-only server).
+func IsAuthError(err error) bool {
+	return err != nil && (errors.Is(err, ErrNoCredentials) ||
+		strings.Contains(err.Error(), "unable to authenticate"))
+}
+
+// Run logs in to p (through its jump chain) and runs the probe script.
+// Only non-interactive logins are tried — the SSH agent and the profile's
+// key — so it never prompts; use RunWith to allow a password.
+func Run(p profile.Profile) (Facts, error) {
+	return RunWith(p, Options{})
+}
+
+// Options adjusts RunWith.
+type Options struct {
+	Timeout time.Duration // 0 = DefaultTimeout
+	// Password, when set, is asked for a password after key logins fail.
+	Password func(prompt string) (string, error)
+	// Identity, when set, is the only key offered to p itself — no agent,
+	// no password — to prove that this key alone logs in. Jump hosts still
+	// use the usual credentials.
+	Identity string
+}
+
+// RunWith is Run with options.
+func RunWith(p profile.Profile, opts Options) (Facts, error) {
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	c, err := dial(p, opts)
+	if err != nil {
+		return Facts{}, err
+	}
+	defer c.Close()
+
+	out, err := c.run(script)
+	if err != nil {
+		return Facts{}, fmt.Errorf("run probe: %w", err)
+	}
+	f := parse(out)
+	f.Auth = c.auth
+	return f, nil
+}
+
+// Exec logs in to p as RunWith does and runs script under sh -c, returning
+// its standard output.
+func Exec(p profile.Profile, opts Options, script string) ([]byte, error) {
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	c, err := dial(p, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.run(script)
+}
+
+// script prints one "key=value" line per fact. It is run through sh -c so
+// it works whatever the login shell is, and checks the usual install
+// directories because non-interactive PATHs (macOS especially) often miss
+// /opt/homebrew/bin and /usr/local/bin.
+var script = `find_bin() {
+  p=$(command -v "$1" 2>/dev/null)
+  if [ -z "$p" ]; then
+    for d in /opt/homebrew/bin /usr/local/bin /usr/bin /bin /usr/sbin /snap/bin; do
+      if [ -x "$d/$1" ]; then p="$d/$1"; break; fi
+    done
+  fi
+  echo "$p"
+}
+echo "os=$(uname -s 2>/dev/null)"
+echo "shell=$SHELL"
+echo "mosh=$(find_bin mosh-server)"
+t=$(find_bin tmux); [ -n "$t" ] && echo "tmux=$("$t" -V 2>/dev/null)"
+[ -n "$t" ] && "$t" list-sessions -F 'session=` + tmux.Format + `' 2>/dev/null
+for b in ` + strings.Join(Tools, " ") + `; do echo "tool.$b=$(find_bin $b)"; done
+[ "$(uname -s)" = Darwin ] && echo "macos=$(sw_vers -productVersion 2>/dev/null)"
+[ -r /etc/os-release ] && sed 's/^/release./' /etc/os-release
+exit 0`
+
+// parse reads the probe script's output.
+func parse(out []byte) Facts {
+	f := Facts{OSRelease: map[string]string{}, Tools: map[string]string{}}
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		key, val, ok := strings.Cut(strings.TrimRight(sc.Text(), "\r"), "=")
+		if !ok {
+			continue
+		}
+		if key != "session" { // tabs separate its fields
+			val = strings.TrimSpace(val)
+		}
+		switch {
+		case key == "os":
+			f.OS = val
+		case key == "shell":
+			f.Shell = val
+		case key == "mosh":
+			f.MoshServer = val
+		case key == "tmux":
+			f.Tmux = val
+		case key == "macos":
+			f.MacVersion = val
+		case key == "session":
+			if s, ok := tmux.ParseLine(val); ok {
+				f.Sessions = append(f.Sessions, s)
+			}
+		case strings.HasPrefix(key, "tool."):
+			f.Tools[strings.TrimPrefix(key, "tool.")] = val
+		case strings.HasPrefix(key, "release."):
+			f.OSRelease[strings.TrimPrefix(key, "release.")] = strings.Trim(val, `"'`)
+		}
+	}
+	tmux.Sort(f.Sessions)
+	return f
+}
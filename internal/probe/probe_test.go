@@ -0,0 +1,304 @@
+package probe
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/ainsuotain/sshtie/internal/cloud/cloudtest"
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+func TestParse(t *testing.T) {
+	out := []byte("os=Linux\r\n" +
+		"shell=/bin/bash\n" +
+		"mosh=/usr/bin/mosh-server\n" +
+		"tmux=tmux 3.3a\n" +
+		"tool.brew=\n" +
+		"tool.apt-get=/usr/bin/apt-get\n" +
+		"release.ID=ubuntu\n" +
+		`release.PRETTY_NAME="Ubuntu 22.04.4 LTS"` + "\n" +
+		"session=old\t1\t0\t1700000000\n" +
+		"session=work\t3\t1\t1700000500\n" +
+		"garbage line\n")
+	f := parse(out)
+
+	if f.OS != "Linux" || f.Shell != "/bin/bash" {
+		t.Errorf("OS/Shell = %q/%q", f.OS, f.Shell)
+	}
+	if !f.Has("mosh-server") || !f.Has("tmux") || f.Tmux != "tmux 3.3a" {
+		t.Errorf("mosh/tmux = %q/%q", f.MoshServer, f.Tmux)
+	}
+	if f.Has("brew") || !f.Has("apt-get") {
+		t.Errorf("Tools = %v", f.Tools)
+	}
+	if got := f.OSName(); got != "Ubuntu 22.04.4 LTS" {
+		t.Errorf("OSName() = %q", got)
+	}
+	if f.OSRelease["ID"] != "ubuntu" {
+		t.Errorf("OSRelease = %v", f.OSRelease)
+	}
+	if len(f.Sessions) != 2 || f.Sessions[0].Name != "work" || f.Sessions[0].Windows != 3 || f.Sessions[0].Attached != 1 {
+		t.Errorf("Sessions = %+v", f.Sessions)
+	}
+}
+
+func TestParse_macOS(t *testing.T) {
+	f := parse([]byte("os=Darwin\nmacos=14.5\nmosh=\n"))
+	if got := f.OSName(); got != "macOS 14.5" {
+		t.Errorf("OSName() = %q", got)
+	}
+	if f.Has("mosh-server") || f.Has("tmux") {
+		t.Errorf("expected mosh-server and tmux missing: %+v", f)
+	}
+}
+
+// TestScript runs the probe script against this machine's sh to make sure
+// it parses and reports at least the basics.
+func TestScript(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	out, err := exec.Command("sh", "-c", script).Output()
+	if err != nil {
+		t.Fatalf("script failed: %v\n%s", err, out)
+	}
+	f := parse(out)
+	if f.OS == "" {
+		t.Errorf("no os= line in:\n%s", out)
+	}
+	for _, tool := range Tools {
+		if _, ok := f.Tools[tool]; !ok {
+			t.Errorf("no tool.%s line", tool)
+		}
+	}
+}
+
+// TestRunWith logs in to an in-process SSH server with a key file and runs
+// the probe script through the local sh.
+func TestRunWith(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	_, clientPriv, _ := ed25519.GenerateKey(nil)
+	block, err := ssh.MarshalPrivateKey(clientPriv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(home, "id_test")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	clientSigner, _ := ssh.NewSignerFromKey(clientPriv)
+
+	addr := serveSSH(t, clientSigner.PublicKey())
+	host, port, _ := net.SplitHostPort(addr)
+	p := profile.Profile{Name: "t", Host: host, User: "me", Key: keyPath}
+	p.Port, _ = strconv.Atoi(port)
+
+	f, err := RunWith(p, Options{Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Auth != "key ~/id_test" {
+		t.Errorf("Auth = %q", f.Auth)
+	}
+	if f.OS == "" {
+		t.Errorf("OS not reported: %+v", f)
+	}
+
+	// Without the key the server refuses us.
+	p.Key = filepath.Join(home, "missing")
+	if _, err := RunWith(p, Options{Timeout: 5 * time.Second}); !IsAuthError(err) {
+		t.Errorf("err = %v, want an auth error", err)
+	}
+
+	// A known_hosts that doesn't parse must not turn host key checks off.
+	p.Key = keyPath
+	if err := os.MkdirAll(filepath.Join(home, ".ssh"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".ssh", "known_hosts"), []byte("example.com ssh-ed25519 not-base64!\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunWith(p, Options{Timeout: 5 * time.Second}); err == nil || !strings.Contains(err.Error(), "known_hosts") {
+		t.Errorf("malformed known_hosts: err = %v, want it reported", err)
+	}
+}
+
+// TestRunWith_hopTimeout reaches a target through a jump host whose
+// forwarded channel never answers: the handshake over that channel can't
+// take a deadline, yet the timeout must still end it.
+func TestRunWith_hopTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	_, clientPriv, _ := ed25519.GenerateKey(nil)
+	block, _ := ssh.MarshalPrivateKey(clientPriv, "")
+	keyPath := filepath.Join(home, "id_test")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	clientSigner, _ := ssh.NewSignerFromKey(clientPriv)
+	// serveSSH accepts the direct-tcpip channel and then never relays.
+	hop := serveSSH(t, clientSigner.PublicKey())
+
+	p := profile.Profile{Name: "t", Host: "10.0.0.5", User: "me", Key: keyPath, Jump: []string{"me@" + hop}}
+	start := time.Now()
+	_, err := RunWith(p, Options{Timeout: time.Second})
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("err = %v, want a timeout", err)
+	}
+	if took := time.Since(start); took > 5*time.Second {
+		t.Errorf("RunWith took %s with a 1s timeout", took)
+	}
+}
+
+// serveSSH starts a one-purpose SSH server that accepts only allowed and
+// runs exec requests with sh -c.
+func serveSSH(t *testing.T, allowed ssh.PublicKey) string {
+	t.Helper()
+	_, hostPriv, _ := ed25519.GenerateKey(nil)
+	hostSigner, _ := ssh.NewSignerFromKey(hostPriv)
+	cfg := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), allowed.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown key")
+		},
+	}
+	cfg.AddHostKey(hostSigner)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(nc, cfg)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for nch := range chans {
+					ch, chReqs, err := nch.Accept()
+					if err != nil {
+						continue
+					}
+					go func() {
+						defer ch.Close()
+						for req := range chReqs {
+							if req.Type != "exec" {
+								req.Reply(false, nil)
+								continue
+							}
+							var payload struct{ Command string }
+							ssh.Unmarshal(req.Payload, &payload)
+							req.Reply(true, nil)
+							cmd := exec.Command("sh", "-c", payload.Command)
+							cmd.Stdout, cmd.Stderr = ch, ch.Stderr()
+							status := struct{ Code uint32 }{}
+							if err := cmd.Run(); err != nil {
+								status.Code = 1
+							}
+							ch.SendRequest("exit-status", false, ssh.Marshal(&status))
+							return
+						}
+					}()
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestRunWith_transport(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	_, clientPriv, _ := ed25519.GenerateKey(nil)
+	block, _ := ssh.MarshalPrivateKey(clientPriv, "")
+	keyPath := filepath.Join(home, "id_test")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	clientSigner, _ := ssh.NewSignerFromKey(clientPriv)
+	addr := serveSSH(t, clientSigner.PublicKey())
+	_, port, _ := net.SplitHostPort(addr)
+
+	// The stub aws relays to the test server only when asked for the
+	// profile's instance and port; the relay is this test binary.
+	cloudtest.StubCLIs(t, map[string]string{"aws": fmt.Sprintf(`case "$*" in
+  *"--target i-0abc "*"portNumber=%s"*) exec env SSHTIE_TUNNEL_TO=%s %q -test.run='^TestTunnelHelper$';;
+esac
+echo "An error occurred (TargetNotConnected)" >&2; exit 254
+`, port, addr, os.Args[0])})
+
+	p := profile.Profile{Name: "t", Host: "i-0abc", User: "me", Key: keyPath,
+		Transport: profile.Transport{Type: profile.TransportSSM, Instance: "i-0abc"}}
+	p.Port, _ = strconv.Atoi(port)
+	f, err := RunWith(p, Options{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Auth != "key ~/id_test" || f.OS == "" {
+		t.Errorf("facts = %+v", f)
+	}
+
+	p.Transport.Instance = "i-0other"
+	if _, err := RunWith(p, Options{Timeout: 10 * time.Second}); err == nil {
+		t.Error("wrong instance: want an error")
+	}
+}
+
+// TestTunnelHelper is the relay behind the stub aws in
+// TestRunWith_transport: stdin and stdout to $SSHTIE_TUNNEL_TO.
+func TestTunnelHelper(t *testing.T) {
+	to := os.Getenv("SSHTIE_TUNNEL_TO")
+	if to == "" {
+		t.Skip("only run as a tunnel")
+	}
+	conn, err := net.Dial("tcp", to)
+	if err != nil {
+		os.Exit(1)
+	}
+	go func() {
+		io.Copy(conn, os.Stdin)
+		conn.Close()
+	}()
+	io.Copy(os.Stdout, conn)
+	os.Exit(0)
+}
@@ -0,0 +1,85 @@
+package profile
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Address is one candidate address of a profile. A profile can be reached
+// under different names depending on where you are, and lists them in order
+// of preference; the conditions say where each one can work at all:
+//
+//	host: home.example.net            # fallback, tried last
+//	addresses:
+//	  - host: 192.168.1.10
+//	    subnet: 192.168.1.0/24        # only on the home LAN
+//	  - host: homeserver.tail-xyz.ts.net
+//	    tailscale: true               # only while Tailscale is running
+//
+// Which one is used is decided at connect time (see package locate).
+type Address struct {
+	Host      string `yaml:"host"`
+	Port      int    `yaml:"port,omitempty"`      // 0 = the profile's port
+	Subnet    string `yaml:"subnet,omitempty"`    // CIDR the local network must be inside
+	Tailscale bool   `yaml:"tailscale,omitempty"` // only when Tailscale runs locally
+}
+
+// Validate checks that a is usable.
+func (a Address) Validate() error {
+	if strings.TrimSpace(a.Host) == "" {
+		return fmt.Errorf("address has no host")
+	}
+	if a.Port < 0 || a.Port > 65535 {
+		return fmt.Errorf("address %s: invalid port %d", a.Host, a.Port)
+	}
+	if a.Subnet != "" {
+		if _, _, err := net.ParseCIDR(a.Subnet); err != nil {
+			return fmt.Errorf("address %s: invalid subnet %q (want CIDR, e.g. 192.168.1.0/24)", a.Host, a.Subnet)
+		}
+	}
+	return nil
+}
+
+// Conditional reports whether a only applies on some networks.
+func (a Address) Conditional() bool {
+	return a.Subnet != "" || a.Tailscale
+}
+
+// String renders a as host[:port] followed by its conditions, e.g.
+// "192.168.1.10 (subnet 192.168.1.0/24)".
+func (a Address) String() string {
+	s := a.Host
+	if a.Port != 0 {
+		s = net.JoinHostPort(a.Host, strconv.Itoa(a.Port))
+	}
+	var conds []string
+	if a.Subnet != "" {
+		conds = append(conds, "subnet "+a.Subnet)
+	}
+	if a.Tailscale {
+		conds = append(conds, "tailscale")
+	}
+	if len(conds) > 0 {
+		s += " (" + strings.Join(conds, ", ") + ")"
+	}
+	return s
+}
+
+// Candidates returns p's addresses in order of preference: the addresses
+// list, then Host as an unconditional fallback unless it is already listed.
+func (p Profile) Candidates() []Address {
+	out := make([]Address, 0, len(p.Addresses)+1)
+	listed := false
+	for _, a := range p.Addresses {
+		out = append(out, a)
+		if a.Host == p.Host && (a.Port == 0 || a.Port == p.Port) {
+			listed = true
+		}
+	}
+	if p.Host != "" && !listed {
+		out = append(out, Address{Host: p.Host})
+	}
+	return out
+}
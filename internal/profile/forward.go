@@ -0,0 +1,190 @@
+package profile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Forward is one port forward opened alongside the shell. Exactly one of the
+// fields is set, written in ssh's own -L / -R / -D syntax:
+//
+//	forwards:
+//	  - local: 5432:localhost:5432      # ssh -L
+//	  - remote: 8080:localhost:3000     # ssh -R
+//	  - dynamic: 1080                   # ssh -D (SOCKS)
+type Forward struct {
+	Local   string `yaml:"local,omitempty"`
+	Remote  string `yaml:"remote,omitempty"`
+	Dynamic string `yaml:"dynamic,omitempty"`
+}
+
+// ParseForward parses the compact form used on the command line and in the
+// edit UI: the ssh flag letter followed by its spec, e.g. "L5432:db:5432",
+// "R8080:localhost:3000" or "D1080".
+func ParseForward(s string) (Forward, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 {
+		return Forward{}, fmt.Errorf("invalid forward %q (want L…, R… or D…)", s)
+	}
+	spec := strings.TrimSpace(s[1:])
+	var f Forward
+	switch s[0] {
+	case 'L', 'l':
+		f.Local = spec
+	case 'R', 'r':
+		f.Remote = spec
+	case 'D', 'd':
+		f.Dynamic = spec
+	default:
+		return Forward{}, fmt.Errorf("invalid forward %q (want L…, R… or D…)", s)
+	}
+	if err := f.Validate(); err != nil {
+		return Forward{}, err
+	}
+	return f, nil
+}
+
+// ParseForwards parses a space- or comma-separated list of compact forwards.
+func ParseForwards(s string) ([]Forward, error) {
+	var out []Forward
+	for _, tok := range strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' }) {
+		f, err := ParseForward(tok)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+// Flag returns the ssh option letter for f: "L", "R" or "D".
+func (f Forward) Flag() string {
+	switch {
+	case f.Local != "":
+		return "L"
+	case f.Remote != "":
+		return "R"
+	default:
+		return "D"
+	}
+}
+
+// Spec returns the forward's ssh spec, without the flag letter.
+func (f Forward) Spec() string {
+	switch f.Flag() {
+	case "L":
+		return f.Local
+	case "R":
+		return f.Remote
+	default:
+		return f.Dynamic
+	}
+}
+
+// String returns the compact form accepted by ParseForward.
+func (f Forward) String() string {
+	return f.Flag() + f.Spec()
+}
+
+// Args returns the ssh command-line arguments for f, e.g. ["-L", "5432:db:5432"].
+func (f Forward) Args() []string {
+	return []string{"-" + f.Flag(), f.Spec()}
+}
+
+// ConfigLine returns f as an ssh_config directive, e.g.
+// "LocalForward 5432 db:5432".
+func (f Forward) ConfigLine() string {
+	parts := splitForwardSpec(f.Spec())
+	switch {
+	case f.Flag() == "D":
+		return "DynamicForward " + f.Spec()
+	case len(parts) <= 2:
+		// Remote dynamic forward: RemoteForward [bind:]port
+		return "RemoteForward " + f.Spec()
+	}
+	listen := strings.Join(parts[:len(parts)-2], ":")
+	target := strings.Join(parts[len(parts)-2:], ":")
+	name := "LocalForward"
+	if f.Flag() == "R" {
+		name = "RemoteForward"
+	}
+	return name + " " + listen + " " + target
+}
+
+// Validate checks that exactly one kind is set and that its spec has the
+// shape ssh expects.
+func (f Forward) Validate() error {
+	set := 0
+	for _, v := range []string{f.Local, f.Remote, f.Dynamic} {
+		if v != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("forward must set exactly one of local, remote or dynamic")
+	}
+
+	parts := splitForwardSpec(f.Spec())
+	var ports []string
+	switch f.Flag() {
+	case "L":
+		// [bind:]port:host:hostport
+		if len(parts) != 3 && len(parts) != 4 {
+			return fmt.Errorf("invalid forward %q (want [bind:]port:host:hostport)", f.String())
+		}
+		ports = []string{parts[len(parts)-3], parts[len(parts)-1]}
+	case "R":
+		// [bind:]port:host:hostport, or [bind:]port for a remote SOCKS proxy
+		switch len(parts) {
+		case 1, 2:
+			ports = []string{parts[len(parts)-1]}
+		case 3, 4:
+			ports = []string{parts[len(parts)-3], parts[len(parts)-1]}
+		default:
+			return fmt.Errorf("invalid forward %q (want [bind:]port[:host:hostport])", f.String())
+		}
+	case "D":
+		// [bind:]port
+		if len(parts) != 1 && len(parts) != 2 {
+			return fmt.Errorf("invalid forward %q (want [bind:]port)", f.String())
+		}
+		ports = []string{parts[len(parts)-1]}
+	}
+	for _, p := range ports {
+		if n, err := strconv.Atoi(p); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("invalid forward %q: bad port %q", f.String(), p)
+		}
+	}
+	return nil
+}
+
+// ForwardArgs returns the ssh arguments for all of p's forwards.
+func (p Profile) ForwardArgs() []string {
+	var args []string
+	for _, f := range p.Forwards {
+		args = append(args, f.Args()...)
+	}
+	return args
+}
+
+// splitForwardSpec splits a forward spec on ':' while keeping bracketed
+// IPv6 addresses ("[::1]") intact.
+func splitForwardSpec(spec string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range spec {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ':':
+			if depth == 0 {
+				parts = append(parts, spec[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, spec[start:])
+}
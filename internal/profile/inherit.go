@@ -0,0 +1,394 @@
+package profile
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Inheritance
+//
+// profiles.yaml may declare named groups of defaults next to the profiles:
+//
+//	groups:
+//	  - name: prod
+//	    user: deploy
+//	    key: ~/.ssh/prod_ed25519
+//	  - name: prod-eu
+//	    extends: prod
+//	    jump: [bastion-eu]
+//	profiles:
+//	  - name: web1
+//	    extends: prod-eu
+//	    host: 10.0.0.1
+//
+// Load resolves every profile through its extends chain (a key present in a
+// lower layer wins, even when it holds a zero value such as false; the keys
+// of a reconnect block are merged one by one rather than as a whole). Save does
+// the reverse: a profile only stores the keys it set itself or that now
+// differ from what it inherits, so editing one profile never flattens the
+// groups into it.
+
+// FieldSource describes where one resolved profile value came from.
+type FieldSource struct {
+	Field  string // yaml key, e.g. "server_alive_interval"
+	Value  string // value rendered as YAML flow text
+	Source string // profile name, group name, or "" for the built-in default
+}
+
+type fieldInfo struct {
+	index int
+	key   string
+}
+
+// profileFields lists Profile's yaml keys in declaration order.
+var profileFields = func() []fieldInfo {
+	t := reflect.TypeOf(Profile{})
+	var out []fieldInfo
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("yaml")
+		if !ok || !f.IsExported() {
+			continue
+		}
+		key, _, _ := strings.Cut(tag, ",")
+		out = append(out, fieldInfo{index: i, key: key})
+	}
+	return out
+}()
+
+// mergedFields are mapping-valued keys a layer sets key by key: a profile's
+// reconnect: {max_attempts: 3} keeps the backoff its group sets. Their
+// sub-keys are tracked as "reconnect.backoff" and so on.
+var mergedFields = map[string]bool{"reconnect": true}
+
+// layered is a set of resolved yaml fields plus the layer each came from.
+type layered struct {
+	fields map[string]*yaml.Node
+	origin map[string]string
+}
+
+type resolver struct {
+	groups map[string]*yaml.Node
+	cache  map[string]layered
+}
+
+func newResolver(groups []yaml.Node) (*resolver, error) {
+	r := &resolver{groups: make(map[string]*yaml.Node), cache: make(map[string]layered)}
+	for i := range groups {
+		g := &groups[i]
+		name, err := nodeName(g)
+		if err != nil {
+			return nil, fmt.Errorf("groups: %w", err)
+		}
+		if _, dup := r.groups[name]; dup {
+			return nil, fmt.Errorf("group %q defined twice", name)
+		}
+		r.groups[name] = g
+	}
+	return r, nil
+}
+
+// group resolves a named group through its own extends chain.
+func (r *resolver) group(name string, visiting map[string]bool) (layered, error) {
+	if l, ok := r.cache[name]; ok {
+		return l, nil
+	}
+	n, ok := r.groups[name]
+	if !ok {
+		return layered{}, fmt.Errorf("group %q not found", name)
+	}
+	if visiting[name] {
+		return layered{}, fmt.Errorf("group %q extends itself", name)
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	l, err := r.overlay(n, name, visiting)
+	if err != nil {
+		return layered{}, err
+	}
+	r.cache[name] = l
+	return l, nil
+}
+
+// base returns the inherited fields for an extends value ("" = none).
+func (r *resolver) base(extends string) (layered, error) {
+	if extends == "" {
+		return layered{fields: map[string]*yaml.Node{}, origin: map[string]string{}}, nil
+	}
+	return r.group(extends, map[string]bool{})
+}
+
+// overlay resolves n's parent and lays n's own keys on top of it.
+func (r *resolver) overlay(n *yaml.Node, layerName string, visiting map[string]bool) (layered, error) {
+	parent := layered{fields: map[string]*yaml.Node{}, origin: map[string]string{}}
+	if ext := scalarField(n, "extends"); ext != "" {
+		var err error
+		if parent, err = r.group(ext, visiting); err != nil {
+			return layered{}, err
+		}
+	}
+	out := layered{
+		fields: make(map[string]*yaml.Node, len(parent.fields)),
+		origin: make(map[string]string, len(parent.origin)),
+	}
+	for k, v := range parent.fields {
+		out.fields[k] = v
+		out.origin[k] = parent.origin[k]
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k := n.Content[i].Value
+		if k == "name" || k == "extends" {
+			continue
+		}
+		v := n.Content[i+1]
+		if prev := out.fields[k]; mergedFields[k] && v.Kind == yaml.MappingNode &&
+			prev != nil && prev.Kind == yaml.MappingNode {
+			v = mergeMapping(prev, v)
+		}
+		if mergedFields[k] && n.Content[i+1].Kind == yaml.MappingNode {
+			for j := 0; j+1 < len(n.Content[i+1].Content); j += 2 {
+				out.origin[k+"."+n.Content[i+1].Content[j].Value] = layerName
+			}
+		}
+		out.fields[k] = v
+		out.origin[k] = layerName
+	}
+	return out, nil
+}
+
+// mergeMapping returns base with top's keys laid over it.
+func mergeMapping(base, top *yaml.Node) *yaml.Node {
+	out := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for i := 0; i+1 < len(base.Content); i += 2 {
+		if mappingValue(top, base.Content[i].Value) == nil {
+			out.Content = append(out.Content, base.Content[i], base.Content[i+1])
+		}
+	}
+	out.Content = append(out.Content, top.Content...)
+	return out
+}
+
+// profile decodes one raw profile entry into a fully resolved Profile.
+func (r *resolver) profile(n *yaml.Node) (Profile, error) {
+	name, err := nodeName(n)
+	if err != nil {
+		return Profile{}, fmt.Errorf("profiles: %w", err)
+	}
+	l, err := r.overlay(n, name, map[string]bool{})
+	if err != nil {
+		return Profile{}, fmt.Errorf("profile %q: %w", name, err)
+	}
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, f := range profileFields {
+		var v *yaml.Node
+		switch f.key {
+		case "name", "extends":
+			v = mappingValue(n, f.key)
+		default:
+			v = l.fields[f.key]
+		}
+		if v != nil {
+			merged.Content = append(merged.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: f.key}, v)
+		}
+	}
+	var p Profile
+	if err := merged.Decode(&p); err != nil {
+		return Profile{}, fmt.Errorf("profile %q: %w", name, err)
+	}
+	p.origin = l.origin
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if !knownField(n.Content[i].Value) {
+			p.extra = append(p.extra, n.Content[i], n.Content[i+1])
+		}
+	}
+	p.own = make(map[string]bool)
+	for k, src := range l.origin {
+		if src == name {
+			p.own[k] = true
+		}
+	}
+	return p, nil
+}
+
+// encode renders p as a yaml mapping that stores only what p adds on top of
+// the layers it extends.
+func (r *resolver) encode(p Profile) (*yaml.Node, error) {
+	b, err := r.base(p.Extends)
+	if err != nil {
+		return nil, fmt.Errorf("profile %q: %w", p.Name, err)
+	}
+	var inherited Profile
+	if len(b.fields) > 0 {
+		m := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for k, v := range b.fields {
+			m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, v)
+		}
+		if err := m.Decode(&inherited); err != nil {
+			return nil, fmt.Errorf("profile %q: %w", p.Name, err)
+		}
+	}
+
+	pv := reflect.ValueOf(p)
+	iv := reflect.ValueOf(inherited)
+	out := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, f := range profileFields {
+		own := pv.Field(f.index)
+		write := false
+		switch f.key {
+		case "name":
+			write = true
+		case "extends":
+			write = p.Extends != ""
+		default:
+			write = p.own[f.key] || !sameValue(own, iv.Field(f.index))
+		}
+		if !write {
+			continue
+		}
+		v := &yaml.Node{}
+		if err := v.Encode(own.Interface()); err != nil {
+			return nil, err
+		}
+		if mergedFields[f.key] {
+			if v, err = p.ownPart(f.key, v, iv.Field(f.index).Interface()); err != nil {
+				return nil, err
+			}
+			if v == nil {
+				continue
+			}
+		}
+		out.Content = append(out.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: f.key}, v)
+	}
+	out.Content = append(out.Content, p.extra...)
+	return out, nil
+}
+
+// ownPart trims the encoded merged field v to what p adds on top of
+// inherited: the sub-keys it set itself or that now differ. nil when
+// nothing is left.
+func (p Profile) ownPart(key string, v *yaml.Node, inherited interface{}) (*yaml.Node, error) {
+	in := &yaml.Node{}
+	if err := in.Encode(inherited); err != nil {
+		return nil, err
+	}
+	out := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for i := 0; i+1 < len(v.Content); i += 2 {
+		sub := v.Content[i].Value
+		prev := mappingValue(in, sub)
+		if p.own[key+"."+sub] || prev == nil || prev.Value != v.Content[i+1].Value {
+			out.Content = append(out.Content, v.Content[i], v.Content[i+1])
+		}
+	}
+	if len(out.Content) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
+
+// Source reports which layer supplied the given yaml field: the profile's
+// own name, the group it was inherited from, or "" when nothing set it and
+// the built-in default applies.
+func (p Profile) Source(field string) string {
+	switch field {
+	case "name":
+		return p.Name
+	case "extends":
+		if p.Extends == "" {
+			return ""
+		}
+		return p.Name
+	}
+	if p.origin == nil {
+		// Not loaded from disk — every non-zero value is the profile's own.
+		for _, f := range profileFields {
+			if f.key == field && !reflect.ValueOf(p).Field(f.index).IsZero() {
+				return p.Name
+			}
+		}
+		return ""
+	}
+	return p.origin[field]
+}
+
+// Sources lists every field that has a value, in declaration order, along
+// with the layer it came from.
+func (p Profile) Sources() []FieldSource {
+	pv := reflect.ValueOf(p)
+	var out []FieldSource
+	for _, f := range profileFields {
+		src := p.Source(f.key)
+		v := pv.Field(f.index)
+		if src == "" && v.IsZero() {
+			continue
+		}
+		out = append(out, FieldSource{Field: f.key, Value: flowValue(v.Interface()), Source: src})
+	}
+	return out
+}
+
+func knownField(key string) bool {
+	for _, f := range profileFields {
+		if f.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// ── yaml node helpers ─────────────────────────────────────────────────────────
+
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func scalarField(n *yaml.Node, key string) string {
+	if v := mappingValue(n, key); v != nil && v.Kind == yaml.ScalarNode {
+		return v.Value
+	}
+	return ""
+}
+
+func nodeName(n *yaml.Node) (string, error) {
+	if n.Kind != yaml.MappingNode {
+		return "", fmt.Errorf("line %d: entry is not a mapping", n.Line)
+	}
+	name := scalarField(n, "name")
+	if name == "" {
+		return "", fmt.Errorf("line %d: entry has no name", n.Line)
+	}
+	return name, nil
+}
+
+func sameValue(a, b reflect.Value) bool {
+	if a.IsZero() && b.IsZero() {
+		return true
+	}
+	if a.Kind() == reflect.Slice && a.Len() == 0 && b.Len() == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+func flowValue(v interface{}) string {
+	n := &yaml.Node{}
+	if err := n.Encode(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	n.Style = yaml.FlowStyle
+	out, err := yaml.Marshal(n)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,168 @@
+package profile
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Hop is one resolved jump host in a ProxyJump chain.
+type Hop struct {
+	Name string // sshtie profile name; empty for raw user@host:port entries
+	User string
+	Host string
+	Port int    // 0 = ssh default (22)
+	Key  string // the profile's own key file, expanded; empty = ssh's defaults
+}
+
+// String formats the hop as ssh -J / ProxyJump expects: [user@]host[:port].
+func (h Hop) String() string {
+	host := h.Host
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6 literal
+	}
+	if h.Port != 0 && h.Port != 22 {
+		host += ":" + strconv.Itoa(h.Port)
+	}
+	if h.User != "" {
+		return h.User + "@" + host
+	}
+	return host
+}
+
+// Label returns the profile name for profile hops, or the address for raw ones.
+func (h Hop) Label() string {
+	if h.Name != "" {
+		return h.Name
+	}
+	return h.String()
+}
+
+// JumpChain resolves p.Jump against the saved profiles and returns the
+// ordered list of hops (outermost bastion first). Empty when p has no jump.
+func JumpChain(p Profile) ([]Hop, error) {
+	if len(p.Jump) == 0 {
+		return nil, nil
+	}
+	all, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	return ResolveJumpChain(p, all)
+}
+
+// ResolveJumpChain is JumpChain against an explicit profile list.
+// A jump entry that names another profile expands to that profile's own
+// chain followed by the profile itself; anything else is parsed as a raw
+// [user@]host[:port] address.
+func ResolveJumpChain(p Profile, all []Profile) ([]Hop, error) {
+	byName := make(map[string]Profile, len(all))
+	for _, q := range all {
+		byName[q.Name] = q
+	}
+	return resolveJumps(p, byName, map[string]bool{p.Name: true})
+}
+
+func resolveJumps(p Profile, byName map[string]Profile, visiting map[string]bool) ([]Hop, error) {
+	var hops []Hop
+	for _, entry := range p.Jump {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if q, ok := byName[entry]; ok {
+			if visiting[q.Name] {
+				return nil, fmt.Errorf("jump chain of %q loops back to %q", p.Name, q.Name)
+			}
+			visiting[q.Name] = true
+			inner, err := resolveJumps(q, byName, visiting)
+			if err != nil {
+				return nil, err
+			}
+			delete(visiting, q.Name)
+			hops = append(hops, inner...)
+			hops = append(hops, Hop{Name: q.Name, User: q.User, Host: q.Host, Port: q.Port, Key: expandHome(q.Key)})
+			continue
+		}
+		h, err := ParseHop(entry)
+		if err != nil {
+			return nil, fmt.Errorf("profile %q: %w", p.Name, err)
+		}
+		hops = append(hops, h)
+	}
+	return hops, nil
+}
+
+// ParseHop parses a raw [user@]host[:port] jump address.
+func ParseHop(s string) (Hop, error) {
+	var h Hop
+	rest := s
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		h.User = rest[:i]
+		rest = rest[i+1:]
+	}
+	switch {
+	case strings.HasPrefix(rest, "["):
+		host, port, err := net.SplitHostPort(rest)
+		if err != nil {
+			// Bracketed IPv6 without a port: "[::1]".
+			if !strings.HasSuffix(rest, "]") {
+				return Hop{}, fmt.Errorf("invalid jump host %q", s)
+			}
+			h.Host = strings.Trim(rest, "[]")
+			break
+		}
+		h.Host = host
+		if h.Port, err = parsePort(port); err != nil {
+			return Hop{}, fmt.Errorf("invalid jump host %q: %w", s, err)
+		}
+	case strings.Count(rest, ":") == 1:
+		host, port, _ := strings.Cut(rest, ":")
+		h.Host = host
+		var err error
+		if h.Port, err = parsePort(port); err != nil {
+			return Hop{}, fmt.Errorf("invalid jump host %q: %w", s, err)
+		}
+	default:
+		h.Host = rest // hostname or bare IPv6 literal
+	}
+	if h.Host == "" {
+		return Hop{}, fmt.Errorf("invalid jump host %q: missing host", s)
+	}
+	return h, nil
+}
+
+func parsePort(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > 65535 {
+		return 0, fmt.Errorf("port must be a number between 1 and 65535")
+	}
+	return n, nil
+}
+
+// ProxyJump joins hops into the comma-separated form used by ssh -J.
+func ProxyJump(hops []Hop) string {
+	parts := make([]string, len(hops))
+	for i, h := range hops {
+		parts[i] = h.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// EntryPoint returns the address sshtie has to reach over TCP to connect to
+// p: the first jump host when a chain is configured, otherwise p itself.
+func EntryPoint(p Profile, hops []Hop) (host string, port int) {
+	if len(hops) > 0 {
+		port = hops[0].Port
+		if port == 0 {
+			port = 22
+		}
+		return hops[0].Host, port
+	}
+	port = p.Port
+	if port == 0 {
+		port = 22
+	}
+	return p.Host, port
+}
@@ -0,0 +1,51 @@
+package profile
+
+import "fmt"
+
+// Window is one tmux window of a profile's layout, created when sshtie
+// starts the profile's session (never on re-attach):
+//
+//	layout:
+//	  - name: logs
+//	    dir: /var/log
+//	    panes:
+//	      - command: tail -f syslog
+//	      - split: down
+//	        command: htop
+//	  - name: editor
+//	    dir: ~/src/api
+//	    panes:
+//	      - command: vim
+//
+// A window without panes is a single shell in Dir.
+type Window struct {
+	Name string `yaml:"name,omitempty"`
+	Dir  string `yaml:"dir,omitempty"` // working directory for panes that don't set one
+	// Layout is applied once the panes exist: a tmux preset (tiled,
+	// even-horizontal, main-vertical, …) or a layout string as captured by
+	// sshtie layout capture.
+	Layout string `yaml:"layout,omitempty"`
+	Panes  []Pane `yaml:"panes,omitempty"`
+}
+
+// Pane is one pane of a Window. Every pane after the first is split off the
+// one before it.
+type Pane struct {
+	Split   string `yaml:"split,omitempty"`   // right (default) | down; ignored for the first pane
+	Dir     string `yaml:"dir,omitempty"`     // default: the window's
+	Command string `yaml:"command,omitempty"` // typed into the pane's shell
+}
+
+// ValidateLayout checks the split directions of a layout.
+func ValidateLayout(windows []Window) error {
+	for i, w := range windows {
+		for j, pane := range w.Panes {
+			switch pane.Split {
+			case "", "right", "down":
+			default:
+				return fmt.Errorf("layout: window %d pane %d: unknown split %q (want right or down)", i+1, j+1, pane.Split)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+
+package profile
+
+import (
+	"os"
+	"syscall"
+)
+
+// tryLockFile takes a non-blocking exclusive advisory lock on f.
+// It reports false (and no error) when another process holds the lock.
+func tryLockFile(f *os.File) (bool, error) {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
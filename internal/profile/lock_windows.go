@@ -0,0 +1,26 @@
+//go:build windows
+
+package profile
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// tryLockFile takes a non-blocking exclusive lock on the first byte of f.
+// It reports false (and no error) when another process holds the lock.
+func tryLockFile(f *os.File) (bool, error) {
+	var ol windows.Overlapped
+	err := windows.LockFileEx(windows.Handle(f.Fd()),
+		windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &ol)
+	if err == windows.ERROR_LOCK_VIOLATION {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func unlockFile(f *os.File) error {
+	var ol windows.Overlapped
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, &ol)
+}
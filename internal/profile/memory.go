@@ -0,0 +1,26 @@
+package profile
+
+import "time"
+
+// DefaultRememberFailures is how long a strategy that failed on a network
+// is skipped there when neither the profile nor profiles.yaml say otherwise.
+const DefaultRememberFailures = 12 * time.Hour
+
+// RememberFailures resolves p's failure memory: the profile's (or its
+// group's) remember_failures, then the top-level one, then the default.
+// Zero means failures are not remembered.
+func RememberFailures(p Profile) time.Duration {
+	d := p.RememberFailures
+	if d == 0 {
+		if s, err := readStoreLocked(); err == nil {
+			d = s.RememberFailures
+		}
+	}
+	switch {
+	case d == 0:
+		return DefaultRememberFailures
+	case d < 0:
+		return 0
+	}
+	return d
+}
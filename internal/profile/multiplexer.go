@@ -0,0 +1,27 @@
+package profile
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Multiplexers are the values multiplexer: accepts. none opens a bare
+// shell, so nothing survives a disconnect.
+var Multiplexers = []string{"tmux", "screen", "zellij", "none"}
+
+// DefaultMultiplexer is used when a profile doesn't set one.
+const DefaultMultiplexer = "tmux"
+
+// Multiplexer resolves the terminal multiplexer p keeps its session in.
+func Multiplexer(p Profile) (string, error) {
+	if p.Multiplexer == "" {
+		return DefaultMultiplexer, nil
+	}
+	for _, m := range Multiplexers {
+		if p.Multiplexer == m {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("profile %q: unknown multiplexer %q (want %s)",
+		p.Name, p.Multiplexer, strings.Join(Multiplexers, ", "))
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -16,19 +17,76 @@ type Profile struct {
 	Port        int      `yaml:"port"`
 	Key         string   `yaml:"key,omitempty"`
 	TmuxSession string   `yaml:"tmux_session"`
+	Multiplexer string   `yaml:"multiplexer,omitempty"` // tmux | screen | zellij | none
 	MoshServer  string   `yaml:"mosh_server,omitempty"`
-	Network     string   `yaml:"network"` // auto | tailscale | direct
+	ETPort      int      `yaml:"et_port,omitempty"` // etserver's TCP port, default 2022
+	Network     string   `yaml:"network"`           // auto | tailscale | direct
 	Tags        []string `yaml:"tags,omitempty"`
 
+	// Extends names a group in profiles.yaml whose values this profile
+	// inherits (see inherit.go).
+	Extends string `yaml:"extends,omitempty"`
+
+	// Addresses are other ways to reach Host, each optionally limited to
+	// some networks (see address.go). Host stays the last resort.
+	Addresses []Address `yaml:"addresses,omitempty"`
+
+	// Jump is an ordered bastion chain (outermost first). Each entry is either
+	// another sshtie profile name or a raw [user@]host[:port] address.
+	Jump []string `yaml:"jump,omitempty"`
+
+	// Transport tunnels SSH through a cloud provider's CLI instead of
+	// dialling Host (see transport.go).
+	Transport Transport `yaml:"transport,omitempty"`
+
+	// Forwards are port forwards opened together with the shell (see forward.go).
+	Forwards []Forward `yaml:"forwards,omitempty"`
+
+	// Layout is the windows and panes built when the tmux session is first
+	// created (see layout.go).
+	Layout []Window `yaml:"layout,omitempty"`
+
+	// Strategies pins the order connection strategies are tried in, e.g.
+	// [ssh+tmux, ssh]. Empty means the default mosh+tmux → ssh+tmux → ssh.
+	Strategies []string `yaml:"strategies,omitempty"`
+
+	// Reconnect tunes how dropped sessions are re-established (see reconnect.go).
+	Reconnect Reconnect `yaml:"reconnect,omitempty"`
+
+	// HostKeys are the SHA256 fingerprints of the server's host keys this
+	// profile trusts, pinned on first connect (see package hostkey).
+	HostKeys []string `yaml:"host_keys,omitempty"`
+
+	// Wake sends a Wake-on-LAN packet when the server is asleep (see wake.go).
+	Wake Wake `yaml:"wake,omitempty"`
+
+	// RememberFailures is how long a strategy that failed on a network is
+	// skipped there (see memory.go). Negative turns the memory off.
+	RememberFailures time.Duration `yaml:"remember_failures,omitempty"`
+
 	// Advanced SSH options (0/false = use built-in default).
 	ForwardAgent        bool `yaml:"forward_agent,omitempty"`
-	ServerAliveInterval int  `yaml:"server_alive_interval,omitempty"` // default 10 s
+	ServerAliveInterval int  `yaml:"server_alive_interval,omitempty"`  // default 10 s
 	ServerAliveCountMax int  `yaml:"server_alive_count_max,omitempty"` // default 60
 	ConnectionAttempts  int  `yaml:"connection_attempts,omitempty"`    // default 3
+
+	origin map[string]string // yaml key → layer that set it (nil = not loaded)
+	own    map[string]bool   // keys set by this profile itself in profiles.yaml
+	extra  []*yaml.Node      // unknown key/value pairs, written back untouched
+	rev    string            // profiles.yaml revision this profile was loaded from
 }
 
+// store is the on-disk layout of profiles.yaml. Entries are kept as raw
+// nodes so inheritance can tell "set to zero" apart from "not set".
 type store struct {
-	Profiles []Profile `yaml:"profiles"`
+	Version          int           `yaml:"version"`
+	Reconnect        Reconnect     `yaml:"reconnect,omitempty"`         // global default policy
+	RememberFailures time.Duration `yaml:"remember_failures,omitempty"` // global default
+	Groups           []yaml.Node   `yaml:"groups,omitempty"`
+	Profiles         []yaml.Node   `yaml:"profiles"`
+
+	rev      string // revision of the bytes this store was read from
+	migrated bool   // upgraded from an older schema while reading
 }
 
 // ConfigDir returns ~/.sshtie
@@ -48,46 +106,233 @@ func configPath() (string, error) {
 	return filepath.Join(dir, "profiles.yaml"), nil
 }
 
-// Load reads all profiles from disk. Returns empty slice if file doesn't exist yet.
-func Load() ([]Profile, error) {
+// readStore reads and parses profiles.yaml. A missing file is an empty store.
+// Files from an older schema are migrated in memory; with persist set (the
+// caller holds the lock) the upgrade is also written back, keeping the
+// original next to it as profiles.yaml.v<N>.bak.
+func readStore(persist bool) (store, error) {
 	path, err := configPath()
 	if err != nil {
-		return nil, err
+		return store{}, err
 	}
 
 	data, err := os.ReadFile(path)
 	if os.IsNotExist(err) {
-		return []Profile{}, nil
+		return store{Version: SchemaVersion}, nil
 	}
 	if err != nil {
-		return nil, fmt.Errorf("read profiles: %w", err)
+		return store{}, fmt.Errorf("read profiles: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return store{}, fmt.Errorf("parse profiles.yaml: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return store{Version: SchemaVersion, rev: revision(data)}, nil // empty file
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return store{}, fmt.Errorf("parse profiles.yaml: top level is not a mapping")
+	}
+
+	from, err := fileVersion(root)
+	if err != nil {
+		return store{}, fmt.Errorf("parse profiles.yaml: %w", err)
+	}
+	migrated, err := migrate(root)
+	if err != nil {
+		return store{}, fmt.Errorf("parse profiles.yaml: %w", err)
+	}
+	rev := revision(data)
+	if migrated && persist {
+		if err := writeBackup(path, from, data); err != nil {
+			return store{}, fmt.Errorf("back up profiles.yaml: %w", err)
+		}
+		out, err := yaml.Marshal(&doc)
+		if err != nil {
+			return store{}, fmt.Errorf("marshal profiles: %w", err)
+		}
+		if err := WriteFileAtomic(path, out, 0600); err != nil {
+			return store{}, fmt.Errorf("write migrated profiles: %w", err)
+		}
+		rev = revision(out)
 	}
 
 	var s store
-	if err := yaml.Unmarshal(data, &s); err != nil {
+	if err := root.Decode(&s); err != nil {
+		return store{}, fmt.Errorf("parse profiles.yaml: %w", err)
+	}
+	s.rev = rev
+	s.migrated = migrated
+	return s, nil
+}
+
+// readStoreLocked is readStore for callers that don't hold the lock: reads
+// are lock-free (writes are atomic), and the lock is only taken when the
+// file needs migrating.
+func readStoreLocked() (store, error) {
+	s, err := readStore(false)
+	if err != nil || !s.migrated {
+		return s, err
+	}
+	unlock, err := lockStore()
+	if err != nil {
+		return store{}, err
+	}
+	defer unlock()
+	return readStore(true)
+}
+
+// resolve turns the raw store into resolved profiles stamped with its revision.
+func (s store) resolve() ([]Profile, error) {
+	r, err := newResolver(s.Groups)
+	if err != nil {
 		return nil, fmt.Errorf("parse profiles.yaml: %w", err)
 	}
-	return s.Profiles, nil
+	profiles := make([]Profile, 0, len(s.Profiles))
+	for i := range s.Profiles {
+		p, err := r.profile(&s.Profiles[i])
+		if err != nil {
+			return nil, fmt.Errorf("parse profiles.yaml: %w", err)
+		}
+		p.rev = s.rev
+		profiles = append(profiles, p)
+	}
+	return profiles, nil
+}
+
+// Load reads all profiles from disk, resolved through their groups.
+// Returns empty slice if file doesn't exist yet.
+func Load() ([]Profile, error) {
+	s, err := readStoreLocked()
+	if err != nil {
+		return nil, err
+	}
+	return s.resolve()
+}
+
+// Groups returns the names of the groups defined in profiles.yaml.
+func Groups() ([]string, error) {
+	s, err := readStoreLocked()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(s.Groups))
+	for i := range s.Groups {
+		if name, err := nodeName(&s.Groups[i]); err == nil {
+			names = append(names, name)
+		}
+	}
+	return names, nil
 }
 
 // Save writes profiles to disk, creating the directory if needed.
+// Groups already in the file are kept as-is, and each profile only stores
+// the values it doesn't inherit from them.
+//
+// Save returns ErrConflict when the profiles were loaded from a version of
+// profiles.yaml that has since been changed by someone else. Prefer Update,
+// which can't conflict.
 func Save(profiles []Profile) error {
-	dir, err := ConfigDir()
+	unlock, err := lockStore()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := readStore(true)
+	if err != nil {
+		return err
+	}
+	if err := checkWritable(current); err != nil {
+		return err
+	}
+	for _, p := range profiles {
+		if p.rev != "" && p.rev != current.rev {
+			return fmt.Errorf("%w — reload and try again", ErrConflict)
+		}
+	}
+	return writeStore(current, profiles)
+}
+
+// Update runs fn on the current profiles while holding the profiles.yaml
+// lock and saves the result atomically. Nothing is written if fn returns an
+// error. fn must not call other functions of this package.
+func Update(fn func(*[]Profile) error) error {
+	return update(func(_ *store, profiles *[]Profile) error {
+		return fn(profiles)
+	})
+}
+
+// update is Update with access to the raw store (for group edits).
+func update(fn func(*store, *[]Profile) error) error {
+	unlock, err := lockStore()
 	if err != nil {
 		return err
 	}
-	if err := os.MkdirAll(dir, 0700); err != nil {
-		return fmt.Errorf("create config dir: %w", err)
+	defer unlock()
+
+	current, err := readStore(true)
+	if err != nil {
+		return err
+	}
+	if err := checkWritable(current); err != nil {
+		return err
 	}
+	profiles, err := current.resolve()
+	if err != nil {
+		return err
+	}
+	if err := fn(&current, &profiles); err != nil {
+		return err
+	}
+	return writeStore(current, profiles)
+}
 
-	path := filepath.Join(dir, "profiles.yaml")
-	s := store{Profiles: profiles}
+// checkWritable refuses to overwrite a file from a newer schema.
+func checkWritable(s store) error {
+	if s.Version > SchemaVersion {
+		return fmt.Errorf("%w (file version %d, this build understands up to %d) — upgrade sshtie before changing profiles",
+			ErrNewerSchema, s.Version, SchemaVersion)
+	}
+	return nil
+}
+
+// writeStore encodes profiles against current's groups and atomically
+// replaces profiles.yaml, keeping current's top-level settings. The caller
+// must hold the lock.
+func writeStore(current store, profiles []Profile) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+
+	r, err := newResolver(current.Groups)
+	if err != nil {
+		return fmt.Errorf("parse profiles.yaml: %w", err)
+	}
+
+	s := store{
+		Version:          SchemaVersion,
+		Reconnect:        current.Reconnect,
+		RememberFailures: current.RememberFailures,
+		Groups:           current.Groups,
+		Profiles:         make([]yaml.Node, 0, len(profiles)),
+	}
+	for _, p := range profiles {
+		n, err := r.encode(p)
+		if err != nil {
+			return fmt.Errorf("marshal profiles: %w", err)
+		}
+		s.Profiles = append(s.Profiles, *n)
+	}
 
 	data, err := yaml.Marshal(&s)
 	if err != nil {
 		return fmt.Errorf("marshal profiles: %w", err)
 	}
-	return os.WriteFile(path, data, 0600)
+	return WriteFileAtomic(path, data, 0600)
 }
 
 // Get returns the profile with the given name, or an error if not found.
@@ -106,34 +351,59 @@ func Get(name string) (Profile, error) {
 
 // Add appends a new profile, returning an error if the name already exists.
 func Add(p Profile) error {
-	profiles, err := Load()
-	if err != nil {
-		return err
-	}
-	for _, existing := range profiles {
-		if existing.Name == p.Name {
-			return fmt.Errorf("profile %q already exists", p.Name)
+	return Update(func(profiles *[]Profile) error {
+		for _, existing := range *profiles {
+			if existing.Name == p.Name {
+				return fmt.Errorf("profile %q already exists", p.Name)
+			}
 		}
-	}
-	profiles = append(profiles, p)
-	return Save(profiles)
+		*profiles = append(*profiles, p)
+		return nil
+	})
 }
 
 // Rename changes a profile's name from oldName to newName.
 func Rename(oldName, newName string) error {
-	profiles, err := Load()
-	if err != nil {
-		return err
-	}
-	for _, p := range profiles {
+	return update(func(s *store, profiles *[]Profile) error {
+		return rename(s, *profiles, oldName, newName)
+	})
+}
+
+// Edit runs fn on the named profile and, when newName differs, renames it —
+// both under one hold of the profiles.yaml lock, so the file ends up with
+// all of the edit or none of it. fn must not call other functions of this
+// package.
+func Edit(name, newName string, fn func(*Profile) error) error {
+	return update(func(s *store, profiles *[]Profile) error {
+		ps := *profiles
+		for i := range ps {
+			if ps[i].Name != name {
+				continue
+			}
+			if err := fn(&ps[i]); err != nil {
+				return err
+			}
+			if newName == "" || newName == name {
+				return nil
+			}
+			return rename(s, ps, name, newName)
+		}
+		return fmt.Errorf("profile %q not found", name)
+	})
+}
+
+// rename renames oldName in ps and keeps jump chains and wake relays — in
+// profiles and in groups — pointing at it.
+func rename(s *store, ps []Profile, oldName, newName string) error {
+	for _, p := range ps {
 		if p.Name == newName {
 			return fmt.Errorf("profile %q already exists", newName)
 		}
 	}
 	found := false
-	for i, p := range profiles {
+	for i, p := range ps {
 		if p.Name == oldName {
-			profiles[i].Name = newName
+			ps[i].Name = newName
 			found = true
 			break
 		}
@@ -141,28 +411,77 @@ func Rename(oldName, newName string) error {
 	if !found {
 		return fmt.Errorf("profile %q not found", oldName)
 	}
-	return Save(profiles)
+	for i := range ps {
+		for j, hop := range ps[i].Jump {
+			if hop == oldName {
+				ps[i].Jump[j] = newName
+			}
+		}
+		if ps[i].Wake.Relay == oldName {
+			ps[i].Wake.Relay = newName
+		}
+	}
+	for i := range s.Groups {
+		if jump := mappingValue(&s.Groups[i], "jump"); jump != nil {
+			for _, hop := range jump.Content {
+				if hop.Value == oldName {
+					hop.Value = newName
+				}
+			}
+		}
+		if wake := mappingValue(&s.Groups[i], "wake"); wake != nil {
+			if relay := mappingValue(wake, "relay"); relay != nil && relay.Value == oldName {
+				relay.Value = newName
+			}
+		}
+	}
+	return nil
 }
 
-// Remove deletes a profile by name.
+// Remove deletes a profile by name. It refuses while a profile or group
+// still names it as a jump host or wake relay.
 func Remove(name string) error {
-	profiles, err := Load()
-	if err != nil {
-		return err
-	}
-	next := profiles[:0]
-	found := false
-	for _, p := range profiles {
-		if p.Name == name {
-			found = true
-			continue
+	return update(func(s *store, profiles *[]Profile) error {
+		for _, p := range *profiles {
+			for _, hop := range p.Jump {
+				if hop == name && p.Name != name {
+					return fmt.Errorf("profile %q is used as a jump host by %q", name, p.Name)
+				}
+			}
+			if p.Wake.Relay == name && p.Name != name {
+				return fmt.Errorf("profile %q is used as the wake relay of %q", name, p.Name)
+			}
 		}
-		next = append(next, p)
-	}
-	if !found {
-		return fmt.Errorf("profile %q not found", name)
-	}
-	return Save(next)
+		// A group nothing extends yet still counts: the next profile to
+		// extend it would fail to connect.
+		for i := range s.Groups {
+			g := &s.Groups[i]
+			if jump := mappingValue(g, "jump"); jump != nil {
+				for _, hop := range jump.Content {
+					if hop.Value == name {
+						return fmt.Errorf("profile %q is used as a jump host by group %q", name, scalarField(g, "name"))
+					}
+				}
+			}
+			if wake := mappingValue(g, "wake"); wake != nil && scalarField(wake, "relay") == name {
+				return fmt.Errorf("profile %q is used as the wake relay of group %q", name, scalarField(g, "name"))
+			}
+		}
+		next := (*profiles)[:0]
+		found := false
+		for _, p := range *profiles {
+			if p.Name == name {
+				found = true
+				continue
+			}
+			next = append(next, p)
+		}
+		if !found {
+			return fmt.Errorf("profile %q not found", name)
+		}
+		*profiles = next
+		return nil
+	})
 }
 
 // DefaultKey returns the expanded path for key, falling back to ~/.ssh/id_ed25519.
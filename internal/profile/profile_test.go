@@ -1,8 +1,17 @@
 package profile
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestAddGetRemove(t *testing.T) {
@@ -134,6 +143,681 @@ func TestMultipleProfiles_order(t *testing.T) {
 	}
 }
 
+func TestResolveJumpChain(t *testing.T) {
+	all := []Profile{
+		{Name: "edge", Host: "edge.example.com", User: "ops"},
+		{Name: "inner", Host: "10.0.0.2", User: "ops", Port: 2222, Jump: []string{"edge"}},
+		{Name: "db", Host: "10.0.1.5", User: "alice", Jump: []string{"inner", "bob@10.0.1.1:2200"}},
+	}
+
+	hops, err := ResolveJumpChain(all[2], all)
+	if err != nil {
+		t.Fatalf("ResolveJumpChain: %v", err)
+	}
+	want := "ops@edge.example.com,ops@10.0.0.2:2222,bob@10.0.1.1:2200"
+	if got := ProxyJump(hops); got != want {
+		t.Errorf("ProxyJump: got %q, want %q", got, want)
+	}
+	if host, port := EntryPoint(all[2], hops); host != "edge.example.com" || port != 22 {
+		t.Errorf("EntryPoint: got %s:%d", host, port)
+	}
+	if host, port := EntryPoint(all[0], nil); host != "edge.example.com" || port != 22 {
+		t.Errorf("EntryPoint without jump: got %s:%d", host, port)
+	}
+}
+
+func TestResolveJumpChain_cycle(t *testing.T) {
+	all := []Profile{
+		{Name: "a", Host: "a", Jump: []string{"b"}},
+		{Name: "b", Host: "b", Jump: []string{"a"}},
+	}
+	if _, err := ResolveJumpChain(all[0], all); err == nil {
+		t.Error("expected error for cyclic jump chain")
+	}
+}
+
+func TestParseHop(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"bastion.example.com", "bastion.example.com", true},
+		{"ops@bastion:2222", "ops@bastion:2222", true},
+		{"ops@[2001:db8::1]:2222", "ops@[2001:db8::1]:2222", true},
+		{"host:22", "host", true},
+		{"host:notaport", "", false},
+		{"ops@", "", false},
+	}
+	for _, c := range cases {
+		h, err := ParseHop(c.in)
+		if (err == nil) != c.ok {
+			t.Errorf("ParseHop(%q) err = %v, want ok=%v", c.in, err, c.ok)
+			continue
+		}
+		if c.ok && h.String() != c.want {
+			t.Errorf("ParseHop(%q) = %q, want %q", c.in, h.String(), c.want)
+		}
+	}
+}
+
+func TestRenameRemove_jumpReferences(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("HOME", tmp)
+
+	_ = Add(Profile{Name: "bastion", Host: "b", User: "u"})
+	_ = Add(Profile{Name: "app", Host: "a", User: "u", Jump: []string{"bastion"}})
+
+	if err := Remove("bastion"); err == nil {
+		t.Error("Remove should refuse a profile used as a jump host")
+	}
+	if err := Rename("bastion", "edge"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	app, err := Get("app")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(app.Jump) != 1 || app.Jump[0] != "edge" {
+		t.Errorf("Jump after rename: got %v, want [edge]", app.Jump)
+	}
+}
+
+func TestRemove_groupReferences(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeProfilesYAML(t, `groups:
+  - name: eu
+    jump: [bastion]
+  - name: lab
+    wake:
+      mac: 3c:22:fb:12:34:56
+      relay: nas
+profiles:
+  - name: bastion
+    host: b
+  - name: nas
+    host: n
+  - name: spare
+    host: s
+`)
+	// No profile extends either group.
+	if err := Remove("bastion"); err == nil || !strings.Contains(err.Error(), `group "eu"`) {
+		t.Errorf("Remove(bastion) = %v, want it refused for group eu", err)
+	}
+	if err := Remove("nas"); err == nil || !strings.Contains(err.Error(), `group "lab"`) {
+		t.Errorf("Remove(nas) = %v, want it refused for group lab", err)
+	}
+	if err := Remove("spare"); err != nil {
+		t.Errorf("Remove(spare): %v", err)
+	}
+}
+
+func TestRenameRemove_wakeRelay(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	_ = Add(Profile{Name: "nas", Host: "n", User: "u"})
+	_ = Add(Profile{Name: "lab", Host: "l", User: "u", Wake: Wake{MAC: "3c:22:fb:12:34:56", Relay: "nas"}})
+
+	if err := Remove("nas"); err == nil {
+		t.Error("Remove should refuse a profile used as a wake relay")
+	}
+	if err := Rename("nas", "nas2"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	lab, err := Get("lab")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if lab.Wake.Relay != "nas2" || lab.Wake.MAC != "3c:22:fb:12:34:56" {
+		t.Errorf("Wake after rename: %+v", lab.Wake)
+	}
+}
+
+func TestEdit_renameIsAllOrNothing(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	_ = Add(Profile{Name: "web", Host: "w", User: "u"})
+	_ = Add(Profile{Name: "db", Host: "d", User: "u"})
+
+	// The new name is taken: the option change must not be saved either.
+	err := Edit("web", "db", func(p *Profile) error {
+		p.ConnectionAttempts = 7
+		return nil
+	})
+	if err == nil {
+		t.Fatal("Edit onto an existing name: want error")
+	}
+	if web, _ := Get("web"); web.ConnectionAttempts != 0 {
+		t.Errorf("ConnectionAttempts = %d after a failed edit, want 0", web.ConnectionAttempts)
+	}
+
+	if err := Edit("web", "www", func(p *Profile) error {
+		p.ConnectionAttempts = 7
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if www, err := Get("www"); err != nil || www.ConnectionAttempts != 7 {
+		t.Errorf("after edit: %+v, %v", www, err)
+	}
+}
+
+const inheritYAML = `groups:
+  - name: base
+    user: deploy
+    port: 2222
+    forward_agent: true
+    server_alive_interval: 30
+  - name: eu
+    extends: base
+    jump: [bastion-eu]
+profiles:
+  - name: web1
+    extends: eu
+    host: 10.0.0.1
+  - name: web2
+    extends: eu
+    host: 10.0.0.2
+    forward_agent: false
+`
+
+func writeProfilesYAML(t *testing.T, content string) string {
+	t.Helper()
+	dir, err := ConfigDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "profiles.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestInheritance_resolve(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeProfilesYAML(t, inheritYAML)
+
+	web1, err := Get("web1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if web1.User != "deploy" || web1.Port != 2222 || !web1.ForwardAgent || web1.ServerAliveInterval != 30 {
+		t.Errorf("web1 not resolved from groups: %+v", web1)
+	}
+	if len(web1.Jump) != 1 || web1.Jump[0] != "bastion-eu" {
+		t.Errorf("web1 jump: got %v", web1.Jump)
+	}
+	if got := web1.Source("user"); got != "base" {
+		t.Errorf("Source(user): got %q, want base", got)
+	}
+	if got := web1.Source("jump"); got != "eu" {
+		t.Errorf("Source(jump): got %q, want eu", got)
+	}
+	if got := web1.Source("host"); got != "web1" {
+		t.Errorf("Source(host): got %q, want web1", got)
+	}
+
+	// An explicit false overrides the group's true.
+	web2, _ := Get("web2")
+	if web2.ForwardAgent {
+		t.Error("web2 forward_agent: explicit false should win over group")
+	}
+}
+
+func TestInheritance_saveDoesNotFlatten(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := writeProfilesYAML(t, inheritYAML)
+
+	profiles, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	profiles[0].ServerAliveInterval = 60 // change one value on web1
+	if err := Save(profiles); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	var raw struct {
+		Groups   []map[string]interface{} `yaml:"groups"`
+		Profiles []map[string]interface{} `yaml:"profiles"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(raw.Groups) != 2 {
+		t.Fatalf("groups lost on save: %s", data)
+	}
+	web1 := raw.Profiles[0]
+	if _, ok := web1["user"]; ok {
+		t.Errorf("inherited user was flattened into web1: %v", web1)
+	}
+	if web1["server_alive_interval"] != 60 {
+		t.Errorf("changed value not written to web1: %v", web1)
+	}
+	if web2 := raw.Profiles[1]; web2["forward_agent"] != false {
+		t.Errorf("explicit override lost on web2: %v", web2)
+	}
+
+	// Resolved view is unchanged apart from the edit.
+	got, _ := Get("web1")
+	if got.User != "deploy" || got.ServerAliveInterval != 60 {
+		t.Errorf("web1 after save: %+v", got)
+	}
+}
+
+func TestInheritance_unknownGroup(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeProfilesYAML(t, "profiles:\n  - name: x\n    extends: nope\n    host: h\n")
+	if _, err := Load(); err == nil {
+		t.Error("expected error for unknown group")
+	}
+}
+
+func TestSchema_migratesV1(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	v1 := "profiles:\n  - name: old\n    host: h\n    user: u\n    port: 0\n    tmux_session: \"\"\n    network: \"\"\n"
+	path := writeProfilesYAML(t, v1)
+
+	if _, err := Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	bak, err := os.ReadFile(backupPath(path, 1))
+	if err != nil {
+		t.Fatalf("backup not written: %v", err)
+	}
+	if string(bak) != v1 {
+		t.Errorf("backup differs from original:\n%s", bak)
+	}
+
+	var raw struct {
+		Version  int                      `yaml:"version"`
+		Profiles []map[string]interface{} `yaml:"profiles"`
+	}
+	data, _ := os.ReadFile(path)
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if raw.Version != SchemaVersion {
+		t.Errorf("version: got %d, want %d", raw.Version, SchemaVersion)
+	}
+	if _, ok := raw.Profiles[0]["port"]; ok {
+		t.Errorf("port: 0 placeholder should be dropped: %v", raw.Profiles[0])
+	}
+	if raw.Profiles[0]["user"] != "u" {
+		t.Errorf("real values must survive migration: %v", raw.Profiles[0])
+	}
+}
+
+func TestSchema_refusesNewer(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	newer := "version: 99\nprofiles:\n  - name: n\n    host: h\n    future_field: keep-me\n"
+	path := writeProfilesYAML(t, newer)
+
+	profiles, err := Load()
+	if err != nil {
+		t.Fatalf("Load should still read a newer file: %v", err)
+	}
+	if err := Save(profiles); !errors.Is(err, ErrNewerSchema) {
+		t.Errorf("Save: got %v, want ErrNewerSchema", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != newer {
+		t.Error("newer file must not be rewritten")
+	}
+}
+
+func TestSave_keepsUnknownFields(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := writeProfilesYAML(t, "version: 2\nprofiles:\n  - name: n\n    host: h\n    future_field: keep-me\n")
+
+	profiles, _ := Load()
+	profiles[0].User = "alice"
+	if err := Save(profiles); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "future_field: keep-me") {
+		t.Errorf("unknown field dropped:\n%s", data)
+	}
+}
+
+func TestUpdate_appliesChanges(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := Add(Profile{Name: "a", Host: "h"}); err != nil {
+		t.Fatal(err)
+	}
+	err := Update(func(profiles *[]Profile) error {
+		(*profiles)[0].User = "alice"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, _ := Get("a")
+	if got.User != "alice" {
+		t.Errorf("User = %q, want alice", got.User)
+	}
+
+	// An error from fn leaves the file untouched.
+	boom := errors.New("boom")
+	err = Update(func(profiles *[]Profile) error {
+		(*profiles)[0].User = "mallory"
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("Update: got %v, want boom", err)
+	}
+	if got, _ := Get("a"); got.User != "alice" {
+		t.Errorf("failed Update was written: User = %q", got.User)
+	}
+}
+
+func TestUpdate_concurrent(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- Add(Profile{Name: fmt.Sprintf("p%d", i), Host: "h"})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	profiles, _ := Load()
+	if len(profiles) != n {
+		t.Errorf("got %d profiles, want %d (lost update)", len(profiles), n)
+	}
+}
+
+func TestSave_conflict(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := Add(Profile{Name: "a", Host: "h"}); err != nil {
+		t.Fatal(err)
+	}
+	stale, _ := Load()
+	if err := Add(Profile{Name: "b", Host: "h"}); err != nil {
+		t.Fatal(err)
+	}
+	stale[0].User = "alice"
+	if err := Save(stale); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Save: got %v, want ErrConflict", err)
+	}
+	if _, err := Get("b"); err != nil {
+		t.Error("conflicting Save dropped profile b")
+	}
+}
+
+func TestSave_noTempFilesLeft(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("HOME", tmp)
+	for _, name := range []string{"a", "b", "c"} {
+		if err := Add(Profile{Name: name, Host: "h"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, _ := os.ReadDir(filepath.Join(tmp, ".sshtie"))
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".tmp") {
+			t.Errorf("leftover temp file %s", e.Name())
+		}
+	}
+}
+
+func TestParseForward(t *testing.T) {
+	cases := []struct {
+		in      string
+		args    []string
+		config  string
+		wantErr bool
+	}{
+		{in: "L5432:localhost:5432", args: []string{"-L", "5432:localhost:5432"}, config: "LocalForward 5432 localhost:5432"},
+		{in: "L127.0.0.1:8080:web:80", args: []string{"-L", "127.0.0.1:8080:web:80"}, config: "LocalForward 127.0.0.1:8080 web:80"},
+		{in: "L[::1]:8080:[fd00::2]:80", args: []string{"-L", "[::1]:8080:[fd00::2]:80"}, config: "LocalForward [::1]:8080 [fd00::2]:80"},
+		{in: "R8080:localhost:3000", args: []string{"-R", "8080:localhost:3000"}, config: "RemoteForward 8080 localhost:3000"},
+		{in: "R9000", args: []string{"-R", "9000"}, config: "RemoteForward 9000"},
+		{in: "D1080", args: []string{"-D", "1080"}, config: "DynamicForward 1080"},
+		{in: "L5432", wantErr: true},
+		{in: "Lx:localhost:5432", wantErr: true},
+		{in: "D70000", wantErr: true},
+		{in: "X1080", wantErr: true},
+	}
+	for _, c := range cases {
+		f, err := ParseForward(c.in)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("ParseForward(%q): expected error", c.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseForward(%q): %v", c.in, err)
+			continue
+		}
+		if got := f.Args(); strings.Join(got, " ") != strings.Join(c.args, " ") {
+			t.Errorf("ParseForward(%q).Args() = %v, want %v", c.in, got, c.args)
+		}
+		if got := f.ConfigLine(); got != c.config {
+			t.Errorf("ParseForward(%q).ConfigLine() = %q, want %q", c.in, got, c.config)
+		}
+		if got := f.String(); got != c.in {
+			t.Errorf("String() = %q, want %q", got, c.in)
+		}
+	}
+}
+
+func TestForwards_savedAndLoaded(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	want := []Forward{{Local: "5432:localhost:5432"}, {Dynamic: "1080"}}
+	if err := Add(Profile{Name: "db", Host: "h", Forwards: want}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Get("db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Forwards) != 2 || got.Forwards[0] != want[0] || got.Forwards[1] != want[1] {
+		t.Errorf("Forwards = %+v, want %+v", got.Forwards, want)
+	}
+	if args := strings.Join(got.ForwardArgs(), " "); args != "-L 5432:localhost:5432 -D 1080" {
+		t.Errorf("ForwardArgs() = %q", args)
+	}
+}
+
+func TestLayout_savedAndLoaded(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	want := []Window{
+		{Name: "logs", Dir: "/var/log", Panes: []Pane{{Command: "tail -f syslog"}, {Split: "down", Command: "htop"}}},
+		{Name: "shell"},
+	}
+	if err := Add(Profile{Name: "web", Host: "h", Multiplexer: "tmux", Layout: want}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Get("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Layout, want) || got.Multiplexer != "tmux" {
+		t.Errorf("Layout = %+v, want %+v", got.Layout, want)
+	}
+	if err := ValidateLayout([]Window{{Panes: []Pane{{Split: "up"}}}}); err == nil {
+		t.Error("split up: want error")
+	}
+}
+
+func TestTransport_savedAndValidated(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	want := Transport{Type: TransportIAP, Instance: "build-vm-1", Zone: "europe-west1-b", Project: "ci"}
+	if err := Add(Profile{Name: "build", Host: "build-vm-1", Transport: want}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Get("build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Transport != want || ValidateTransport(got) != nil {
+		t.Errorf("Transport = %+v (%v), want %+v", got.Transport, ValidateTransport(got), want)
+	}
+
+	for _, bad := range []Profile{
+		{Name: "a", Transport: Transport{Type: "azure-bastion", Instance: "x"}},
+		{Name: "b", Transport: Transport{Type: TransportSSM}},
+		{Name: "c", Transport: Transport{Type: TransportIAP, Instance: "vm"}},                     // no zone
+		{Name: "d", Transport: Transport{Type: TransportSSM, Instance: "i-1", Zone: "eu-west-1"}}, // wrong setting
+		{Name: "e", Transport: Transport{Type: TransportSSM, Instance: "i-1 ; rm"}},
+		{Name: "g", Transport: Transport{Type: TransportSSM, Instance: "i-1;id"}},
+		{Name: "h", Transport: Transport{Type: TransportSSM, Instance: "i-1", Region: "eu-west-1$(id)"}},
+		{Name: "i", Transport: Transport{Type: TransportIAP, Instance: "vm", Zone: "a", Project: "p%h"}},
+		{Name: "j", Transport: Transport{Type: TransportSSM, Instance: "i-1", Region: "--profile=x"}},
+		{Name: "k", Transport: Transport{Type: TransportIAP, Instance: "-vm", Zone: "a"}},
+		{Name: "f", Transport: Transport{Type: TransportSSM, Instance: "i-1"}, Jump: []string{"bastion"}},
+	} {
+		if err := ValidateTransport(bad); err == nil {
+			t.Errorf("%s: %+v should not validate", bad.Name, bad.Transport)
+		}
+	}
+	if err := ValidateTransport(Profile{Name: "plain"}); err != nil {
+		t.Errorf("no transport: %v", err)
+	}
+}
+
+func TestAddresses_candidates(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	lan := Address{Host: "192.168.1.10", Subnet: "192.168.1.0/24"}
+	ts := Address{Host: "home.tail-xyz.ts.net", Tailscale: true}
+	if err := Add(Profile{Name: "home", Host: "home.example.net", Addresses: []Address{lan, ts}}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Get("home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := got.Candidates()
+	if len(c) != 3 || c[0] != lan || c[1] != ts || c[2] != (Address{Host: "home.example.net"}) {
+		t.Errorf("Candidates() = %+v", c)
+	}
+
+	// Host already listed: not repeated.
+	got.Addresses = append(got.Addresses, Address{Host: "home.example.net"})
+	if n := len(got.Candidates()); n != 3 {
+		t.Errorf("Candidates() has %d entries, want 3", n)
+	}
+
+	if err := (Address{Host: "x", Subnet: "192.168.1.10"}).Validate(); err == nil {
+		t.Error("subnet without prefix length should not validate")
+	}
+}
+
+func TestReconnectPolicy(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := writeProfilesYAML(t, `version: 2
+reconnect:
+  max_attempts: -1
+  backoff: 2s
+profiles:
+  - name: a
+    host: h
+    reconnect:
+      backoff: 5s
+      deadline: 10m
+  - name: b
+    host: h
+`)
+	a, err := Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pol, err := ReconnectPolicy(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Reconnect{MaxAttempts: -1, Backoff: 5 * time.Second, MaxBackoff: DefaultMaxBackoff,
+		Deadline: 10 * time.Minute, StartupThreshold: DefaultStartupThreshold}
+	if pol != want {
+		t.Errorf("policy(a) = %+v, want %+v", pol, want)
+	}
+	b, _ := Get("b")
+	if pol, _ := ReconnectPolicy(b); pol.Backoff != 2*time.Second || !pol.Unlimited() {
+		t.Errorf("policy(b) = %+v, want global backoff and unlimited attempts", pol)
+	}
+
+	// Saving a profile keeps the global block and writes durations readably.
+	if err := Update(func(ps *[]Profile) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"max_attempts: -1", "backoff: 2s", "deadline: 10m0s"} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("saved file lacks %q:\n%s", s, data)
+		}
+	}
+}
+
+func TestReconnectPolicy_mergesPerField(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := writeProfilesYAML(t, `version: 2
+reconnect:
+  startup_threshold: 5s
+groups:
+  - name: laptop
+    reconnect:
+      backoff: 2s
+      deadline: 30m
+profiles:
+  - name: dev
+    host: h
+    extends: laptop
+    reconnect:
+      max_attempts: 3
+`)
+	dev, err := Get("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pol, err := ReconnectPolicy(dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Reconnect{MaxAttempts: 3, Backoff: 2 * time.Second, MaxBackoff: DefaultMaxBackoff,
+		Deadline: 30 * time.Minute, StartupThreshold: 5 * time.Second}
+	if pol != want {
+		t.Errorf("policy = %+v, want %+v", pol, want)
+	}
+	if src := dev.Source("reconnect"); src != "dev" {
+		t.Errorf("Source(reconnect) = %q, want dev", src)
+	}
+
+	// Saving keeps only the profile's own key, so the group still decides
+	// the rest.
+	if err := Update(func(ps *[]Profile) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "deadline: 30m"); n != 1 {
+		t.Errorf("deadline written %d times, want once (in the group):\n%s", n, data)
+	}
+	if !strings.Contains(string(data), "max_attempts: 3") {
+		t.Errorf("saved file lost the profile's max_attempts:\n%s", data)
+	}
+}
+
 // Ensure HOME is always set (belt-and-suspenders for CI).
 func TestMain(m *testing.M) {
 	os.Exit(m.Run())
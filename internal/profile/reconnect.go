@@ -0,0 +1,121 @@
+package profile
+
+import (
+	"fmt"
+	"time"
+)
+
+// Reconnect is the policy for re-establishing a dropped session. It can be
+// set per profile (or group) and as a global default at the top level of
+// profiles.yaml; unset fields fall through profile → group → global →
+// built-in, one by one:
+//
+//	reconnect:                  # global default
+//	  max_attempts: -1          # unlimited
+//	profiles:
+//	  - name: laptop-dev
+//	    reconnect:
+//	      backoff: 2s
+//	      max_backoff: 1m
+//	      deadline: 30m
+type Reconnect struct {
+	// MaxAttempts caps reconnect attempts; negative means unlimited.
+	MaxAttempts int `yaml:"max_attempts,omitempty"`
+	// Backoff is the first delay between network polls; it doubles (with
+	// jitter) up to MaxBackoff.
+	Backoff    time.Duration `yaml:"backoff,omitempty"`
+	MaxBackoff time.Duration `yaml:"max_backoff,omitempty"`
+	// Deadline gives up reconnecting this long after the session first
+	// dropped, however many attempts and outages that spanned. Zero keeps
+	// going until MaxAttempts.
+	Deadline time.Duration `yaml:"deadline,omitempty"`
+	// StartupThreshold: a session that exits sooner than this never really
+	// came up (auth refused, tool missing) and is not retried.
+	StartupThreshold time.Duration `yaml:"startup_threshold,omitempty"`
+}
+
+// Built-in reconnect defaults.
+const (
+	DefaultMaxAttempts      = 10
+	DefaultBackoff          = 1 * time.Second
+	DefaultMaxBackoff       = 30 * time.Second
+	DefaultStartupThreshold = 2 * time.Second
+)
+
+// Unlimited reports whether r retries forever.
+func (r Reconnect) Unlimited() bool { return r.MaxAttempts < 0 }
+
+// Merge fills r's unset fields from fallback.
+func (r Reconnect) Merge(fallback Reconnect) Reconnect {
+	if r.MaxAttempts == 0 {
+		r.MaxAttempts = fallback.MaxAttempts
+	}
+	if r.Backoff == 0 {
+		r.Backoff = fallback.Backoff
+	}
+	if r.MaxBackoff == 0 {
+		r.MaxBackoff = fallback.MaxBackoff
+	}
+	if r.Deadline == 0 {
+		r.Deadline = fallback.Deadline
+	}
+	if r.StartupThreshold == 0 {
+		r.StartupThreshold = fallback.StartupThreshold
+	}
+	return r
+}
+
+// WithDefaults fills r's unset fields from the built-in defaults.
+func (r Reconnect) WithDefaults() Reconnect {
+	r = r.Merge(Reconnect{
+		MaxAttempts:      DefaultMaxAttempts,
+		Backoff:          DefaultBackoff,
+		MaxBackoff:       DefaultMaxBackoff,
+		StartupThreshold: DefaultStartupThreshold,
+	})
+	if r.MaxBackoff < r.Backoff {
+		r.MaxBackoff = r.Backoff
+	}
+	return r
+}
+
+// Validate rejects negative durations.
+func (r Reconnect) Validate() error {
+	for _, d := range []struct {
+		key string
+		v   time.Duration
+	}{
+		{"backoff", r.Backoff},
+		{"max_backoff", r.MaxBackoff},
+		{"deadline", r.Deadline},
+		{"startup_threshold", r.StartupThreshold},
+	} {
+		if d.v < 0 {
+			return fmt.Errorf("reconnect.%s must not be negative", d.key)
+		}
+	}
+	return nil
+}
+
+// GlobalReconnect returns the top-level `reconnect:` block of profiles.yaml.
+func GlobalReconnect() (Reconnect, error) {
+	s, err := readStoreLocked()
+	if err != nil {
+		return Reconnect{}, err
+	}
+	return s.Reconnect, nil
+}
+
+// ReconnectPolicy resolves p's effective reconnect policy: the profile's own
+// settings, then the global default, then the built-ins.
+func ReconnectPolicy(p Profile) (Reconnect, error) {
+	global, err := GlobalReconnect()
+	if err != nil {
+		return Reconnect{}.WithDefaults(), err
+	}
+	pol := p.Reconnect.Merge(global).WithDefaults()
+	if err := pol.Validate(); err != nil {
+		return Reconnect{}.WithDefaults(), fmt.Errorf("profile %q: %w", p.Name, err)
+	}
+	return pol, nil
+}
@@ -0,0 +1,129 @@
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion is the profiles.yaml layout this build reads and writes.
+// Files without a version key predate versioning and count as version 1.
+//
+//	1 — original flat list of profiles
+//	2 — groups / extends; unset fields are omitted instead of written as zero
+const SchemaVersion = 2
+
+// ErrNewerSchema is returned by Save when profiles.yaml was written by a
+// newer sshtie than this one. Rewriting it would drop fields this build
+// doesn't know about.
+var ErrNewerSchema = errors.New("profiles.yaml was written by a newer sshtie")
+
+// migration upgrades a parsed profiles.yaml document from version from to
+// from+1, in place.
+type migration struct {
+	from  int
+	apply func(root *yaml.Node) error
+}
+
+var migrations = []migration{
+	{from: 1, apply: migrateV1toV2},
+}
+
+// migrateV1toV2 drops the zero-value placeholders v1 wrote for every profile
+// (port: 0, user: "", …). Under v2 an explicit key overrides the group it
+// extends, so leaving them would shadow inherited values.
+func migrateV1toV2(root *yaml.Node) error {
+	list := mappingValue(root, "profiles")
+	if list == nil {
+		return nil
+	}
+	placeholders := map[string]string{
+		"user":         "",
+		"port":         "0",
+		"tmux_session": "",
+		"network":      "",
+	}
+	for _, entry := range list.Content {
+		if entry.Kind != yaml.MappingNode {
+			continue
+		}
+		kept := entry.Content[:0]
+		for i := 0; i+1 < len(entry.Content); i += 2 {
+			k, v := entry.Content[i], entry.Content[i+1]
+			if zero, ok := placeholders[k.Value]; ok && v.Kind == yaml.ScalarNode && v.Value == zero {
+				continue
+			}
+			kept = append(kept, k, v)
+		}
+		entry.Content = kept
+	}
+	return nil
+}
+
+// fileVersion reads the version key from a parsed document (1 if absent).
+func fileVersion(root *yaml.Node) (int, error) {
+	v := scalarField(root, "version")
+	if v == "" {
+		return 1, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid version %q", v)
+	}
+	return n, nil
+}
+
+// setVersion writes (or adds) the version key at the top of the document.
+func setVersion(root *yaml.Node, version int) {
+	val := strconv.Itoa(version)
+	if v := mappingValue(root, "version"); v != nil {
+		v.Value = val
+		v.Tag = "!!int"
+		return
+	}
+	root.Content = append([]*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"},
+		{Kind: yaml.ScalarNode, Tag: "!!int", Value: val},
+	}, root.Content...)
+}
+
+// migrate upgrades root to SchemaVersion. It reports whether anything changed.
+func migrate(root *yaml.Node) (bool, error) {
+	version, err := fileVersion(root)
+	if err != nil {
+		return false, err
+	}
+	if version >= SchemaVersion {
+		return false, nil
+	}
+	for _, m := range migrations {
+		if m.from < version {
+			continue
+		}
+		if err := m.apply(root); err != nil {
+			return false, fmt.Errorf("migrate v%d → v%d: %w", m.from, m.from+1, err)
+		}
+		version = m.from + 1
+	}
+	setVersion(root, version)
+	return true, nil
+}
+
+// backupPath returns where the pre-migration copy of a file is kept,
+// e.g. profiles.yaml → profiles.yaml.v1.bak.
+func backupPath(path string, version int) string {
+	return fmt.Sprintf("%s.v%d.bak", path, version)
+}
+
+// writeBackup stores the original bytes before a migration rewrites them.
+// An existing backup for the same version is left alone — it is the oldest copy.
+func writeBackup(path string, version int, data []byte) error {
+	bak := backupPath(path, version)
+	if _, err := os.Stat(bak); err == nil {
+		return nil
+	}
+	return os.WriteFile(bak, data, 0600)
+}
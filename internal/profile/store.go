@@ -0,0 +1,117 @@
+package profile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Concurrency
+//
+// The CLI and the tray app both edit profiles.yaml. Every write happens
+// under an advisory lock on profiles.yaml.lock and replaces the file
+// atomically (temp file + rename), so a crash never leaves it truncated.
+// Profiles remember the revision of the file they were loaded from; Save
+// refuses to overwrite a file that changed in the meantime. Update runs the
+// whole read-modify-write cycle under the lock and is what callers should use.
+// Other files under ~/.sshtie that both processes write reuse the same
+// scheme through LockFile and WriteFileAtomic.
+
+// ErrConflict is returned by Save when profiles.yaml changed on disk after
+// the profiles being saved were loaded.
+var ErrConflict = errors.New("profiles.yaml changed since it was loaded")
+
+// lockTimeout bounds how long a writer waits for another sshtie process.
+const lockTimeout = 10 * time.Second
+
+// lockStore takes the profiles.yaml lock.
+func lockStore() (func(), error) { return LockFile("profiles.yaml") }
+
+// LockFile takes the advisory lock for the named file in the config dir
+// (name + ".lock"), creating the dir if needed, and waits up to 10 s for
+// another sshtie process to let go of it. The returned func releases it.
+func LockFile(name string) (func(), error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create config dir: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, name+".lock"), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("open lock file: %w", err)
+	}
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		ok, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("lock %s: %w", name, err)
+		}
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("%s is locked by another sshtie process", name)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return func() {
+		_ = unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// revision fingerprints the raw bytes of profiles.yaml.
+func revision(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// WriteFileAtomic replaces path with data so readers see either the old or
+// the new content, never a partial write.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	cleanup := func() { _ = os.Remove(tmpName) }
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		cleanup()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		cleanup()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		cleanup()
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		cleanup()
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		cleanup()
+		return err
+	}
+	// Persist the rename itself. Best-effort: directories can't be opened
+	// for sync on every platform.
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+	return nil
+}
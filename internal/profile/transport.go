@@ -0,0 +1,112 @@
+package profile
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Transport types.
+const (
+	TransportSSM = "aws-ssm" // AWS Systems Manager Session Manager
+	TransportIAP = "gcp-iap" // Google Cloud Identity-Aware Proxy
+)
+
+// Transports lists the accepted transport types.
+var Transports = []string{TransportSSM, TransportIAP}
+
+// Transport reaches a server that has no SSH port of its own through its
+// cloud provider's tunnel, run as ssh's ProxyCommand (see package cloud):
+//
+//	transport:
+//	  type: aws-ssm
+//	  instance: i-0123456789abcdef0
+//	  region: eu-west-1            # optional, the aws CLI's default otherwise
+//
+//	transport:
+//	  type: gcp-iap
+//	  instance: build-vm-1
+//	  zone: europe-west1-b
+//	  project: my-project          # optional, gcloud's default otherwise
+//
+// Host stays what ssh calls the server — the instance ID is fine — and
+// what its host keys are recorded under.
+type Transport struct {
+	Type     string `yaml:"type,omitempty"`
+	Instance string `yaml:"instance,omitempty"` // EC2 instance ID or GCE instance name
+	Region   string `yaml:"region,omitempty"`   // aws-ssm
+	Zone     string `yaml:"zone,omitempty"`     // gcp-iap
+	Project  string `yaml:"project,omitempty"`  // gcp-iap
+}
+
+// Enabled reports whether t is configured.
+func (t Transport) Enabled() bool { return t.Type != "" }
+
+// transportWord matches the instance IDs and names, regions, zones and
+// projects the clouds hand out. Nothing else is let through: the settings
+// end up unquoted in a ProxyCommand, which ssh expands (%h, %p, …) and runs
+// with sh -c, and one starting with '-' would reach aws or gcloud as an
+// option (--profile=…, --endpoint-url=…).
+var transportWord = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Validate checks that t names a known type and everything that type needs.
+func (t Transport) Validate() error {
+	for _, v := range []string{t.Instance, t.Region, t.Zone, t.Project} {
+		if v != "" && !transportWord.MatchString(v) {
+			return fmt.Errorf("transport: %q must start with a letter or digit and contain only letters, digits, '.', '_' and '-'", v)
+		}
+	}
+	switch t.Type {
+	case TransportSSM:
+		if t.Instance == "" {
+			return fmt.Errorf("transport aws-ssm: instance is required (e.g. i-0123456789abcdef0)")
+		}
+		if t.Zone != "" || t.Project != "" {
+			return fmt.Errorf("transport aws-ssm: zone and project are gcp-iap settings; use region")
+		}
+	case TransportIAP:
+		if t.Instance == "" {
+			return fmt.Errorf("transport gcp-iap: instance is required")
+		}
+		if t.Zone == "" {
+			return fmt.Errorf("transport gcp-iap: zone is required (e.g. europe-west1-b)")
+		}
+		if t.Region != "" {
+			return fmt.Errorf("transport gcp-iap: region is an aws-ssm setting; use zone")
+		}
+	default:
+		return fmt.Errorf("unknown transport %q (want %s)", t.Type, strings.Join(Transports, " or "))
+	}
+	return nil
+}
+
+// String describes t, e.g. "AWS SSM i-0123456789abcdef0" or
+// "IAP build-vm-1".
+func (t Transport) String() string {
+	switch t.Type {
+	case TransportSSM:
+		return "AWS SSM " + t.Instance
+	case TransportIAP:
+		return "IAP " + t.Instance
+	}
+	return t.Type
+}
+
+// ValidateTransport checks p's transport, which replaces the direct TCP
+// path to Host and so can't be combined with a jump chain or alternative
+// addresses. A profile without one is valid.
+func ValidateTransport(p Profile) error {
+	if !p.Transport.Enabled() {
+		return nil
+	}
+	if err := p.Transport.Validate(); err != nil {
+		return fmt.Errorf("profile %q: %w", p.Name, err)
+	}
+	switch {
+	case len(p.Jump) > 0:
+		return fmt.Errorf("profile %q: transport %s can't be combined with jump", p.Name, p.Transport.Type)
+	case len(p.Addresses) > 0:
+		return fmt.Errorf("profile %q: transport %s can't be combined with addresses", p.Name, p.Transport.Type)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package profile
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// Wake configures Wake-on-LAN for a server that sleeps. The magic packet is
+// broadcast from this machine, or — when this machine isn't on the server's
+// LAN — from a relay: another profile on that LAN that is always up.
+//
+//	wake:
+//	  mac: 3c:22:fb:12:34:56
+//	  broadcast: 192.168.1.255     # optional, default 255.255.255.255:9
+//	  relay: nas                   # optional, send it from this profile
+//	  timeout: 3m                  # optional, how long to wait for SSH
+type Wake struct {
+	MAC       string        `yaml:"mac,omitempty"`
+	Broadcast string        `yaml:"broadcast,omitempty"` // host[:port]; port defaults to 9
+	Relay     string        `yaml:"relay,omitempty"`     // profile that sends the packet
+	Timeout   time.Duration `yaml:"timeout,omitempty"`   // 0 = DefaultWakeTimeout
+}
+
+// DefaultWakeTimeout is how long sshtie waits for SSH after a magic packet.
+const DefaultWakeTimeout = 2 * time.Minute
+
+// Enabled reports whether w is configured.
+func (w Wake) Enabled() bool { return w.MAC != "" }
+
+// WaitFor returns how long to wait for the server to wake.
+func (w Wake) WaitFor() time.Duration {
+	if w.Timeout > 0 {
+		return w.Timeout
+	}
+	return DefaultWakeTimeout
+}
+
+// Validate checks the MAC and broadcast address.
+func (w Wake) Validate() error {
+	if !w.Enabled() {
+		return fmt.Errorf("no wake.mac set")
+	}
+	hw, err := net.ParseMAC(w.MAC)
+	if err != nil || len(hw) != 6 {
+		return fmt.Errorf("wake: invalid MAC address %q", w.MAC)
+	}
+	if w.Broadcast != "" {
+		if _, _, err := net.SplitHostPort(w.Broadcast); err != nil && net.ParseIP(w.Broadcast) == nil {
+			return fmt.Errorf("wake: invalid broadcast address %q (want IP or IP:port)", w.Broadcast)
+		}
+	}
+	return nil
+}
@@ -22,3 +22,16 @@ func IsAlive(pid int) bool {
 	err = p.Signal(syscall.Signal(0))
 	return err == nil || err == syscall.EPERM
 }
+
+// Stop asks the process to shut down with SIGTERM, giving it the chance to
+// stop its own children (a tunnel's ssh) and clean up its session file.
+func Stop(pid int) error {
+	if pid <= 0 {
+		return nil
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Signal(syscall.SIGTERM)
+}
@@ -3,6 +3,10 @@
 package session
 
 import (
+	"os/exec"
+	"strconv"
+	"syscall"
+
 	"golang.org/x/sys/windows"
 )
 
@@ -25,3 +29,14 @@ func IsAlive(pid int) bool {
 	// STILL_ACTIVE == 259
 	return code == 259
 }
+
+// Stop terminates the process together with its children (a tunnel's ssh).
+// Windows has no SIGTERM, so the whole tree is killed via taskkill.
+func Stop(pid int) error {
+	if pid <= 0 {
+		return nil
+	}
+	cmd := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(pid))
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	return cmd.Run()
+}
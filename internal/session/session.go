@@ -3,6 +3,8 @@
 // Each active connection writes a JSON file to ~/.sshtie/sessions/<name>.json.
 // Because file names map 1-to-1 with profile names, multiple servers can be
 // connected simultaneously — each has its own independent lock file.
+// Background tunnels (see MethodTunnel) use <name>.tunnel.json so a profile
+// can have a tunnel and an interactive session at the same time.
 package session
 
 import (
@@ -18,8 +20,30 @@ import (
 type Session struct {
 	Profile   string    `json:"profile"`
 	PID       int       `json:"pid"`
-	Method    string    `json:"method"` // "mosh", "ssh+tmux", "ssh"
+	Method    string    `json:"method"` // strategy as shown ("mosh+tmux", "ssh+screen", "ssh", …) or "tunnel"
 	StartedAt time.Time `json:"started_at"`
+	Forwards  []string  `json:"forwards,omitempty"` // tunnels only, e.g. "L5432:localhost:5432"
+}
+
+// MethodTunnel marks a background `ssh -N` port-forward tunnel rather than
+// an interactive session.
+const MethodTunnel = "tunnel"
+
+// IsTunnel reports whether s is a background tunnel.
+func (s Session) IsTunnel() bool {
+	return s.Method == MethodTunnel
+}
+
+// fileKey returns the lock file name (without extension) for s.
+func (s Session) fileKey() string {
+	if s.IsTunnel() {
+		return tunnelKey(s.Profile)
+	}
+	return s.Profile
+}
+
+func tunnelKey(name string) string {
+	return name + ".tunnel"
 }
 
 // SessionDir returns the directory that stores session lock files.
@@ -36,18 +60,19 @@ func SessionDir() (string, error) {
 	return d, nil
 }
 
-// lockPath returns the path to the lock file for the given profile name.
-func lockPath(name string) (string, error) {
+// lockPath returns the path to the lock file for the given key (a profile
+// name, or tunnelKey of one).
+func lockPath(key string) (string, error) {
 	dir, err := SessionDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(dir, name+".json"), nil
+	return filepath.Join(dir, key+".json"), nil
 }
 
 // Write creates (or overwrites) the session lock file for this session.
 func Write(s Session) error {
-	path, err := lockPath(s.Profile)
+	path, err := lockPath(s.fileKey())
 	if err != nil {
 		return err
 	}
@@ -61,7 +86,17 @@ func Write(s Session) error {
 // Read loads the session file for the named profile.
 // Returns os.ErrNotExist if the file is not present.
 func Read(name string) (Session, error) {
-	path, err := lockPath(name)
+	return read(name)
+}
+
+// ReadTunnel loads the background tunnel file for the named profile.
+// Returns os.ErrNotExist if the file is not present.
+func ReadTunnel(name string) (Session, error) {
+	return read(tunnelKey(name))
+}
+
+func read(key string) (Session, error) {
+	path, err := lockPath(key)
 	if err != nil {
 		return Session{}, err
 	}
@@ -79,7 +114,16 @@ func Read(name string) (Session, error) {
 // Delete removes the session lock file for the named profile.
 // It is a no-op if the file does not exist.
 func Delete(name string) error {
-	path, err := lockPath(name)
+	return remove(name)
+}
+
+// DeleteTunnel removes the background tunnel file for the named profile.
+func DeleteTunnel(name string) error {
+	return remove(tunnelKey(name))
+}
+
+func remove(key string) error {
+	path, err := lockPath(key)
 	if err != nil {
 		return err
 	}
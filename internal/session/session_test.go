@@ -93,3 +93,40 @@ func TestIsAlive_deadPID(t *testing.T) {
 		t.Error("IsAlive(999999999) should return false")
 	}
 }
+
+func TestTunnel_separateFromSession(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("HOME", tmp)
+
+	pid := os.Getpid()
+	if err := Write(Session{Profile: "db", PID: pid, Method: "ssh"}); err != nil {
+		t.Fatalf("Write session: %v", err)
+	}
+	tun := Session{Profile: "db", PID: pid, Method: MethodTunnel, Forwards: []string{"L5432:localhost:5432"}}
+	if err := Write(tun); err != nil {
+		t.Fatalf("Write tunnel: %v", err)
+	}
+
+	got, err := ReadTunnel("db")
+	if err != nil {
+		t.Fatalf("ReadTunnel: %v", err)
+	}
+	if !got.IsTunnel() || len(got.Forwards) != 1 {
+		t.Errorf("ReadTunnel: got %+v", got)
+	}
+	if s, _ := Read("db"); s.IsTunnel() {
+		t.Error("Read returned the tunnel instead of the session")
+	}
+
+	active, _ := ListActive()
+	if len(active) != 2 {
+		t.Errorf("expected session + tunnel, got %d entries", len(active))
+	}
+
+	if err := DeleteTunnel("db"); err != nil {
+		t.Fatalf("DeleteTunnel: %v", err)
+	}
+	if _, err := Read("db"); err != nil {
+		t.Errorf("DeleteTunnel removed the session too: %v", err)
+	}
+}
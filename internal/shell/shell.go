@@ -0,0 +1,34 @@
+// Package shell quotes words for POSIX shell command lines.
+package shell
+
+import "strings"
+
+// Quote returns s as one shell word, in single quotes unless every
+// character is one the shell takes literally.
+func Quote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	safe := true
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+			strings.ContainsRune("-_./:@%+=,", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Join renders argv as a single command line, quoting only the words that
+// need it.
+func Join(argv []string) string {
+	out := make([]string, len(argv))
+	for i, a := range argv {
+		out[i] = Quote(a)
+	}
+	return strings.Join(out, " ")
+}
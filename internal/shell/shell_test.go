@@ -0,0 +1,26 @@
+package shell
+
+import "testing"
+
+func TestQuote(t *testing.T) {
+	for in, want := range map[string]string{
+		"main":    "main",
+		"api-v2":  "api-v2",
+		"my proj": "'my proj'",
+		"it's":    `'it'\''s'`,
+		"$(id)":   `'$(id)'`,
+		"":        "''",
+	} {
+		if got := Quote(in); got != want {
+			t.Errorf("Quote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	got := Join([]string{"ssh", "-p", "22", "--ssh=ssh -p 22", "it's", "", "a@b:1"})
+	want := `ssh -p 22 '--ssh=ssh -p 22' 'it'\''s' '' a@b:1`
+	if got != want {
+		t.Errorf("Join = %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,296 @@
+// Package sshconfig reads OpenSSH client config files (~/.ssh/config) so
+// existing Host entries can be imported as sshtie profiles.
+//
+// Only the options sshtie has a use for are resolved: HostName, User, Port,
+// IdentityFile and ProxyJump. Everything else is ignored.
+package sshconfig
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Markers around the block `sshtie ssh-config` writes. Hosts inside it are
+// sshtie's own profiles and are never imported back.
+const (
+	ManagedBegin = "# BEGIN sshtie managed — do not edit this block manually"
+	ManagedEnd   = "# END sshtie managed"
+)
+
+// maxIncludeDepth mirrors OpenSSH's limit on nested Include directives.
+const maxIncludeDepth = 16
+
+// Host is one concrete Host alias with its options resolved the way ssh
+// would: for each option the first value found in a matching block wins,
+// wildcard blocks such as "Host *" included.
+type Host struct {
+	Alias        string
+	HostName     string // defaults to Alias
+	User         string
+	Port         int // 0 = not set
+	IdentityFile string
+	ProxyJump    []string
+
+	Source string // file:line of the Host line
+}
+
+// block is one Host section in file order.
+type block struct {
+	patterns []string
+	options  [][2]string // lower-cased keyword, value
+	source   string
+	managed  bool
+}
+
+// Parse reads the config at path, following Include directives, and returns
+// every concrete host alias it defines in file order. Wildcard-only and
+// negated patterns, Match blocks and hosts inside sshtie's managed block are
+// skipped.
+func Parse(file string) ([]Host, error) {
+	p := &parser{sshDir: filepath.Dir(file)}
+	if home, err := os.UserHomeDir(); err == nil {
+		p.sshDir = filepath.Join(home, ".ssh")
+	}
+	// Options before the first Host line apply to every host.
+	p.blocks = []block{{patterns: []string{"*"}, source: file}}
+	if err := p.parseFile(file, 0); err != nil {
+		return nil, err
+	}
+	return p.hosts(), nil
+}
+
+type parser struct {
+	sshDir  string // base for relative Include paths
+	blocks  []block
+	managed bool
+}
+
+func (p *parser) parseFile(file string, depth int) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("%s: Include nested too deeply", file)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	line := 0
+	for sc.Scan() {
+		line++
+		raw := strings.TrimSpace(sc.Text())
+		switch raw {
+		case ManagedBegin:
+			p.managed = true
+			continue
+		case ManagedEnd:
+			p.managed = false
+			continue
+		}
+		if raw == "" || strings.HasPrefix(raw, "#") {
+			continue
+		}
+		key, args := splitLine(raw)
+		if key == "" {
+			continue
+		}
+		source := fmt.Sprintf("%s:%d", file, line)
+		switch key {
+		case "host":
+			p.blocks = append(p.blocks, block{patterns: args, source: source, managed: p.managed})
+		case "match":
+			// Conditional blocks can't be evaluated without a connection;
+			// keep their options away from every host.
+			p.blocks = append(p.blocks, block{source: source})
+		case "include":
+			for _, pattern := range args {
+				if err := p.include(pattern, depth); err != nil {
+					return fmt.Errorf("%s: %w", source, err)
+				}
+			}
+		default:
+			if len(args) == 0 {
+				continue
+			}
+			cur := &p.blocks[len(p.blocks)-1]
+			cur.options = append(cur.options, [2]string{key, strings.Join(args, " ")})
+		}
+	}
+	return sc.Err()
+}
+
+// include parses every file matching pattern. Relative paths are taken from
+// ~/.ssh, like ssh does for the user config. A pattern matching nothing is
+// not an error.
+func (p *parser) include(pattern string, depth int) error {
+	pattern = expandHome(pattern)
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(p.sshDir, pattern)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("Include %s: %w", pattern, err)
+	}
+	sort.Strings(matches)
+	for _, m := range matches {
+		if fi, err := os.Stat(m); err != nil || fi.IsDir() {
+			continue
+		}
+		if err := p.parseFile(m, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hosts resolves every concrete alias against all blocks that match it.
+func (p *parser) hosts() []Host {
+	var out []Host
+	seen := make(map[string]bool)
+	for _, b := range p.blocks {
+		if b.managed {
+			continue
+		}
+		for _, alias := range b.patterns {
+			if !isConcrete(alias) || seen[alias] {
+				continue
+			}
+			seen[alias] = true
+			out = append(out, p.resolve(alias, b.source))
+		}
+	}
+	return out
+}
+
+func (p *parser) resolve(alias, source string) Host {
+	h := Host{Alias: alias, Source: source}
+	set := make(map[string]bool)
+	for _, b := range p.blocks {
+		if b.managed || !matches(b.patterns, alias) {
+			continue
+		}
+		for _, opt := range b.options {
+			key, val := opt[0], opt[1]
+			if set[key] {
+				continue
+			}
+			switch key {
+			case "hostname":
+				h.HostName = strings.ReplaceAll(val, "%h", alias)
+			case "user":
+				h.User = val
+			case "port":
+				n, err := strconv.Atoi(val)
+				if err != nil || n < 1 || n > 65535 {
+					continue
+				}
+				h.Port = n
+			case "identityfile":
+				h.IdentityFile = val
+			case "proxyjump":
+				if !strings.EqualFold(val, "none") {
+					for _, hop := range strings.Split(val, ",") {
+						if hop = strings.TrimSpace(hop); hop != "" {
+							h.ProxyJump = append(h.ProxyJump, hop)
+						}
+					}
+				}
+			default:
+				continue
+			}
+			set[key] = true
+		}
+	}
+	if h.HostName == "" {
+		h.HostName = alias
+	}
+	return h
+}
+
+// ── pattern matching ──────────────────────────────────────────────────────────
+
+// isConcrete reports whether a Host pattern names a single host.
+func isConcrete(pattern string) bool {
+	return pattern != "" && !strings.HasPrefix(pattern, "!") && !strings.ContainsAny(pattern, "*?")
+}
+
+// matches applies ssh's Host pattern rules: any positive match and no
+// negated match.
+func matches(patterns []string, host string) bool {
+	ok := false
+	for _, pat := range patterns {
+		if neg := strings.TrimPrefix(pat, "!"); neg != pat {
+			if match(neg, host) {
+				return false
+			}
+			continue
+		}
+		if match(pat, host) {
+			ok = true
+		}
+	}
+	return ok
+}
+
+func match(pattern, host string) bool {
+	ok, err := path.Match(strings.ToLower(pattern), strings.ToLower(host))
+	return err == nil && ok
+}
+
+// ── line parsing ──────────────────────────────────────────────────────────────
+
+// splitLine splits "Keyword value ..." or "Keyword=value" into a lower-cased
+// keyword and its arguments, honouring double quotes.
+func splitLine(line string) (string, []string) {
+	var fields []string
+	var cur strings.Builder
+	inQuote, hasField := false, false
+	flush := func() {
+		if hasField {
+			fields = append(fields, cur.String())
+		}
+		cur.Reset()
+		hasField = false
+	}
+	for _, r := range line {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			hasField = true
+		case inQuote:
+			cur.WriteRune(r)
+		case r == ' ' || r == '\t':
+			flush()
+		case r == '=' && len(fields) == 0:
+			flush()
+		default:
+			cur.WriteRune(r)
+			hasField = true
+		}
+	}
+	flush()
+	return keyword(fields)
+}
+
+func keyword(fields []string) (string, []string) {
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return strings.ToLower(fields[0]), fields[1:]
+}
+
+func expandHome(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, p[1:])
+		}
+	}
+	return p
+}
@@ -0,0 +1,106 @@
+package sshconfig
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	sshDir := filepath.Join(home, ".ssh")
+
+	writeFile(t, filepath.Join(sshDir, "config"), `
+Include conf.d/*.conf
+
+Host web1 web1-alias
+    HostName 10.0.0.1
+    User deploy
+    Port 2222
+
+Host db
+    HostName=%h.internal
+    ProxyJump bastion,jump2
+
+Host *.example.com !skip.example.com
+    User wildcard
+
+Match host web1
+    User matched
+
+Host *
+    User fallback
+    IdentityFile ~/.ssh/id_work
+
+`+ManagedBegin+`
+Host mine
+  HostName 1.2.3.4
+`+ManagedEnd+`
+`)
+	writeFile(t, filepath.Join(sshDir, "conf.d", "bastion.conf"), `
+Host bastion
+    HostName bastion.example.net
+    User "jump user"
+`)
+
+	hosts, err := Parse(filepath.Join(sshDir, "config"))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want := []Host{
+		{Alias: "bastion", HostName: "bastion.example.net", User: "jump user", IdentityFile: "~/.ssh/id_work"},
+		{Alias: "web1", HostName: "10.0.0.1", User: "deploy", Port: 2222, IdentityFile: "~/.ssh/id_work"},
+		{Alias: "web1-alias", HostName: "10.0.0.1", User: "deploy", Port: 2222, IdentityFile: "~/.ssh/id_work"},
+		{Alias: "db", HostName: "db.internal", User: "fallback", IdentityFile: "~/.ssh/id_work", ProxyJump: []string{"bastion", "jump2"}},
+	}
+	for i := range hosts {
+		hosts[i].Source = ""
+	}
+	if !reflect.DeepEqual(hosts, want) {
+		t.Errorf("Parse:\n got %+v\nwant %+v", hosts, want)
+	}
+}
+
+func TestParse_includeCycle(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	path := filepath.Join(home, ".ssh", "config")
+	writeFile(t, path, "Include config\nHost a\n")
+
+	if _, err := Parse(path); err == nil {
+		t.Error("expected an error for a self-including config")
+	}
+}
+
+func TestMatches(t *testing.T) {
+	cases := []struct {
+		patterns []string
+		host     string
+		want     bool
+	}{
+		{[]string{"*"}, "web", true},
+		{[]string{"web?"}, "web1", true},
+		{[]string{"*.example.com"}, "a.EXAMPLE.com", true},
+		{[]string{"*.example.com", "!b.example.com"}, "b.example.com", false},
+		{[]string{"!b"}, "a", false},
+		{nil, "a", false},
+	}
+	for _, c := range cases {
+		if got := matches(c.patterns, c.host); got != c.want {
+			t.Errorf("matches(%v, %q) = %v, want %v", c.patterns, c.host, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// Package sshkey generates per-profile SSH keys and installs them in, or
+// removes them from, a server's ~/.ssh/authorized_keys.
+package sshkey
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/ainsuotain/sshtie/internal/probe"
+	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/shell"
+)
+
+// Path is where the key generated for the named profile lives:
+// ~/.ssh/sshtie_<name>.
+func Path(name string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".ssh", "sshtie_"+name), nil
+}
+
+// Display writes path with the home directory as ~, the way profiles.yaml
+// keeps keys.
+func Display(path string) string {
+	home, err := os.UserHomeDir()
+	if err == nil && strings.HasPrefix(path, home+string(filepath.Separator)) {
+		return "~" + filepath.ToSlash(path[len(home):])
+	}
+	return path
+}
+
+// Comment labels a key made for the named profile, e.g.
+// "sshtie:homeserver@laptop".
+func Comment(name string) string {
+	host, _ := os.Hostname()
+	if i := strings.IndexByte(host, '.'); i > 0 {
+		host = host[:i]
+	}
+	if host == "" {
+		return "sshtie:" + name
+	}
+	return "sshtie:" + name + "@" + host
+}
+
+// Generate writes a new unencrypted ed25519 key pair to path and
+// path.pub. It never overwrites an existing key.
+func Generate(path, comment string) (ssh.PublicKey, error) {
+	for _, f := range []string{path, path + ".pub"} {
+		if _, err := os.Stat(f); err == nil {
+			return nil, fmt.Errorf("%s already exists", f)
+		}
+	}
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	block, err := ssh.MarshalPrivateKey(priv, comment)
+	if err != nil {
+		return nil, err
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path+".pub", []byte(AuthorizedLine(sshPub, comment)+"\n"), 0o644); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return sshPub, nil
+}
+
+// Delete removes a key pair written by Generate.
+func Delete(path string) {
+	os.Remove(path)
+	os.Remove(path + ".pub")
+}
+
+// Move renames the key pair at from (and from.pub) to to.
+func Move(from, to string) error {
+	if err := os.Rename(from, to); err != nil {
+		return err
+	}
+	if err := os.Rename(from+".pub", to+".pub"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// PublicKey returns the public half of the private key at path, from
+// path.pub or, failing that, from the key itself when it isn't encrypted.
+// The comment comes from path.pub when there is one.
+func PublicKey(path string) (ssh.PublicKey, string, error) {
+	if data, err := os.ReadFile(path + ".pub"); err == nil {
+		k, comment, _, _, err := ssh.ParseAuthorizedKey(data)
+		if err == nil {
+			return k, comment, nil
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	s, err := ssh.ParsePrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) && missing.PublicKey != nil {
+		return missing.PublicKey, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", path, err)
+	}
+	return s.PublicKey(), "", nil
+}
+
+// AuthorizedLine is k as an authorized_keys line.
+func AuthorizedLine(k ssh.PublicKey, comment string) string {
+	line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(k)))
+	if comment != "" {
+		line += " " + comment
+	}
+	return line
+}
+
+// blob is the base64 part of k's authorized_keys line, which identifies
+// the key whatever options or comment a line carries.
+func blob(k ssh.PublicKey) string {
+	f := strings.Fields(string(ssh.MarshalAuthorizedKey(k)))
+	return f[1]
+}
+
+// Push appends k to p's ~/.ssh/authorized_keys, unless it is already
+// there. opts decides how to log in — typically with a password prompt,
+// since the key isn't installed yet. It reports whether k was added.
+func Push(p profile.Profile, opts probe.Options, k ssh.PublicKey, comment string) (bool, error) {
+	out, err := probe.Exec(p, opts, addScript(AuthorizedLine(k, comment), blob(k)))
+	if err != nil {
+		return false, err
+	}
+	switch strings.TrimSpace(string(out)) {
+	case "added":
+		return true, nil
+	case "present":
+		return false, nil
+	}
+	return false, fmt.Errorf("unexpected reply from the server: %q", bytes.TrimSpace(out))
+}
+
+// Revoke removes every line for k from p's ~/.ssh/authorized_keys and
+// reports how many there were.
+func Revoke(p profile.Profile, opts probe.Options, k ssh.PublicKey) (int, error) {
+	out, err := probe.Exec(p, opts, removeScript(blob(k)))
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	if _, err := fmt.Sscanf(strings.TrimSpace(string(out)), "removed %d", &n); err != nil {
+		return 0, fmt.Errorf("unexpected reply from the server: %q", bytes.TrimSpace(out))
+	}
+	return n, nil
+}
+
+// Works reports whether the key at path alone logs in to p.
+func Works(p profile.Profile, path string) error {
+	_, err := probe.Exec(p, probe.Options{Identity: path}, "true")
+	return err
+}
+
+// addScript appends line to authorized_keys unless a line with the same
+// key blob is there, creating ~/.ssh with the permissions sshd insists on.
+func addScript(line, blob string) string {
+	return fmt.Sprintf(`umask 077
+mkdir -p "$HOME/.ssh" && chmod 700 "$HOME/.ssh" || exit 1
+f="$HOME/.ssh/authorized_keys"
+touch "$f" && chmod 600 "$f" || exit 1
+if grep -qF %[2]s "$f"; then echo present; exit 0; fi
+if [ -s "$f" ] && [ -n "$(tail -c 1 "$f")" ]; then echo >> "$f"; fi
+printf '%%s\n' %[1]s >> "$f" && echo added`, shell.Quote(line), shell.Quote(blob))
+}
+
+// removeScript drops the lines carrying blob from authorized_keys,
+// rewriting the file in place so its owner and permissions stay.
+func removeScript(blob string) string {
+	return fmt.Sprintf(`f="$HOME/.ssh/authorized_keys"
+[ -f "$f" ] || { echo "removed 0"; exit 0; }
+n=$(grep -cF %[1]s "$f")
+if [ "$n" -gt 0 ]; then
+  grep -vF %[1]s "$f" > "$f.sshtie"
+  cat "$f.sshtie" > "$f" && rm -f "$f.sshtie" || exit 1
+fi
+echo "removed $n"`, shell.Quote(blob))
+}
@@ -0,0 +1,91 @@
+package sshkey
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sshtie_web")
+	k, err := Generate(path, "sshtie:web@laptop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Type() != "ssh-ed25519" {
+		t.Errorf("type = %s", k.Type())
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("private key mode = %v, %v", fi.Mode().Perm(), err)
+	}
+
+	got, comment, err := PublicKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if AuthorizedLine(got, "") != AuthorizedLine(k, "") || comment != "sshtie:web@laptop" {
+		t.Errorf("PublicKey = %s %q", AuthorizedLine(got, ""), comment)
+	}
+
+	// Without the .pub file the key is read from the private half.
+	os.Remove(path + ".pub")
+	if got, _, err = PublicKey(path); err != nil || AuthorizedLine(got, "") != AuthorizedLine(k, "") {
+		t.Errorf("PublicKey without .pub = %v, %v", got, err)
+	}
+
+	if _, err := Generate(path, ""); err == nil {
+		t.Error("Generate overwrote an existing key")
+	}
+}
+
+// TestScripts runs the authorized_keys scripts against a fake $HOME.
+func TestScripts(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh")
+	}
+	home := t.TempDir()
+	run := func(script string) string {
+		t.Helper()
+		cmd := exec.Command("sh", "-c", script)
+		cmd.Env = append(os.Environ(), "HOME="+home)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("%v: %s", err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	dir := t.TempDir()
+	a, _ := Generate(filepath.Join(dir, "a"), "")
+	b, _ := Generate(filepath.Join(dir, "b"), "")
+
+	f := filepath.Join(home, ".ssh", "authorized_keys")
+	os.MkdirAll(filepath.Dir(f), 0o700)
+	os.WriteFile(f, []byte("ssh-rsa AAAAother kept"), 0o600) // no trailing newline
+
+	if got := run(addScript(AuthorizedLine(a, "it's a"), blob(a))); got != "added" {
+		t.Errorf("first add = %q", got)
+	}
+	if got := run(addScript(AuthorizedLine(a, "again"), blob(a))); got != "present" {
+		t.Errorf("second add = %q", got)
+	}
+	run(addScript(AuthorizedLine(b, "b"), blob(b)))
+
+	data, _ := os.ReadFile(f)
+	want := "ssh-rsa AAAAother kept\n" + AuthorizedLine(a, "it's a") + "\n" + AuthorizedLine(b, "b") + "\n"
+	if string(data) != want {
+		t.Errorf("authorized_keys =\n%s\nwant\n%s", data, want)
+	}
+
+	if got := run(removeScript(blob(a))); got != "removed 1" {
+		t.Errorf("remove = %q", got)
+	}
+	if got := run(removeScript(blob(a))); got != "removed 0" {
+		t.Errorf("second remove = %q", got)
+	}
+	data, _ = os.ReadFile(f)
+	if want := "ssh-rsa AAAAother kept\n" + AuthorizedLine(b, "b") + "\n"; string(data) != want {
+		t.Errorf("after remove =\n%s\nwant\n%s", data, want)
+	}
+}
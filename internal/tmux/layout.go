@@ -0,0 +1,170 @@
+package tmux
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/shell"
+)
+
+// AttachScript attaches to session, first building layout's windows and
+// panes when the session doesn't exist yet — re-attaching leaves it as it
+// is. It is run with sh -c on the server.
+func AttachScript(session string, layout []profile.Window) string {
+	var b strings.Builder
+	b.WriteString("if ! tmux has-session -t " + shell.Quote(target(session)) + " 2>/dev/null")
+	for i, w := range layout {
+		dir := func(p profile.Pane) string {
+			if p.Dir != "" {
+				return p.Dir
+			}
+			return w.Dir
+		}
+		panes := w.Panes
+		if len(panes) == 0 {
+			panes = []profile.Pane{{}}
+		}
+		// The first window comes with the session; the && guard means a
+		// session someone else created meanwhile is attached as it is.
+		if i == 0 {
+			b.WriteString(" &&\n  p=$(tmux new-session -d -P -F '#{pane_id}' -s " + shell.Quote(session) +
+				windowFlags(w.Name, dir(panes[0])) + "); then\n  first=$p\n")
+		} else {
+			b.WriteString("  p=$(tmux new-window -d -P -F '#{pane_id}' -t " + shell.Quote(target(session)+":") +
+				windowFlags(w.Name, dir(panes[0])) + ")\n")
+		}
+		b.WriteString("  w=$p\n")
+		for j, p := range panes {
+			if j > 0 {
+				split := "-h"
+				if p.Split == "down" {
+					split = "-v"
+				}
+				b.WriteString("  p=$(tmux split-window -d -P -F '#{pane_id}' -t \"$p\" " + split + dirFlag(dir(p)) + ")\n")
+			}
+			if p.Command != "" {
+				b.WriteString("  tmux send-keys -t \"$p\" -l " + shell.Quote(p.Command) + " && tmux send-keys -t \"$p\" Enter\n")
+			}
+		}
+		if w.Layout != "" {
+			b.WriteString("  tmux select-layout -t \"$w\" " + shell.Quote(w.Layout) + " >/dev/null 2>&1\n")
+		}
+	}
+	if len(layout) == 0 {
+		b.WriteString("; then\n  tmux new-session -d -s " + shell.Quote(session) + "\n")
+	} else {
+		b.WriteString("  tmux select-window -t \"$first\"\n")
+	}
+	b.WriteString("fi\nexec tmux attach-session -t " + shell.Quote(target(session)))
+	return b.String()
+}
+
+func windowFlags(name, dir string) string {
+	flags := ""
+	if name != "" {
+		flags += " -n " + shell.Quote(name)
+	}
+	return flags + dirFlag(dir)
+}
+
+// dirFlag is tmux's -c for dir, with a leading ~ expanded by the shell.
+func dirFlag(dir string) string {
+	switch {
+	case dir == "":
+		return ""
+	case dir == "~":
+		return ` -c "$HOME"`
+	case strings.HasPrefix(dir, "~/"):
+		return ` -c "$HOME"/` + shell.Quote(dir[2:])
+	}
+	return " -c " + shell.Quote(dir)
+}
+
+// paneFormat is the list-panes -F template CaptureScript prints.
+const paneFormat = "#{window_index}\t#{window_name}\t#{window_layout}\t#{pane_current_path}\t#{pane_current_command}"
+
+// CaptureScript prints the server's $HOME, then every pane of session
+// with its window, for ParseCapture.
+func CaptureScript(session string) string {
+	return find + `"$t" has-session -t ` + shell.Quote(target(session)) + ` 2>/dev/null ||
+  { echo "no tmux session called "` + shell.Quote(session) + `; exit 1; }
+echo "$HOME"
+"$t" list-panes -s -t ` + shell.Quote(target(session)) + ` -F ` + shell.Quote(paneFormat)
+}
+
+// shells are the commands a pane runs when nothing was started in it.
+var shells = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "fish": true, "dash": true,
+	"ksh": true, "tcsh": true, "csh": true, "-bash": true, "-zsh": true,
+}
+
+// ParseCapture turns CaptureScript's output into a layout. Paths under the
+// server's home are written with ~, a directory shared by every pane of a
+// window moves up to the window, and panes sitting at a shell prompt get
+// no command. The split geometry is kept as the window's layout string.
+func ParseCapture(out []byte) []profile.Window {
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	home := ""
+	if sc.Scan() {
+		home = strings.TrimRight(sc.Text(), "\r")
+	}
+	var windows []profile.Window
+	var index []string // window_index of each entry of windows
+	for sc.Scan() {
+		f := strings.Split(strings.TrimRight(sc.Text(), "\r"), "\t")
+		if len(f) != 5 {
+			continue
+		}
+		if len(index) == 0 || index[len(index)-1] != f[0] {
+			windows = append(windows, profile.Window{Name: f[1], Layout: f[2]})
+			index = append(index, f[0])
+		}
+		p := profile.Pane{Dir: tildePath(f[3], home)}
+		if !shells[f[4]] {
+			p.Command = f[4]
+		}
+		w := &windows[len(windows)-1]
+		w.Panes = append(w.Panes, p)
+	}
+	for i := range windows {
+		tidyWindow(&windows[i])
+	}
+	return windows
+}
+
+// tidyWindow moves a directory every pane shares up to w and drops what a
+// single shell doesn't need.
+func tidyWindow(w *profile.Window) {
+	shared := w.Panes[0].Dir
+	for _, p := range w.Panes[1:] {
+		if p.Dir != shared {
+			shared = ""
+		}
+	}
+	if shared != "" {
+		w.Dir = shared
+		for i := range w.Panes {
+			w.Panes[i].Dir = ""
+		}
+	}
+	if len(w.Panes) == 1 {
+		w.Layout = ""
+		if w.Panes[0] == (profile.Pane{}) {
+			w.Panes = nil
+		}
+	}
+}
+
+func tildePath(path, home string) string {
+	switch {
+	case home == "" || home == "/":
+		return path
+	case path == home:
+		return "~"
+	case strings.HasPrefix(path, home+"/"):
+		return "~" + path[len(home):]
+	}
+	return path
+}
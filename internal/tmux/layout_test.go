@@ -0,0 +1,90 @@
+package tmux
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+// TestAttachScript runs the script against a stub tmux that logs its
+// arguments, hands out pane ids and knows session "up".
+func TestAttachScript(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	stub := "#!/bin/sh\nfor a in \"$@\"; do printf '[%s]' \"$a\" >> " + log + "; done\necho >> " + log + "\n" +
+		"case \"$1\" in\n" +
+		"  has-session) [ \"$3\" = =up ] ;;\n" +
+		"  new-session|new-window|split-window) n=$(wc -l < " + log + "); echo \"%$n\" ;;\n" +
+		"esac\n"
+	if err := os.WriteFile(filepath.Join(dir, "tmux"), []byte(stub), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	run := func(script string) string {
+		t.Helper()
+		os.Remove(log)
+		cmd := exec.Command("sh", "-c", script)
+		cmd.Env = append(os.Environ(), "PATH="+dir+":/usr/bin:/bin", "HOME=/home/al")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%v: %s", err, out)
+		}
+		data, _ := os.ReadFile(log)
+		return string(data)
+	}
+
+	layout := []profile.Window{
+		{Name: "logs", Dir: "/var/log", Layout: "even-vertical", Panes: []profile.Pane{
+			{Command: "tail -f syslog"},
+			{Split: "down", Dir: "~/tmp", Command: "htop"},
+		}},
+		{Name: "shell"},
+	}
+	want := "[has-session][-t][=work]\n" +
+		"[new-session][-d][-P][-F][#{pane_id}][-s][work][-n][logs][-c][/var/log]\n" +
+		"[send-keys][-t][%2][-l][tail -f syslog]\n" +
+		"[send-keys][-t][%2][Enter]\n" +
+		"[split-window][-d][-P][-F][#{pane_id}][-t][%2][-v][-c][/home/al/tmp]\n" +
+		"[send-keys][-t][%5][-l][htop]\n" +
+		"[send-keys][-t][%5][Enter]\n" +
+		"[select-layout][-t][%2][even-vertical]\n" +
+		"[new-window][-d][-P][-F][#{pane_id}][-t][=work:][-n][shell]\n" +
+		"[select-window][-t][%2]\n" +
+		"[attach-session][-t][=work]\n"
+	if got := run(AttachScript("work", layout)); got != want {
+		t.Errorf("new session:\n%s\nwant:\n%s", got, want)
+	}
+
+	// An existing session is attached as it is.
+	want = "[has-session][-t][=up]\n[attach-session][-t][=up]\n"
+	if got := run(AttachScript("up", layout)); got != want {
+		t.Errorf("existing session:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestParseCapture(t *testing.T) {
+	out := []byte("/home/al\n" +
+		"1\tlogs\tb25f,80x24,0,0[80x12,0,0,1,80x11,0,13,2]\t/var/log\ttail\n" +
+		"1\tlogs\tb25f,80x24,0,0[80x12,0,0,1,80x11,0,13,2]\t/var/log\tbash\n" +
+		"2\tedit\tc3d1,80x24,0,0,3\t/home/al/src\tvim\n" +
+		"3\tzsh\tc3d2,80x24,0,0,4\t/home/al\tzsh\n" +
+		"4\tmixed\tabcd,80x24,0,0{40x24,0,0,5,39x24,41,0,6}\t/srv\t-bash\n" +
+		"4\tmixed\tabcd,80x24,0,0{40x24,0,0,5,39x24,41,0,6}\t/home/al/x\tnode\n")
+	want := []profile.Window{
+		{Name: "logs", Dir: "/var/log", Layout: "b25f,80x24,0,0[80x12,0,0,1,80x11,0,13,2]",
+			Panes: []profile.Pane{{Command: "tail"}, {}}},
+		{Name: "edit", Dir: "~/src", Panes: []profile.Pane{{Command: "vim"}}},
+		{Name: "zsh", Dir: "~"},
+		{Name: "mixed", Layout: "abcd,80x24,0,0{40x24,0,0,5,39x24,41,0,6}",
+			Panes: []profile.Pane{{Dir: "/srv"}, {Dir: "~/x", Command: "node"}}},
+	}
+	if got := ParseCapture(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseCapture =\n%+v\nwant\n%+v", got, want)
+	}
+}
@@ -0,0 +1,164 @@
+// Package tmux describes the tmux sessions running on a server and builds
+// the remote shell snippets that list, create, rename, kill and lay them out.
+// Running the snippets is left to the caller (see connector.TmuxSessions).
+package tmux
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ainsuotain/sshtie/internal/shell"
+)
+
+// Session is one tmux session on a server.
+type Session struct {
+	Name     string
+	Windows  int
+	Attached int       // clients attached right now
+	Activity time.Time // last input or output
+}
+
+// Format is the list-sessions -F template that Parse reads.
+const Format = "#{session_name}\t#{session_windows}\t#{session_attached}\t#{session_activity}"
+
+// Status summarizes s, e.g. "3 windows · attached · active 5m ago".
+func (s Session) Status() string {
+	parts := []string{plural(s.Windows, "window")}
+	switch {
+	case s.Attached == 1:
+		parts = append(parts, "attached")
+	case s.Attached > 1:
+		parts = append(parts, fmt.Sprintf("attached ×%d", s.Attached))
+	}
+	if !s.Activity.IsZero() {
+		parts = append(parts, "active "+Ago(s.Activity))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// Ago formats how long ago t was in one unit: "just now", "5m ago", "3h ago",
+// "2d ago".
+func Ago(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return "1 " + word
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
+
+// ParseLine reads one line printed with Format.
+func ParseLine(line string) (Session, bool) {
+	f := strings.Split(strings.TrimRight(line, "\r"), "\t")
+	if len(f) != 4 || f[0] == "" {
+		return Session{}, false
+	}
+	s := Session{Name: f[0]}
+	s.Windows, _ = strconv.Atoi(f[1])
+	s.Attached, _ = strconv.Atoi(f[2])
+	if sec, err := strconv.ParseInt(f[3], 10, 64); err == nil && sec > 0 {
+		s.Activity = time.Unix(sec, 0)
+	}
+	return s, true
+}
+
+// Parse reads list-sessions output printed with Format, most recently
+// active first.
+func Parse(out []byte) []Session {
+	var ss []Session
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		if s, ok := ParseLine(sc.Text()); ok {
+			ss = append(ss, s)
+		}
+	}
+	Sort(ss)
+	return ss
+}
+
+// Sort orders sessions most recently active first, then by name.
+func Sort(ss []Session) {
+	sort.SliceStable(ss, func(i, j int) bool {
+		if !ss[i].Activity.Equal(ss[j].Activity) {
+			return ss[i].Activity.After(ss[j].Activity)
+		}
+		return ss[i].Name < ss[j].Name
+	})
+}
+
+// Find returns the session called name.
+func Find(ss []Session, name string) (Session, bool) {
+	for _, s := range ss {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Session{}, false
+}
+
+// ValidName checks a session name the way tmux does: not empty, and no
+// ':' or '.', which tmux reads as window and pane separators.
+func ValidName(name string) error {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return fmt.Errorf("session name is empty")
+	case strings.ContainsAny(name, ":."):
+		return fmt.Errorf("session name %q can't contain ':' or '.'", name)
+	case strings.ContainsAny(name, "\t\n\r"):
+		return fmt.Errorf("session name %q can't contain tabs or newlines", name)
+	}
+	return nil
+}
+
+// find locates tmux on the server like the probe script does, since
+// non-interactive PATHs often miss /opt/homebrew/bin and /usr/local/bin.
+const find = `t=$(command -v tmux 2>/dev/null)
+for d in /opt/homebrew/bin /usr/local/bin /usr/bin /bin; do
+  [ -z "$t" ] && [ -x "$d/tmux" ] && t="$d/tmux"
+done
+[ -n "$t" ] || { echo "tmux is not installed on the server"; exit 127; }
+`
+
+// ListScript prints the server's sessions with Format. No tmux server
+// running is an empty list, not an error.
+func ListScript() string {
+	return find + `"$t" list-sessions -F ` + shell.Quote(Format) + ` 2>/dev/null
+exit 0`
+}
+
+// NewScript starts a detached session called name.
+func NewScript(name string) string {
+	return find + `"$t" new-session -d -s ` + shell.Quote(name) + ` 2>&1`
+}
+
+// RenameScript renames session from to to.
+func RenameScript(from, to string) string {
+	return find + `"$t" rename-session -t ` + shell.Quote(target(from)) + ` ` + shell.Quote(to) + ` 2>&1`
+}
+
+// KillScript ends session name and every program running in it.
+func KillScript(name string) string {
+	return find + `"$t" kill-session -t ` + shell.Quote(target(name)) + ` 2>&1`
+}
+
+// target is an exact-match session target: without the '=' tmux would
+// also accept a prefix of another session's name.
+func target(name string) string {
+	return "=" + name
+}
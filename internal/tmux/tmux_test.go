@@ -0,0 +1,95 @@
+package tmux
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	out := []byte("old\t1\t0\t1700000000\r\n" +
+		"work\t3\t2\t1700000500\n" +
+		"\t1\t0\t0\n" + // no name
+		"garbage\n" +
+		"new\t1\t0\t1700000500\n")
+	ss := Parse(out)
+	var names []string
+	for _, s := range ss {
+		names = append(names, s.Name)
+	}
+	if got := strings.Join(names, ","); got != "new,work,old" {
+		t.Fatalf("order = %s", got)
+	}
+	w, ok := Find(ss, "work")
+	if !ok || w.Windows != 3 || w.Attached != 2 || !w.Activity.Equal(time.Unix(1700000500, 0)) {
+		t.Errorf("work = %+v", w)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	s := Session{Name: "a", Windows: 1, Attached: 2, Activity: time.Now().Add(-5 * time.Minute)}
+	if got := s.Status(); got != "1 window · attached ×2 · active 5m ago" {
+		t.Errorf("Status() = %q", got)
+	}
+	s = Session{Name: "b", Windows: 3}
+	if got := s.Status(); got != "3 windows" {
+		t.Errorf("Status() = %q", got)
+	}
+}
+
+func TestValidName(t *testing.T) {
+	for _, ok := range []string{"main", "my project", "api-v2_x"} {
+		if err := ValidName(ok); err != nil {
+			t.Errorf("ValidName(%q) = %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"", "  ", "a:b", "a.b", "a\tb"} {
+		if ValidName(bad) == nil {
+			t.Errorf("ValidName(%q) accepted", bad)
+		}
+	}
+}
+
+// TestScripts runs the scripts against a stub tmux that logs its arguments.
+func TestScripts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	stub := "#!/bin/sh\nfor a in \"$@\"; do printf '[%s]' \"$a\" >> " + log + "; done\necho >> " + log + "\n" +
+		"[ \"$1\" = list-sessions ] && printf 'main\\t1\\t0\\t1700000000\\n'\nexit 0\n"
+	if err := os.WriteFile(filepath.Join(dir, "tmux"), []byte(stub), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	run := func(script string) string {
+		t.Helper()
+		cmd := exec.Command("sh", "-c", script)
+		cmd.Env = append(os.Environ(), "PATH="+dir+":/usr/bin:/bin")
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("%v: %s", err, out)
+		}
+		return string(out)
+	}
+
+	if ss := Parse([]byte(run(ListScript()))); len(ss) != 1 || ss[0].Name != "main" {
+		t.Errorf("list = %+v", ss)
+	}
+	run(NewScript("it's new"))
+	run(RenameScript("main", "work"))
+	run(KillScript("work"))
+
+	data, _ := os.ReadFile(log)
+	want := "[list-sessions][-F][" + Format + "]\n" +
+		"[new-session][-d][-s][it's new]\n" +
+		"[rename-session][-t][=main][work]\n" +
+		"[kill-session][-t][=work]\n"
+	if string(data) != want {
+		t.Errorf("tmux calls:\n%s\nwant:\n%s", data, want)
+	}
+}
@@ -1,10 +1,9 @@
 package tui
 
 import (
+	"context"
 	"fmt"
 	"net"
-	"os"
-	"os/exec"
 	"strconv"
 	"strings"
 	"time"
@@ -12,33 +11,50 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
+	"golang.org/x/crypto/ssh"
+
+	"github.com/ainsuotain/sshtie/internal/cloud"
+	"github.com/ainsuotain/sshtie/internal/connector"
+	"github.com/ainsuotain/sshtie/internal/hostkey"
+	"github.com/ainsuotain/sshtie/internal/locate"
+	"github.com/ainsuotain/sshtie/internal/probe"
 	"github.com/ainsuotain/sshtie/internal/profile"
 	"github.com/ainsuotain/sshtie/internal/tailscale"
+	"github.com/ainsuotain/sshtie/internal/tmux"
 )
 
 // ConnectAction is what the user chose after seeing the check results.
 type ConnectAction int
 
 const (
-	ConnectNone    ConnectAction = iota
-	ConnectProceed               // enter — go ahead and connect
-	ConnectInstall               // i     — install missing tools first
-	ConnectQuit                  // q/esc — abort
+	ConnectNone        ConnectAction = iota
+	ConnectProceed                   // enter — go ahead and connect
+	ConnectInstall                   // i     — install missing tools first
+	ConnectQuit                      // q/esc — abort
+	ConnectTrustNewKey               // t     — the host key changed; pin the new one and connect
 )
 
 // ConnectResult is returned by RunConnect after the TUI exits.
 type ConnectResult struct {
 	Action ConnectAction
+	// HostKey is the host key check; with ConnectTrustNewKey, its
+	// Presented keys are the ones to pin.
+	HostKey connector.HostKeyCheck
+	// Session is the tmux session picked on the server; "" when there was
+	// nothing to pick and the profile's own applies.
+	Session string
 }
 
 // ── check indices ─────────────────────────────────────────────────────────────
 
 const (
 	idxSSH       = 0
-	idxTmux      = 1
-	idxMosh      = 2
-	idxTailscale = 3
-	numChecks    = 4
+	idxHostKey   = 1
+	idxTmux      = 2
+	idxMosh      = 3
+	idxTailscale = 4
+	idxET        = 5 // only shown when the chain has et+tmux
+	numChecks    = 6
 )
 
 // ── check state ───────────────────────────────────────────────────────────────
@@ -76,6 +92,16 @@ type remoteDepsMsg struct {
 	tmuxState  cState
 	tmuxDetail string
 	tmuxHint   string
+	etState    cState
+	etDetail   string
+	etHint     string
+	sessions   []tmux.Session
+}
+
+// hostKeyMsg carries the host key check.
+type hostKeyMsg struct {
+	chk connector.HostKeyCheck
+	err error
 }
 
 type cSpinTickMsg struct{}
@@ -100,6 +126,12 @@ var (
 			BorderForeground(lipgloss.Color("39")).
 			Padding(0, 2).
 			Foreground(lipgloss.Color("252"))
+	cChangedStyle = lipgloss.NewStyle().
+			Border(lipgloss.DoubleBorder()).
+			BorderForeground(lipgloss.Color("196")).
+			Padding(0, 2).
+			Foreground(lipgloss.Color("252"))
+	cDangerStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("196"))
 )
 
 var cSpinFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
@@ -113,22 +145,42 @@ type connectModel struct {
 	allDone      bool
 	action       ConnectAction
 	strategy     string
+	plan         []connector.Step
+	planErr      error
 	needsInstall bool
-	isNewHost    bool
+	hostKey      connector.HostKeyCheck
+	picked       locate.Choice // which of the profile's addresses is used
+
+	// Remote tmux sessions, offered when the chosen strategy uses tmux.
+	pickSession bool
+	sessions    []tmux.Session
+	choices     []string // session names to pick from; the profile's own may be new
+	choice      int
 }
 
-func newConnectModel(p profile.Profile, isNewHost bool) connectModel {
+func newConnectModel(p profile.Profile, picked locate.Choice, pickSession bool) connectModel {
 	port := p.Port
 	if port == 0 {
 		port = 22
 	}
-	m := connectModel{prof: p, isNewHost: isNewHost}
+	m := connectModel{prof: p, picked: picked, pickSession: pickSession}
+	m.plan, m.planErr = connector.Plan(p)
 	m.checks[idxSSH] = checkItem{label: fmt.Sprintf("SSH  (port %d)", port)}
-	m.checks[idxTmux] = checkItem{label: "tmux         (server)"}
+	if p.Transport.Enabled() {
+		m.checks[idxSSH].label = fmt.Sprintf("SSH  (%s)", p.Transport.Type)
+	}
+	m.checks[idxHostKey] = checkItem{label: "host key     (server)"}
+	m.checks[idxTmux] = checkItem{label: muxLabel(muxOf(p))}
 	m.checks[idxMosh] = checkItem{label: "mosh-server  (server)"}
 	m.checks[idxTailscale] = checkItem{label: "Tailscale    (local)"}
+	if connector.InChain(p, "et+tmux") {
+		m.checks[idxET] = checkItem{label: "etserver     (server)"}
+	}
 	for i := range m.checks {
 		m.checks[i].state = cChecking
+		if m.checks[i].label == "" {
+			m.checks[i].state = cSkip
+		}
 	}
 	return m
 }
@@ -152,11 +204,26 @@ func (m connectModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.action = ConnectQuit
 			return m, tea.Quit
 		}
+		if m.hostKey.Changed != nil && msg.String() == "t" {
+			m.action = ConnectTrustNewKey
+			return m, tea.Quit
+		}
 		if !m.allDone {
 			return m, nil
 		}
 		switch msg.String() {
+		case "up", "k":
+			if m.choice > 0 {
+				m.choice--
+			}
+		case "down", "j":
+			if m.choice < len(m.choices)-1 {
+				m.choice++
+			}
 		case "enter":
+			if m.hostKey.Changed != nil {
+				return m, nil
+			}
 			if m.checks[idxSSH].state == cOK {
 				m.action = ConnectProceed
 				return m, tea.Quit
@@ -176,20 +243,11 @@ func (m connectModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		var next tea.Cmd
 		if msg.idx == idxSSH {
 			if msg.state == cOK {
-				// SSH reachable — now check remote tools.
-				next = cmdCheckRemoteDeps(m.prof)
+				// SSH reachable — make sure it's the right server first.
+				next = cmdCheckHostKey(m.prof)
 			} else {
 				// SSH failed — skip remote checks immediately.
-				m.checks[idxMosh] = checkItem{
-					label:  m.checks[idxMosh].label,
-					state:  cSkip,
-					detail: cSkipStyle.Render("skipped"),
-				}
-				m.checks[idxTmux] = checkItem{
-					label:  m.checks[idxTmux].label,
-					state:  cSkip,
-					detail: cSkipStyle.Render("skipped"),
-				}
+				m.skipRemote(idxHostKey, idxMosh, idxTmux, idxET)
 			}
 		}
 
@@ -197,7 +255,32 @@ func (m connectModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.allDone {
 			m.strategy = m.calcStrategy()
 			m.needsInstall = m.checks[idxMosh].state == cFail ||
-				m.checks[idxTmux].state == cFail
+				m.checks[idxTmux].state == cFail || m.checks[idxET].state == cFail
+			m.choices, m.choice = m.sessionChoices()
+		}
+		return m, next
+
+	case hostKeyMsg:
+		m.hostKey = msg.chk
+		c := &m.checks[idxHostKey]
+		var next tea.Cmd
+		switch {
+		case msg.err != nil:
+			c.state, c.detail = cSkip, cSkipStyle.Render(probeSkipReason(msg.err))
+			next = cmdCheckRemoteDeps(m.prof)
+		case msg.chk.Changed != nil:
+			c.state, c.detail = cFail, cDangerStyle.Render("CHANGED — not connecting")
+			m.skipRemote(idxMosh, idxTmux, idxET)
+		case msg.chk.New:
+			c.state, c.detail = cOK, cOKStyle.Render("new — pinned on connect")
+			next = cmdCheckRemoteDeps(m.prof)
+		default:
+			c.state, c.detail = cOK, cOKStyle.Render("matches pin  "+shortFingerprint(msg.chk.Presented))
+			next = cmdCheckRemoteDeps(m.prof)
+		}
+		m.allDone = m.isDone()
+		if m.allDone {
+			m.strategy = m.calcStrategy()
 		}
 		return m, next
 
@@ -208,12 +291,19 @@ func (m connectModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.checks[idxTmux].state = msg.tmuxState
 		m.checks[idxTmux].detail = msg.tmuxDetail
 		m.checks[idxTmux].hint = msg.tmuxHint
+		if m.checks[idxET].label != "" {
+			m.checks[idxET].state = msg.etState
+			m.checks[idxET].detail = msg.etDetail
+			m.checks[idxET].hint = msg.etHint
+		}
+		m.sessions = msg.sessions
 
 		m.allDone = m.isDone()
 		if m.allDone {
 			m.strategy = m.calcStrategy()
 			m.needsInstall = m.checks[idxMosh].state == cFail ||
-				m.checks[idxTmux].state == cFail
+				m.checks[idxTmux].state == cFail || m.checks[idxET].state == cFail
+			m.choices, m.choice = m.sessionChoices()
 		}
 
 	case cSpinTickMsg:
@@ -225,6 +315,20 @@ func (m connectModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// skipRemote marks checks that need the server as skipped.
+func (m *connectModel) skipRemote(idx ...int) {
+	for _, i := range idx {
+		if m.checks[i].label == "" {
+			continue
+		}
+		m.checks[i] = checkItem{
+			label:  m.checks[i].label,
+			state:  cSkip,
+			detail: cSkipStyle.Render("skipped"),
+		}
+	}
+}
+
 func (m connectModel) isDone() bool {
 	for _, c := range m.checks {
 		if c.state == cChecking {
@@ -234,22 +338,128 @@ func (m connectModel) isDone() bool {
 	return true
 }
 
+// strategyNotes explains the built-in strategies next to the chosen one.
+var strategyNotes = map[string]string{
+	"mosh+tmux": "✨ reconnects automatically if network drops",
+	"ssh+tmux":  "(session stays alive, no auto-reconnect)",
+	"ssh":       "(basic, no persistent session)",
+	"et+tmux":   "✨ survives roaming and sleep over TCP",
+}
+
+// toolChecks maps a strategy's server requirement to the check that covers it.
+var toolChecks = map[string]int{
+	"mosh-server": idxMosh,
+	"tmux":        idxTmux,
+	"screen":      idxTmux,
+	"zellij":      idxTmux,
+	"etserver":    idxET,
+}
+
+// calcStrategy renders the chain Connect will walk: steps that will be
+// skipped are dimmed and the first one expected to work is highlighted.
 func (m connectModel) calcStrategy() string {
-	moshOK := m.checks[idxMosh].state == cOK
-	tmuxOK := m.checks[idxTmux].state == cOK
-	switch {
-	case moshOK && tmuxOK:
-		return cOKStyle.Render("mosh + tmux") + cSubStyle.Render("  ✨ reconnects automatically if network drops")
-	case tmuxOK:
-		return cStrategyStyle.Render("ssh + tmux") + cSubStyle.Render("  (session stays alive, no auto-reconnect)")
-	default:
-		return cWarnStyle.Render("ssh only") + cSubStyle.Render("  (basic, no persistent session)")
+	if m.planErr != nil {
+		return cWarnStyle.Render(m.planErr.Error())
+	}
+	chosen := -1
+	parts := make([]string, len(m.plan))
+	for i, st := range m.plan {
+		switch {
+		case m.stepSkipped(st):
+			parts[i] = cSkipStyle.Render(st.Label)
+		case chosen < 0:
+			chosen = i
+			parts[i] = cOKStyle.Render(st.Label)
+		default:
+			parts[i] = cStrategyStyle.Render(st.Label)
+		}
+	}
+	out := strings.Join(parts, cSubStyle.Render(" → "))
+	if chosen < 0 {
+		return out + cWarnStyle.Render("  (nothing viable — connect will likely fail)")
 	}
+	if note := strategyNotes[m.plan[chosen].Name]; note != "" {
+		out += cSubStyle.Render("  " + note)
+	}
+	return out
+}
+
+// sessionChoices lists the tmux sessions to pick from — the running ones
+// plus the profile's own when it isn't running yet — and which one starts
+// selected. There is nothing to pick when the strategy expected to be used
+// doesn't run tmux, or when the profile's session is the only one.
+func (m connectModel) sessionChoices() ([]string, int) {
+	if !m.pickSession || len(m.sessions) == 0 {
+		return nil, 0
+	}
+	st, ok := m.chosenStep()
+	if !ok || !requires(st, "tmux") {
+		return nil, 0
+	}
+	own := m.prof.TmuxSession
+	if own == "" {
+		own = "main"
+	}
+	var names []string
+	sel := -1
+	for _, s := range m.sessions {
+		if s.Name == own {
+			sel = len(names)
+		}
+		names = append(names, s.Name)
+	}
+	if sel < 0 {
+		sel = len(names)
+		names = append(names, own)
+	}
+	if len(names) == 1 {
+		return nil, 0
+	}
+	return names, sel
+}
+
+// chosenStep is the first step of the plan expected to work.
+func (m connectModel) chosenStep() (connector.Step, bool) {
+	if m.planErr != nil {
+		return connector.Step{}, false
+	}
+	for _, st := range m.plan {
+		if !m.stepSkipped(st) {
+			return st, true
+		}
+	}
+	return connector.Step{}, false
+}
+
+func requires(st connector.Step, tool string) bool {
+	for _, t := range st.Requires {
+		if t == tool {
+			return true
+		}
+	}
+	return false
+}
+
+// stepSkipped reports whether st is ruled out, either up front (platform,
+// network mode) or because a tool it needs is missing on the server.
+func (m connectModel) stepSkipped(st connector.Step) bool {
+	if st.Skip != "" {
+		return true
+	}
+	for _, tool := range st.Requires {
+		if idx, ok := toolChecks[tool]; ok && m.checks[idx].state == cFail {
+			return true
+		}
+	}
+	return false
 }
 
 // ── View ──────────────────────────────────────────────────────────────────────
 
 func (m connectModel) View() string {
+	if m.hostKey.Changed != nil {
+		return m.viewChanged()
+	}
 	var b strings.Builder
 
 	port := m.prof.Port
@@ -260,11 +470,18 @@ func (m connectModel) View() string {
 	// ── header ──
 	b.WriteString("\n")
 	b.WriteString("  " + titleStyle.Render("sshtie") + "  →  " + titleStyle.Render(m.prof.Name) + "\n")
-	b.WriteString(cSubStyle.Render(fmt.Sprintf("  %s@%s · port %d", m.prof.User, m.prof.Host, port)) + "\n\n")
+	b.WriteString(cSubStyle.Render(fmt.Sprintf("  %s@%s · port %d%s", m.prof.User, m.prof.Host, port, viaSuffix(m.prof))) + "\n")
+	if m.picked.Multiple() {
+		b.WriteString(cSubStyle.Render("  address "+m.picked.String()+pickedSuffix(m.picked, m.prof)) + "\n")
+	}
+	b.WriteString("\n")
 	b.WriteString(cSubStyle.Render("  ───────────────────────────────────────────") + "\n")
 
 	// ── checks ──
 	for _, c := range m.checks {
+		if c.label == "" {
+			continue
+		}
 		icon := ""
 		detail := c.detail
 		switch c.state {
@@ -294,11 +511,20 @@ func (m connectModel) View() string {
 	// ── strategy ──
 	b.WriteString("  " + cSubStyle.Render("Strategy: ") + m.strategy + "\n\n")
 
+	// ── tmux session picker ──
+	if len(m.choices) > 0 {
+		b.WriteString(m.viewSessions() + "\n")
+	}
+
 	// ── new host notice ──
-	if m.isNewHost {
-		notice := "First time connecting to this server.\n" +
-			"SSH will verify and save the server's fingerprint —\n" +
-			"this keeps you safe from impersonation on future connections."
+	if m.hostKey.New {
+		notice := "First time connecting to this server. sshtie will pin its host key:\n\n"
+		for _, fp := range hostkey.Fingerprints(m.hostKey.Presented) {
+			notice += "  " + fp + "\n"
+		}
+		notice += "\nFrom then on, a different key stops the connection —\n" +
+			"this keeps you safe from impersonation on future connections.\n" +
+			"To be sure, compare with  ssh-keygen -lf /etc/ssh/ssh_host_ed25519_key.pub  on the server."
 		b.WriteString("  " + cNewHostStyle.Render("🔐 Security notice\n\n"+notice) + "\n\n")
 	}
 
@@ -313,6 +539,9 @@ func (m connectModel) View() string {
 	sshOK := m.checks[idxSSH].state == cOK
 	if sshOK {
 		b.WriteString("  " + cKeyStyle.Render("[enter]") + cSubStyle.Render(" connect"))
+		if len(m.choices) > 0 {
+			b.WriteString("   " + cKeyStyle.Render("[↑/↓]") + cSubStyle.Render(" session"))
+		}
 		if m.needsInstall {
 			b.WriteString("   " + cKeyStyle.Render("[i]") + cSubStyle.Render(" install missing tools"))
 		}
@@ -324,6 +553,95 @@ func (m connectModel) View() string {
 	return b.String()
 }
 
+// viewSessions renders the tmux session picker.
+func (m connectModel) viewSessions() string {
+	var b strings.Builder
+	for i, name := range m.choices {
+		label := cSubStyle.Render("Session:  ")
+		if i > 0 {
+			label = strings.Repeat(" ", 10)
+		}
+		status := "new"
+		if s, ok := tmux.Find(m.sessions, name); ok {
+			status = s.Status()
+		}
+		line := fmt.Sprintf("  %-18s %s", name, cSubStyle.Render(status))
+		if i == m.choice {
+			line = cOKStyle.Render(fmt.Sprintf("▸ %-18s", name)) + " " + cSubStyle.Render(status)
+		}
+		b.WriteString("  " + label + line + "\n")
+	}
+	return b.String()
+}
+
+// viewChanged is the screen shown instead of the checks when the server
+// presents a host key that doesn't match what is trusted for it.
+func (m connectModel) viewChanged() string {
+	e := m.hostKey.Changed
+	var b strings.Builder
+	b.WriteString("\n")
+	b.WriteString("  " + titleStyle.Render("sshtie") + "  →  " + titleStyle.Render(m.prof.Name) + "\n")
+	b.WriteString(cSubStyle.Render("  "+e.Addr) + "\n\n")
+
+	src := "pinned in profiles.yaml"
+	if e.Source != "profile" {
+		src = "in " + e.Source
+	}
+	var t strings.Builder
+	t.WriteString(cDangerStyle.Render("⚠  THE SERVER'S HOST KEY HAS CHANGED") + "\n\n")
+	t.WriteString("Expected (" + src + "):\n")
+	for _, fp := range e.Expected {
+		t.WriteString("  " + fp + "\n")
+	}
+	t.WriteString("\nPresented now:\n")
+	for _, fp := range e.Presented {
+		t.WriteString("  " + cWarnStyle.Render(fp) + "\n")
+	}
+	t.WriteString("\nThis usually means one of:\n" +
+		"  • the server was reinstalled or its SSH keys were regenerated\n" +
+		"  • the address now points at a different machine\n" +
+		"  • something between you and the server is impersonating it\n\n" +
+		"Check the key on the server itself before trusting it:\n" +
+		"  ssh-keygen -lf /etc/ssh/ssh_host_ed25519_key.pub")
+	b.WriteString("  " + cChangedStyle.Render(t.String()) + "\n\n")
+
+	b.WriteString("  " + cKeyStyle.Render("[t]") + cSubStyle.Render(" trust the new key and connect") +
+		"   " + cKeyStyle.Render("[q]") + cSubStyle.Render(" quit") + "\n\n")
+	return b.String()
+}
+
+// shortFingerprint is the first presented key's fingerprint, shortened.
+func shortFingerprint(keys []ssh.PublicKey) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	fp := hostkey.Fingerprint(keys[0])
+	if len(fp) > 19 {
+		fp = fp[:19] + "…"
+	}
+	return cSubStyle.Render(fp)
+}
+
+// viaSuffix renders " · via a → b" for profiles with a jump chain.
+func viaSuffix(p profile.Profile) string {
+	if len(p.Jump) == 0 {
+		return ""
+	}
+	return " · via " + strings.Join(p.Jump, " → ")
+}
+
+// pickedSuffix says why the address was picked.
+func pickedSuffix(c locate.Choice, p profile.Profile) string {
+	switch {
+	case c.Reachable:
+		return " · answered first"
+	case len(p.Jump) > 0:
+		return " · first that applies"
+	default:
+		return " · none answered"
+	}
+}
+
 func (m connectModel) collectHints() []string {
 	var out []string
 	for _, c := range m.checks {
@@ -348,6 +666,39 @@ func cmdCheckSSH(p profile.Profile) tea.Cmd {
 		if port == 0 {
 			port = 22
 		}
+		if p.Transport.Enabled() {
+			return checkTransport(p)
+		}
+		hops, err := profile.JumpChain(p)
+		if err != nil {
+			return checkDoneMsg{
+				idx:    idxSSH,
+				state:  cFail,
+				detail: cWarnStyle.Render("invalid jump chain"),
+				hint:   fmt.Sprintf("%v\n  Fix it with:  sshtie edit %s", err, p.Name),
+			}
+		}
+		if len(hops) > 0 {
+			host, hopPort := profile.EntryPoint(p, hops)
+			conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(hopPort)), 5*time.Second)
+			if err != nil {
+				return checkDoneMsg{
+					idx:    idxSSH,
+					state:  cFail,
+					detail: cWarnStyle.Render("jump host unreachable"),
+					hint: fmt.Sprintf(
+						"SSH couldn't reach the jump host %s (%s:%d).\n"+
+							"  %s is only reachable through it — check that the bastion is online.",
+						hops[0].Label(), host, hopPort, p.Name),
+				}
+			}
+			conn.Close()
+			return checkDoneMsg{
+				idx:    idxSSH,
+				state:  cOK,
+				detail: cOKStyle.Render("reachable via " + hops[0].Label()),
+			}
+		}
 		addr := net.JoinHostPort(p.Host, strconv.Itoa(port))
 		conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
 		if err != nil {
@@ -373,6 +724,46 @@ func cmdCheckSSH(p profile.Profile) tea.Cmd {
 	}
 }
 
+// checkTransport asks the cloud CLI whether p's instance is running, in
+// place of dialling an SSH port it doesn't expose.
+func checkTransport(p profile.Profile) checkDoneMsg {
+	if err := profile.ValidateTransport(p); err != nil {
+		return checkDoneMsg{
+			idx:    idxSSH,
+			state:  cFail,
+			detail: cWarnStyle.Render("invalid transport"),
+			hint:   fmt.Sprintf("%v\n  Fix it with:  sshtie edit %s", err, p.Name),
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	if err := cloud.Running(ctx, p.Transport); err != nil {
+		return checkDoneMsg{
+			idx:    idxSSH,
+			state:  cFail,
+			detail: cWarnStyle.Render("instance not running"),
+			hint: fmt.Sprintf(
+				"%v\n"+
+					"  Things to check:\n"+
+					"  • Is the %s CLI installed and logged in?\n"+
+					"  • Is the instance started?",
+				err, cloud.CLI(p.Transport)),
+		}
+	}
+	return checkDoneMsg{
+		idx:    idxSSH,
+		state:  cOK,
+		detail: cOKStyle.Render("instance running · " + p.Transport.String()),
+	}
+}
+
+func cmdCheckHostKey(p profile.Profile) tea.Cmd {
+	return func() tea.Msg {
+		chk, err := connector.CheckHostKey(p)
+		return hostKeyMsg{chk: chk, err: err}
+	}
+}
+
 func cmdCheckTailscale() tea.Cmd {
 	return func() tea.Msg {
 		if tailscale.ClientRunning() {
@@ -393,44 +784,33 @@ func cmdCheckTailscale() tea.Cmd {
 
 func cmdCheckRemoteDeps(p profile.Profile) tea.Cmd {
 	return func() tea.Msg {
-		port := p.Port
-		if port == 0 {
-			port = 22
-		}
-		args := []string{
-			"-p", strconv.Itoa(port),
-			"-o", "StrictHostKeyChecking=accept-new",
-			"-o", "ConnectTimeout=5",
-			"-o", "BatchMode=yes",
-		}
-		key := p.DefaultKey()
-		if _, err := os.Stat(key); err == nil {
-			args = append(args, "-i", key)
-		}
-		args = append(args, fmt.Sprintf("%s@%s", p.User, p.Host))
-		args = append(args, "which mosh-server >/dev/null 2>&1 && echo M; which tmux >/dev/null 2>&1 && echo T")
-
-		out, err := exec.Command("ssh", args...).Output()
+		facts, err := probe.Run(p)
 		if err != nil {
 			// SSH needs a password, or another transient error — skip gracefully.
+			why := probeSkipReason(err)
 			return remoteDepsMsg{
 				moshState:  cSkip,
-				moshDetail: cSkipStyle.Render("couldn't verify  (password auth?)"),
+				moshDetail: cSkipStyle.Render(why),
 				tmuxState:  cSkip,
-				tmuxDetail: cSkipStyle.Render("couldn't verify  (password auth?)"),
+				tmuxDetail: cSkipStyle.Render(why),
+				etState:    cSkip,
+				etDetail:   cSkipStyle.Render(why),
 			}
 		}
+		hasMosh := facts.Has("mosh-server")
+		mux := muxOf(p)
 
-		s := string(out)
-		hasMosh := strings.Contains(s, "M")
-		hasTmux := strings.Contains(s, "T")
-
-		msg := remoteDepsMsg{}
+		msg := remoteDepsMsg{sessions: facts.Sessions}
 
-		if hasMosh {
+		switch {
+		case p.Transport.Enabled():
+			// The cloud tunnel only carries ssh; mosh's UDP can't get through.
+			msg.moshState = cSkip
+			msg.moshDetail = cSkipStyle.Render("not through " + p.Transport.Type)
+		case hasMosh:
 			msg.moshState = cOK
 			msg.moshDetail = cOKStyle.Render("installed")
-		} else {
+		default:
 			msg.moshState = cFail
 			msg.moshDetail = cWarnStyle.Render("not installed")
 			msg.moshHint = fmt.Sprintf(
@@ -440,34 +820,83 @@ func cmdCheckRemoteDeps(p profile.Profile) tea.Cmd {
 					"  Install it with:  sshtie install %s", p.Name)
 		}
 
-		if hasTmux {
+		switch {
+		case mux == "none":
+			msg.tmuxState = cSkip
+			msg.tmuxDetail = cSkipStyle.Render("not used")
+		case facts.Has(mux):
 			msg.tmuxState = cOK
 			msg.tmuxDetail = cOKStyle.Render("installed")
-		} else {
+		default:
 			msg.tmuxState = cFail
 			msg.tmuxDetail = cWarnStyle.Render("not installed")
 			msg.tmuxHint = fmt.Sprintf(
-				"tmux is not on the server.\n"+
-					"  tmux keeps your work running on the server even after you disconnect —\n"+
+				"%s is not on the server.\n"+
+					"  %s keeps your work running on the server even after you disconnect —\n"+
 					"  log back in any time and resume exactly where you stopped.\n"+
-					"  Install it with:  sshtie install %s", p.Name)
+					"  Install it with:  sshtie install %s", mux, mux, p.Name)
+		}
+
+		if facts.Has("etserver") {
+			msg.etState = cOK
+			msg.etDetail = cOKStyle.Render("installed")
+		} else {
+			msg.etState = cFail
+			msg.etDetail = cWarnStyle.Render("not installed")
+			msg.etHint = fmt.Sprintf(
+				"etserver is not on the server.\n"+
+					"  Eternal Terminal reconnects over TCP when you roam or wake your laptop,\n"+
+					"  where mosh's UDP is blocked.\n"+
+					"  Install it with:  sshtie install %s --et", p.Name)
 		}
 
 		return msg
 	}
 }
 
+// muxOf is p's multiplexer. An invalid one reads as tmux here; Plan
+// reports the error.
+func muxOf(p profile.Profile) string {
+	mux, err := profile.Multiplexer(p)
+	if err != nil {
+		return profile.DefaultMultiplexer
+	}
+	return mux
+}
+
+// muxLabel is the multiplexer check's label, aligned with the others.
+func muxLabel(mux string) string {
+	if mux == "none" {
+		mux = "multiplexer"
+	}
+	return fmt.Sprintf("%-13s(server)", mux)
+}
+
+// probeSkipReason is the short "couldn't verify" detail for a failed probe.
+func probeSkipReason(err error) string {
+	if probe.IsAuthError(err) {
+		return "couldn't verify  (password auth?)"
+	}
+	return "couldn't verify"
+}
+
 // ── public entry point ────────────────────────────────────────────────────────
 
 // RunConnect launches the connection-progress TUI and returns what the user chose.
 // The caller must act on the result AFTER this returns so the terminal is restored.
-func RunConnect(p profile.Profile, isNewHost bool) (ConnectResult, error) {
-	m := newConnectModel(p, isNewHost)
+// picked is the address locate.Apply chose for p. With pickSession, the
+// tmux sessions running on the server are offered to pick from.
+func RunConnect(p profile.Profile, picked locate.Choice, pickSession bool) (ConnectResult, error) {
+	m := newConnectModel(p, picked, pickSession)
 	prog := tea.NewProgram(m, tea.WithAltScreen())
 	final, err := prog.Run()
 	if err != nil {
 		return ConnectResult{}, err
 	}
 	fm := final.(connectModel)
-	return ConnectResult{Action: fm.action}, nil
+	res := ConnectResult{Action: fm.action, HostKey: fm.hostKey}
+	if len(fm.choices) > 0 {
+		res.Session = fm.choices[fm.choice]
+	}
+	return res, nil
 }
@@ -1,9 +1,9 @@
 package tui
 
 import (
+	"context"
 	"fmt"
 	"net"
-	"os"
 	"os/exec"
 	"strconv"
 	"strings"
@@ -11,6 +11,10 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 
+	"github.com/ainsuotain/sshtie/internal/cloud"
+	"github.com/ainsuotain/sshtie/internal/connector"
+	"github.com/ainsuotain/sshtie/internal/netenv"
+	"github.com/ainsuotain/sshtie/internal/probe"
 	"github.com/ainsuotain/sshtie/internal/profile"
 	"github.com/ainsuotain/sshtie/internal/tailscale"
 )
@@ -39,7 +43,8 @@ const (
 	dUDP      = 3
 	dTSClient = 4
 	dTSServer = 5
-	dTotal    = 6
+	dET       = 6 // only shown when the chain has et+tmux
+	dTotal    = 7
 )
 
 // ── messages ──────────────────────────────────────────────────────────────────
@@ -58,6 +63,9 @@ type dRemoteMsg struct {
 	moshState  cState
 	moshDetail string
 	moshHint   string
+	etState    cState
+	etDetail   string
+	etHint     string
 }
 
 type dTSServerMsg struct {
@@ -86,13 +94,28 @@ func newDoctorModel(p profile.Profile) doctorModel {
 	}
 	m := doctorModel{prof: p}
 	m.checks[dSSH] = checkItem{label: fmt.Sprintf("SSH          (port %d)", port)}
-	m.checks[dTmux] = checkItem{label: "tmux         (server)"}
+	if p.Transport.Enabled() {
+		m.checks[dSSH].label = fmt.Sprintf("SSH          (%s)", p.Transport.Type)
+	}
+	m.checks[dTmux] = checkItem{label: muxLabel(muxOf(p))}
 	m.checks[dMosh] = checkItem{label: "mosh-server  (server)"}
-	m.checks[dUDP] = checkItem{label: "UDP 60001    (firewall)"}
+	m.checks[dUDP] = checkItem{label: "mosh UDP     (firewall)"}
 	m.checks[dTSClient] = checkItem{label: "Tailscale    (local)"}
 	m.checks[dTSServer] = checkItem{label: "Tailscale    (server)"}
+	if connector.InChain(p, "et+tmux") {
+		m.checks[dET] = checkItem{label: "etserver     (server)"}
+	}
 	for i := range m.checks {
 		m.checks[i].state = cChecking
+		if m.checks[i].label == "" {
+			m.checks[i].state = cSkip
+		}
+	}
+	if p.Transport.Enabled() {
+		// The cloud tunnel only carries ssh; mosh's UDP can't get through.
+		why := cSkipStyle.Render("not through " + p.Transport.Type)
+		m.checks[dMosh].state, m.checks[dMosh].detail = cSkip, why
+		m.checks[dUDP].state, m.checks[dUDP].detail = cSkip, why
 	}
 	return m
 }
@@ -101,9 +124,9 @@ func (m doctorModel) Init() tea.Cmd {
 	return tea.Batch(
 		dTick(),
 		cmdDoctorSSH(m.prof),
-		cmdDoctorUDP(m.prof.Host),
 		cmdDoctorTSClient(),
 		// remote deps: fired after SSH passes
+		// mosh UDP:    fired after remote deps find mosh-server
 		// TS server:   fired after TS client passes
 	)
 }
@@ -146,6 +169,8 @@ func (m doctorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			} else {
 				m.checks[dTmux] = checkItem{label: m.checks[dTmux].label, state: cSkip, detail: cSkipStyle.Render("skipped")}
 				m.checks[dMosh] = checkItem{label: m.checks[dMosh].label, state: cSkip, detail: cSkipStyle.Render("skipped")}
+				m.checks[dUDP] = checkItem{label: m.checks[dUDP].label, state: cSkip, detail: cSkipStyle.Render("skipped")}
+				m.checks[dET] = checkItem{label: m.checks[dET].label, state: cSkip, detail: cSkipStyle.Render("skipped")}
 			}
 		case dTSClient:
 			if msg.state == cOK {
@@ -158,7 +183,8 @@ func (m doctorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.allDone = m.dIsDone()
 		if m.allDone {
 			m.strategy = m.dStrategy()
-			m.needsInstall = m.checks[dMosh].state == cFail || m.checks[dTmux].state == cFail
+			m.needsInstall = m.checks[dMosh].state == cFail || m.checks[dTmux].state == cFail ||
+				m.checks[dET].state == cFail
 		}
 		return m, next
 
@@ -166,15 +192,34 @@ func (m doctorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.checks[dTmux].state = msg.tmuxState
 		m.checks[dTmux].detail = msg.tmuxDetail
 		m.checks[dTmux].hint = msg.tmuxHint
-		m.checks[dMosh].state = msg.moshState
-		m.checks[dMosh].detail = msg.moshDetail
-		m.checks[dMosh].hint = msg.moshHint
+		if m.checks[dET].label != "" {
+			m.checks[dET].state = msg.etState
+			m.checks[dET].detail = msg.etDetail
+			m.checks[dET].hint = msg.etHint
+		}
+
+		var next tea.Cmd
+		if !m.prof.Transport.Enabled() {
+			m.checks[dMosh].state = msg.moshState
+			m.checks[dMosh].detail = msg.moshDetail
+			m.checks[dMosh].hint = msg.moshHint
+			switch msg.moshState {
+			case cOK:
+				next = cmdDoctorMosh(m.prof)
+			case cSkip:
+				next = cmdDoctorUDP(m.prof.Host) // no key login: heuristic only
+			default:
+				m.checks[dUDP] = checkItem{label: m.checks[dUDP].label, state: cSkip, detail: cSkipStyle.Render("skipped")}
+			}
+		}
 
 		m.allDone = m.dIsDone()
 		if m.allDone {
 			m.strategy = m.dStrategy()
-			m.needsInstall = m.checks[dMosh].state == cFail || m.checks[dTmux].state == cFail
+			m.needsInstall = m.checks[dMosh].state == cFail || m.checks[dTmux].state == cFail ||
+				m.checks[dET].state == cFail
 		}
+		return m, next
 
 	case dTSServerMsg:
 		m.checks[dTSServer].state = msg.state
@@ -183,7 +228,8 @@ func (m doctorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.allDone = m.dIsDone()
 		if m.allDone {
 			m.strategy = m.dStrategy()
-			m.needsInstall = m.checks[dMosh].state == cFail || m.checks[dTmux].state == cFail
+			m.needsInstall = m.checks[dMosh].state == cFail || m.checks[dTmux].state == cFail ||
+				m.checks[dET].state == cFail
 		}
 
 	case dTickMsg:
@@ -205,13 +251,21 @@ func (m doctorModel) dIsDone() bool {
 }
 
 func (m doctorModel) dStrategy() string {
-	moshOK := m.checks[dMosh].state == cOK
-	tmuxOK := m.checks[dTmux].state == cOK
+	moshOK := m.checks[dMosh].state == cOK && m.checks[dUDP].state == cOK
+	mux := muxOf(m.prof)
+	muxOK := mux != "none" && m.checks[dTmux].state == cOK
+	etOK := m.checks[dET].label != "" && m.checks[dET].state == cOK
 	switch {
-	case moshOK && tmuxOK:
-		return cOKStyle.Render("mosh + tmux") + cSubStyle.Render("  ✨ reconnects automatically if network drops")
-	case tmuxOK:
-		return cStrategyStyle.Render("ssh + tmux") + cSubStyle.Render("  (session stays alive, no auto-reconnect)")
+	case etOK && mux == "none":
+		return cOKStyle.Render("et") + cSubStyle.Render("  ✨ survives roaming and sleep over TCP")
+	case etOK && muxOK:
+		return cOKStyle.Render("et + "+mux) + cSubStyle.Render("  ✨ survives roaming and sleep over TCP")
+	case moshOK && mux == "none":
+		return cOKStyle.Render("mosh") + cSubStyle.Render("  ✨ reconnects automatically if network drops")
+	case moshOK && muxOK:
+		return cOKStyle.Render("mosh + "+mux) + cSubStyle.Render("  ✨ reconnects automatically if network drops")
+	case muxOK:
+		return cStrategyStyle.Render("ssh + "+mux) + cSubStyle.Render("  (session stays alive, no auto-reconnect)")
 	default:
 		return cWarnStyle.Render("ssh only") + cSubStyle.Render("  (basic, no persistent session)")
 	}
@@ -230,11 +284,14 @@ func (m doctorModel) View() string {
 	// header
 	b.WriteString("\n")
 	b.WriteString("  " + titleStyle.Render("sshtie doctor") + "  →  " + titleStyle.Render(m.prof.Name) + "\n")
-	b.WriteString(cSubStyle.Render(fmt.Sprintf("  %s@%s · port %d", m.prof.User, m.prof.Host, port)) + "\n\n")
+	b.WriteString(cSubStyle.Render(fmt.Sprintf("  %s@%s · port %d%s", m.prof.User, m.prof.Host, port, viaSuffix(m.prof))) + "\n\n")
 	b.WriteString(cSubStyle.Render("  ───────────────────────────────────────────") + "\n")
 
 	// checks
 	for _, c := range m.checks {
+		if c.label == "" {
+			continue
+		}
 		icon := ""
 		detail := c.detail
 		switch c.state {
@@ -304,6 +361,36 @@ func cmdDoctorSSH(p profile.Profile) tea.Cmd {
 		if port == 0 {
 			port = 22
 		}
+		if p.Transport.Enabled() {
+			return doctorTransport(p)
+		}
+		hops, err := profile.JumpChain(p)
+		if err != nil {
+			return dSingleMsg{
+				idx:    dSSH,
+				state:  cFail,
+				detail: cWarnStyle.Render("invalid jump chain"),
+				hint:   fmt.Sprintf("%v\n  Fix it with:  sshtie edit %s", err, p.Name),
+			}
+		}
+		if len(hops) > 0 {
+			host, hopPort := profile.EntryPoint(p, hops)
+			conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(hopPort)), 5*time.Second)
+			if err != nil {
+				return dSingleMsg{
+					idx:    dSSH,
+					state:  cFail,
+					detail: cWarnStyle.Render("jump host unreachable"),
+					hint: fmt.Sprintf(
+						"SSH can't reach the jump host %s (%s:%d).\n"+
+							"  • Is the bastion online?\n"+
+							"  • Correct jump chain?  sshtie edit %s",
+						hops[0].Label(), host, hopPort, p.Name),
+				}
+			}
+			conn.Close()
+			return dSingleMsg{idx: dSSH, state: cOK, detail: cOKStyle.Render("reachable via " + hops[0].Label())}
+		}
 		conn, err := net.DialTimeout("tcp", net.JoinHostPort(p.Host, strconv.Itoa(port)), 5*time.Second)
 		if err != nil {
 			return dSingleMsg{
@@ -323,6 +410,59 @@ func cmdDoctorSSH(p profile.Profile) tea.Cmd {
 	}
 }
 
+// doctorTransport asks the cloud CLI whether p's instance is running, in
+// place of dialling an SSH port it doesn't expose.
+func doctorTransport(p profile.Profile) dSingleMsg {
+	if err := profile.ValidateTransport(p); err != nil {
+		return dSingleMsg{
+			idx:    dSSH,
+			state:  cFail,
+			detail: cWarnStyle.Render("invalid transport"),
+			hint:   fmt.Sprintf("%v\n  Fix it with:  sshtie edit %s", err, p.Name),
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	if err := cloud.Running(ctx, p.Transport); err != nil {
+		return dSingleMsg{
+			idx:    dSSH,
+			state:  cFail,
+			detail: cWarnStyle.Render("instance not running"),
+			hint: fmt.Sprintf(
+				"%v\n"+
+					"  • Is the %s CLI installed and logged in?\n"+
+					"  • Is the instance started?\n"+
+					"  • Correct instance / region / zone / project?  sshtie edit %s",
+				err, cloud.CLI(p.Transport), p.Name),
+		}
+	}
+	return dSingleMsg{idx: dSSH, state: cOK, detail: cOKStyle.Render("instance running · " + p.Transport.String())}
+}
+
+// cmdDoctorMosh runs the end-to-end mosh test: a throwaway mosh-server and
+// a real encrypted datagram exchange over UDP.
+func cmdDoctorMosh(p profile.Profile) tea.Cmd {
+	return func() tea.Msg {
+		v, _, err := probe.CheckMosh(p, netenv.Current(), false)
+		switch {
+		case err != nil:
+			return dSingleMsg{idx: dUDP, state: cSkip, detail: cSkipStyle.Render("couldn't test")}
+		case !v.OK:
+			return dSingleMsg{
+				idx:    dUDP,
+				state:  cFail,
+				detail: cWarnStyle.Render("no reply"),
+				hint: "mosh-server started, but its UDP packets never made it back.\n" +
+					"  mosh needs ports 60000–61000/UDP open end to end.\n  On the server run:\n    sudo ufw allow 60000:61000/udp",
+			}
+		}
+		return dSingleMsg{idx: dUDP, state: cOK,
+			detail: cOKStyle.Render(fmt.Sprintf("round trip %s", v.RTT.Round(time.Millisecond)))}
+	}
+}
+
+// cmdDoctorUDP is the fallback when the mosh test can't log in: it only
+// notices a port that actively refuses.
 func cmdDoctorUDP(host string) tea.Cmd {
 	return func() tea.Msg {
 		addr := net.JoinHostPort(host, "60001")
@@ -348,7 +488,7 @@ func cmdDoctorUDP(host string) tea.Cmd {
 				hint:   "UDP port 60001 is blocked — mosh needs ports 60000–61000/UDP.\n  On the server run:\n    sudo ufw allow 60000:61000/udp",
 			}
 		}
-		return dSingleMsg{idx: dUDP, state: cOK, detail: cOKStyle.Render("open")}
+		return dSingleMsg{idx: dUDP, state: cOK, detail: cOKStyle.Render("not refused") + cSkipStyle.Render("  (unverified)")}
 	}
 }
 
@@ -387,57 +527,54 @@ func cmdDoctorTSServer(host string) tea.Cmd {
 
 func cmdDoctorRemote(p profile.Profile) tea.Cmd {
 	return func() tea.Msg {
-		port := p.Port
-		if port == 0 {
-			port = 22
-		}
-		args := []string{
-			"-p", strconv.Itoa(port),
-			"-o", "StrictHostKeyChecking=accept-new",
-			"-o", "ConnectTimeout=5",
-			"-o", "BatchMode=yes",
-		}
-		key := p.DefaultKey()
-		if _, err := os.Stat(key); err == nil {
-			args = append(args, "-i", key)
-		}
-		args = append(args, fmt.Sprintf("%s@%s", p.User, p.Host))
-		// Single round-trip: get tmux version + check mosh-server
-		args = append(args, "tmux -V 2>/dev/null; which mosh-server >/dev/null 2>&1 && echo MOSH_OK")
-
-		out, err := exec.Command("ssh", args...).Output()
+		facts, err := probe.Run(p)
 		if err != nil {
+			why := probeSkipReason(err)
 			return dRemoteMsg{
 				tmuxState:  cSkip,
-				tmuxDetail: cSkipStyle.Render("couldn't verify"),
+				tmuxDetail: cSkipStyle.Render(why),
 				moshState:  cSkip,
-				moshDetail: cSkipStyle.Render("couldn't verify"),
+				moshDetail: cSkipStyle.Render(why),
+				etState:    cSkip,
+				etDetail:   cSkipStyle.Render(why),
 			}
 		}
 
-		s := string(out)
 		msg := dRemoteMsg{}
 
-		// tmux: output line looks like "tmux 3.3a"
-		if strings.Contains(s, "tmux ") {
-			for _, line := range strings.Split(s, "\n") {
-				if strings.HasPrefix(line, "tmux ") {
-					msg.tmuxState = cOK
-					msg.tmuxDetail = cOKStyle.Render(strings.TrimSpace(line))
-					break
-				}
-			}
-		} else {
+		switch mux := muxOf(p); {
+		case mux == "none":
+			msg.tmuxState = cSkip
+			msg.tmuxDetail = cSkipStyle.Render("not used")
+		case mux == "tmux" && facts.Has(mux):
+			msg.tmuxState = cOK
+			msg.tmuxDetail = cOKStyle.Render(facts.Tmux)
+		case facts.Has(mux):
+			msg.tmuxState = cOK
+			msg.tmuxDetail = cOKStyle.Render("installed")
+		default:
 			msg.tmuxState = cFail
 			msg.tmuxDetail = cWarnStyle.Render("not installed")
 			msg.tmuxHint = fmt.Sprintf(
-				"tmux is not on the server.\n"+
-					"  tmux keeps your work running even after you disconnect.\n"+
-					"  Install it with:  sshtie install %s", p.Name)
+				"%s is not on the server.\n"+
+					"  %s keeps your work running even after you disconnect.\n"+
+					"  Install it with:  sshtie install %s", mux, mux, p.Name)
+		}
+
+		if facts.Has("etserver") {
+			msg.etState = cOK
+			msg.etDetail = cOKStyle.Render("installed")
+		} else {
+			msg.etState = cFail
+			msg.etDetail = cWarnStyle.Render("not installed")
+			msg.etHint = fmt.Sprintf(
+				"etserver is not on the server.\n"+
+					"  Eternal Terminal keeps your session through roaming, over TCP.\n"+
+					"  Install it with:  sshtie install %s --et", p.Name)
 		}
 
 		// mosh-server
-		if strings.Contains(s, "MOSH_OK") {
+		if facts.Has("mosh-server") {
 			msg.moshState = cOK
 			msg.moshDetail = cOKStyle.Render("installed")
 		} else {
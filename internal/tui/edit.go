@@ -29,12 +29,13 @@ var (
 
 const barWidth = 20
 
-// Row indices: 0-3 = sliders/toggles, 4 = rename, 5 = delete.
+// Row indices: 0-3 = sliders/toggles, 4 = forwards, 5 = rename, 6 = delete.
 const (
 	numSliderOpts = 4
-	optRename     = 4
-	optDelete     = 5
-	numOpts       = 6
+	optForwards   = 4
+	optRename     = 5
+	optDelete     = 6
+	numOpts       = 7
 )
 
 // ── Option definitions ────────────────────────────────────────────────────────
@@ -105,6 +106,9 @@ type EditResult struct {
 	ServerAliveInterval int
 	ServerAliveCountMax int
 	ConnectionAttempts  int
+
+	ForwardsChanged bool              // true → replace the profile's forwards
+	Forwards        []profile.Forward // parsed from the forwards row
 }
 
 type editModel struct {
@@ -112,6 +116,8 @@ type editModel struct {
 	cursor        int
 	opts          []editOpt
 	newName       string // rename text buffer
+	forwards      string // forwards text buffer ("L5432:db:5432 D1080")
+	origForwards  string
 	deleteConfirm bool   // first Enter on delete row = show warning
 	isDeleted     bool   // second Enter on delete row = confirmed
 	done          bool
@@ -120,9 +126,12 @@ type editModel struct {
 }
 
 func newEditModel(p profile.Profile) editModel {
+	fwd := forwardsText(p.Forwards)
 	return editModel{
-		profileName: p.Name,
-		opts:        defaultOpts(p),
+		profileName:  p.Name,
+		opts:         defaultOpts(p),
+		forwards:     fwd,
+		origForwards: fwd,
 	}
 }
 
@@ -134,6 +143,13 @@ func (m editModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	// On text rows, printable keys are input — even q/h/j/k/l.
+	if buf := m.textBuffer(); buf != nil && (key.Type == tea.KeyRunes || key.Type == tea.KeySpace) {
+		*buf += string(key.Runes)
+		m.errMsg = ""
+		return m, nil
+	}
+
 	switch key.String() {
 	case "ctrl+c", "q":
 		m.aborted = true
@@ -153,6 +169,11 @@ func (m editModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			m.deleteConfirm = true
 		default:
+			if _, err := profile.ParseForwards(m.forwards); err != nil {
+				m.cursor = optForwards
+				m.errMsg = err.Error()
+				return m, nil
+			}
 			m.done = true
 			return m, tea.Quit
 		}
@@ -202,23 +223,36 @@ func (m editModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case "backspace":
-		if m.cursor == optRename && len(m.newName) > 0 {
-			runes := []rune(m.newName)
-			m.newName = string(runes[:len(runes)-1])
+		if buf := m.textBuffer(); buf != nil && len(*buf) > 0 {
+			runes := []rune(*buf)
+			*buf = string(runes[:len(runes)-1])
+			m.errMsg = ""
 		}
 
-	default:
-		if m.cursor == optRename {
-			runes := []rune(key.String())
-			if len(runes) == 1 && runes[0] >= 32 {
-				m.newName += string(runes)
-			}
-		}
 	}
 
 	return m, nil
 }
 
+// textBuffer returns the text being typed on the current row, if it is one.
+func (m *editModel) textBuffer() *string {
+	switch m.cursor {
+	case optForwards:
+		return &m.forwards
+	case optRename:
+		return &m.newName
+	}
+	return nil
+}
+
+func forwardsText(fs []profile.Forward) string {
+	parts := make([]string, len(fs))
+	for i, f := range fs {
+		parts[i] = f.String()
+	}
+	return strings.Join(parts, " ")
+}
+
 func largeStep(opt *editOpt) int {
 	span := opt.max - opt.min
 	if span <= 10 { return 1 }
@@ -232,10 +266,13 @@ func (m editModel) View() string {
 	sb.WriteString(editTitle.Render("sshtie edit") + "  ")
 	sb.WriteString(editSub.Render(m.profileName) + "\n\n")
 
-	// Controls hint — changes when on rename row
-	if m.cursor == optRename {
+	// Controls hint — changes when on a text row
+	switch m.cursor {
+	case optRename:
 		sb.WriteString(editHint.Render("  type new name  ·  backspace  delete char  ·  enter  save  ·  esc  cancel") + "\n\n")
-	} else {
+	case optForwards:
+		sb.WriteString(editHint.Render("  L5432:localhost:5432  R8080:localhost:3000  D1080  ·  space-separated  ·  enter  save") + "\n\n")
+	default:
 		sb.WriteString(editHint.Render("  ↑/↓  select  ·  ←/→  adjust  ·  shift+←/→  jump  ·  enter  save  ·  esc  cancel") + "\n\n")
 	}
 
@@ -296,7 +333,26 @@ func (m editModel) View() string {
 	sb.WriteString("\n")
 	sb.WriteString(editMeta.Render("  ─── Profile ────────────────────────────────────────────") + "\n")
 
-	// Row 4: Rename
+	// Row 4: Port forwards
+	if m.cursor == optForwards {
+		sb.WriteString(editActive.Render("  ▶ "))
+		sb.WriteString(editActive.Render(fmt.Sprintf("%-22s", "Port forwards")))
+		sb.WriteString(editVal.Render(m.forwards + "█"))
+		if m.forwards == "" {
+			sb.WriteString(editMeta.Render("  (none — mosh sessions open these over a side-channel ssh)"))
+		}
+	} else {
+		sb.WriteString(editInactive.Render("    "))
+		sb.WriteString(editInactive.Render(fmt.Sprintf("%-22s", "Port forwards")))
+		if m.forwards == "" {
+			sb.WriteString(editMeta.Render("none"))
+		} else {
+			sb.WriteString(editVal.Render(m.forwards))
+		}
+	}
+	sb.WriteString("\n")
+
+	// Row 5: Rename
 	if m.cursor == optRename {
 		sb.WriteString(editActive.Render("  ▶ "))
 		sb.WriteString(editActive.Render(fmt.Sprintf("%-22s", "Rename")))
@@ -319,7 +375,7 @@ func (m editModel) View() string {
 	}
 	sb.WriteString("\n")
 
-	// Row 5: Delete
+	// Row 6: Delete
 	if m.cursor == optDelete {
 		sb.WriteString(editActive.Render("  ▶ "))
 		sb.WriteString(editActive.Render(fmt.Sprintf("%-22s", "Delete profile")))
@@ -362,7 +418,11 @@ func RunEdit(p profile.Profile) (EditResult, error) {
 		newName = "" // no actual rename
 	}
 
+	forwards, _ := profile.ParseForwards(m.forwards) // validated on enter
+
 	return EditResult{
+		ForwardsChanged:     strings.Join(strings.Fields(m.forwards), " ") != m.origForwards,
+		Forwards:            forwards,
 		Saved:               true,
 		Deleted:             m.isDeleted,
 		NewName:             newName,
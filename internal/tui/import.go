@@ -0,0 +1,149 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/ainsuotain/sshtie/internal/profile"
+)
+
+// ImportItem is one candidate host offered by the import picker.
+type ImportItem struct {
+	Profile profile.Profile
+	Source  string // where it was found, e.g. ~/.ssh/config:12
+	Note    string // duplicate warning shown next to the row ("" = none)
+	Blocked bool   // can't be imported (name already taken)
+}
+
+var importWarn = lipgloss.NewStyle().Foreground(lipgloss.Color("208"))
+
+type importModel struct {
+	items    []ImportItem
+	selected []bool
+	cursor   int
+	done     bool
+}
+
+func (m importModel) Init() tea.Cmd { return nil }
+
+func (m importModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	switch key.String() {
+	case "ctrl+c", "q", "esc":
+		return m, tea.Quit
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.items)-1 {
+			m.cursor++
+		}
+	case " ", "x":
+		if len(m.items) > 0 && !m.items[m.cursor].Blocked {
+			m.selected[m.cursor] = !m.selected[m.cursor]
+		}
+	case "a":
+		// Toggle every importable row: select all unless all are selected.
+		all := true
+		for i, it := range m.items {
+			if !it.Blocked && !m.selected[i] {
+				all = false
+			}
+		}
+		for i, it := range m.items {
+			if !it.Blocked {
+				m.selected[i] = !all
+			}
+		}
+	case "enter":
+		m.done = true
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m importModel) View() string {
+	var sb strings.Builder
+
+	sb.WriteString(titleStyle.Render("sshtie import") + "  choose hosts to turn into profiles\n\n")
+
+	count := 0
+	for i, it := range m.items {
+		if m.selected[i] {
+			count++
+		}
+		box := "[ ]"
+		switch {
+		case it.Blocked:
+			box = "[-]"
+		case m.selected[i]:
+			box = "[x]"
+		}
+		p := it.Profile
+		port := p.Port
+		if port == 0 {
+			port = 22
+		}
+		addr := fmt.Sprintf("%s:%d", p.Host, port)
+		if p.User != "" {
+			addr = p.User + "@" + addr
+		}
+		row := fmt.Sprintf("%s %-18s  %-30s", box, p.Name, addr)
+		if len(p.Jump) > 0 {
+			row += "  via " + strings.Join(p.Jump, " → ")
+		}
+
+		switch {
+		case i == m.cursor:
+			sb.WriteString(selectedStyle.Render("▶ "+row) + "\n")
+		case it.Blocked:
+			sb.WriteString(dimStyle.Render("  "+row) + "\n")
+		default:
+			sb.WriteString(normalStyle.Render("  "+row) + "\n")
+		}
+		if it.Note != "" {
+			sb.WriteString("      " + importWarn.Render("⚠ "+it.Note) + "\n")
+		}
+	}
+
+	if len(m.items) > 0 {
+		sb.WriteString("\n" + dimStyle.Render("  from "+m.items[m.cursor].Source) + "\n")
+	}
+	sb.WriteString("\n")
+	sb.WriteString(helpStyle.Render(fmt.Sprintf("  %d selected  •  space  toggle  •  a  all  •  enter  import  •  q  cancel", count)))
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+// RunImport shows the import picker and returns the indexes of the items the
+// user chose. It returns nil when the user cancels.
+func RunImport(items []ImportItem) ([]int, error) {
+	m := importModel{items: items, selected: make([]bool, len(items))}
+	for i, it := range items {
+		// Pre-select everything that isn't a known duplicate.
+		m.selected[i] = !it.Blocked && it.Note == ""
+	}
+	final, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	if err != nil {
+		return nil, err
+	}
+	fm := final.(importModel)
+	if !fm.done {
+		return nil, nil
+	}
+	var chosen []int
+	for i, sel := range fm.selected {
+		if sel {
+			chosen = append(chosen, i)
+		}
+	}
+	return chosen, nil
+}
@@ -0,0 +1,311 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/ainsuotain/sshtie/internal/connector"
+	"github.com/ainsuotain/sshtie/internal/profile"
+	"github.com/ainsuotain/sshtie/internal/tmux"
+)
+
+// sessMode is what the sessions screen is waiting for.
+type sessMode int
+
+const (
+	sessBrowse  sessMode = iota
+	sessNew              // typing a name for a new session
+	sessRename           // typing a new name for the selected session
+	sessConfirm          // y/n before killing the selected session
+)
+
+type sessionsMsg struct {
+	sessions []tmux.Session
+	err      error
+}
+
+// sessionOpMsg reports a finished create/rename/kill; focus is the session
+// to put the cursor on once the list is reloaded.
+type sessionOpMsg struct {
+	note  string
+	focus string
+	err   error
+}
+
+type sessionsModel struct {
+	prof     profile.Profile
+	sessions []tmux.Session
+	cursor   int
+	loading  bool
+	busy     string // operation in progress ("" = none)
+	mode     sessMode
+	buf      string
+	note     string // last operation's result
+	err      string
+	focus    string
+	frame    int
+	attach   string
+}
+
+func (m sessionsModel) Init() tea.Cmd {
+	return tea.Batch(cSpinTick(), cmdLoadSessions(m.prof))
+}
+
+func (m sessionsModel) selected() (tmux.Session, bool) {
+	if m.cursor < 0 || m.cursor >= len(m.sessions) {
+		return tmux.Session{}, false
+	}
+	return m.sessions[m.cursor], true
+}
+
+func (m sessionsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	case sessionsMsg:
+		m.loading = false
+		if msg.err != nil {
+			m.err = msg.err.Error()
+			return m, nil
+		}
+		m.sessions = msg.sessions
+		if m.focus != "" {
+			for i, s := range m.sessions {
+				if s.Name == m.focus {
+					m.cursor = i
+				}
+			}
+			m.focus = ""
+		}
+		if m.cursor >= len(m.sessions) {
+			m.cursor = len(m.sessions) - 1
+		}
+		if m.cursor < 0 {
+			m.cursor = 0
+		}
+		return m, nil
+
+	case sessionOpMsg:
+		m.busy = ""
+		if msg.err != nil {
+			m.err = msg.err.Error()
+			return m, nil
+		}
+		m.note, m.focus, m.loading = msg.note, msg.focus, true
+		return m, tea.Batch(cSpinTick(), cmdLoadSessions(m.prof))
+
+	case cSpinTickMsg:
+		m.frame = (m.frame + 1) % len(cSpinFrames)
+		if m.loading || m.busy != "" {
+			return m, cSpinTick()
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		switch m.mode {
+		case sessNew, sessRename:
+			return m.updateName(msg)
+		case sessConfirm:
+			return m.updateConfirm(msg)
+		}
+		return m.updateBrowse(msg)
+	}
+	return m, nil
+}
+
+func (m sessionsModel) updateBrowse(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc":
+		return m, tea.Quit
+	}
+	if m.loading || m.busy != "" {
+		return m, nil
+	}
+	m.note, m.err = "", ""
+	switch msg.String() {
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.sessions)-1 {
+			m.cursor++
+		}
+	case "enter":
+		if s, ok := m.selected(); ok {
+			m.attach = s.Name
+			return m, tea.Quit
+		}
+	case "n":
+		m.mode, m.buf = sessNew, ""
+	case "r":
+		if s, ok := m.selected(); ok {
+			m.mode, m.buf = sessRename, s.Name
+		}
+	case "x", "d":
+		if _, ok := m.selected(); ok {
+			m.mode = sessConfirm
+		}
+	case "R":
+		m.loading = true
+		return m, tea.Batch(cSpinTick(), cmdLoadSessions(m.prof))
+	}
+	return m, nil
+}
+
+func (m sessionsModel) updateName(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode, m.err = sessBrowse, ""
+	case "enter":
+		name := strings.TrimSpace(m.buf)
+		if err := tmux.ValidName(name); err != nil {
+			m.err = err.Error()
+			return m, nil
+		}
+		if _, taken := tmux.Find(m.sessions, name); taken {
+			m.err = fmt.Sprintf("a session called %q already exists", name)
+			return m, nil
+		}
+		p := m.prof
+		m.err = ""
+		if m.mode == sessNew {
+			m.mode, m.busy = sessBrowse, "creating "+name
+			return m, tea.Batch(cSpinTick(), cmdSessionOp(func() error {
+				return connector.NewTmuxSession(p, name)
+			}, "created "+name, name))
+		}
+		old, _ := m.selected()
+		m.mode, m.busy = sessBrowse, "renaming "+old.Name
+		return m, tea.Batch(cSpinTick(), cmdSessionOp(func() error {
+			return connector.RenameTmuxSession(p, old.Name, name)
+		}, fmt.Sprintf("renamed %s → %s", old.Name, name), name))
+	case "backspace":
+		if r := []rune(m.buf); len(r) > 0 {
+			m.buf = string(r[:len(r)-1])
+		}
+	default:
+		if r := []rune(msg.String()); len(r) == 1 && r[0] >= 32 {
+			m.buf += string(r)
+		}
+	}
+	return m, nil
+}
+
+func (m sessionsModel) updateConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	s, _ := m.selected()
+	m.mode = sessBrowse
+	if msg.String() != "y" {
+		return m, nil
+	}
+	p := m.prof
+	m.busy = "killing " + s.Name
+	return m, tea.Batch(cSpinTick(), cmdSessionOp(func() error {
+		return connector.KillTmuxSession(p, s.Name)
+	}, "killed "+s.Name, ""))
+}
+
+func (m sessionsModel) View() string {
+	var b strings.Builder
+	b.WriteString("\n  " + titleStyle.Render("sshtie sessions") + "  →  " + titleStyle.Render(m.prof.Name) + "\n")
+	b.WriteString(cSubStyle.Render(fmt.Sprintf("  tmux on %s@%s", m.prof.User, m.prof.Host)) + "\n\n")
+
+	switch {
+	case m.loading && len(m.sessions) == 0:
+		b.WriteString("  " + cSpinStyle.Render(cSpinFrames[m.frame]) + cSubStyle.Render(" listing sessions…") + "\n")
+	case len(m.sessions) == 0 && m.err == "":
+		b.WriteString(dimStyle.Render("  No tmux sessions running — press n to start one.") + "\n")
+	}
+
+	own := m.prof.TmuxSession
+	if own == "" {
+		own = "main"
+	}
+	for i, s := range m.sessions {
+		mark := " "
+		if s.Name == own {
+			mark = "★"
+		}
+		row := fmt.Sprintf("%s %-20s %3d win  %-9s %s", mark, s.Name, s.Windows, attachedLabel(s), activityLabel(s))
+		if i == m.cursor {
+			b.WriteString("  " + selectedStyle.Render("▶ "+row) + "\n")
+		} else {
+			b.WriteString("  " + normalStyle.Render("  "+row) + "\n")
+		}
+	}
+	b.WriteString("\n")
+
+	switch m.mode {
+	case sessNew:
+		b.WriteString("  " + cKeyStyle.Render("New session: ") + normalStyle.Render(m.buf+"█") + "\n")
+	case sessRename:
+		b.WriteString("  " + cKeyStyle.Render("Rename to: ") + normalStyle.Render(m.buf+"█") + "\n")
+	case sessConfirm:
+		s, _ := m.selected()
+		b.WriteString("  " + cWarnStyle.Render(fmt.Sprintf("Kill %s and everything running in it? [y/N]", s.Name)) + "\n")
+	}
+	switch {
+	case m.busy != "":
+		b.WriteString("  " + cSpinStyle.Render(cSpinFrames[m.frame]) + cSubStyle.Render(" "+m.busy+"…") + "\n")
+	case m.err != "":
+		b.WriteString("  " + errorStyle.Render("✗ "+m.err) + "\n")
+	case m.note != "":
+		b.WriteString("  " + cOKStyle.Render("✓ "+m.note) + "\n")
+	}
+	b.WriteString("\n")
+
+	if m.mode == sessBrowse {
+		b.WriteString(helpStyle.Render("  ↑/↓  select  •  enter  attach  •  n  new  •  r  rename  •  x  kill  •  R  refresh  •  q  quit"))
+	} else {
+		b.WriteString(helpStyle.Render("  enter  confirm  •  esc  back"))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+func attachedLabel(s tmux.Session) string {
+	switch s.Attached {
+	case 0:
+		return "detached"
+	case 1:
+		return "attached"
+	}
+	return fmt.Sprintf("attached×%d", s.Attached)
+}
+
+func activityLabel(s tmux.Session) string {
+	if s.Activity.IsZero() {
+		return ""
+	}
+	return "active " + tmux.Ago(s.Activity)
+}
+
+func cmdLoadSessions(p profile.Profile) tea.Cmd {
+	return func() tea.Msg {
+		ss, err := connector.TmuxSessions(p)
+		return sessionsMsg{sessions: ss, err: err}
+	}
+}
+
+func cmdSessionOp(op func() error, note, focus string) tea.Cmd {
+	return func() tea.Msg {
+		return sessionOpMsg{note: note, focus: focus, err: op()}
+	}
+}
+
+// RunSessions shows the tmux sessions running on p and lets the user
+// create, rename and kill them. It returns the session to attach to, or ""
+// when the user just quit. p must already be resolved (see
+// connector.Resolve).
+func RunSessions(p profile.Profile) (string, error) {
+	m := sessionsModel{prof: p, loading: true}
+	final, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	if err != nil {
+		return "", err
+	}
+	return final.(sessionsModel).attach, nil
+}
@@ -0,0 +1,63 @@
+// Package wol builds and sends Wake-on-LAN magic packets.
+package wol
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+)
+
+// DefaultAddr is where magic packets go when no broadcast address is set:
+// the limited broadcast address, discard port.
+const DefaultAddr = "255.255.255.255:9"
+
+// MagicPacket returns the 102-byte magic packet for mac: six 0xff bytes
+// followed by the MAC repeated sixteen times.
+func MagicPacket(mac string) ([]byte, error) {
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) != 6 {
+		return nil, fmt.Errorf("invalid MAC address %q", mac)
+	}
+	pkt := bytes.Repeat([]byte{0xff}, 6)
+	for i := 0; i < 16; i++ {
+		pkt = append(pkt, hw...)
+	}
+	return pkt, nil
+}
+
+// Addr normalises a broadcast address: "" becomes DefaultAddr and a bare IP
+// gets port 9.
+func Addr(broadcast string) string {
+	if broadcast == "" {
+		return DefaultAddr
+	}
+	if _, _, err := net.SplitHostPort(broadcast); err == nil {
+		return broadcast
+	}
+	return net.JoinHostPort(broadcast, "9")
+}
+
+// Send broadcasts the magic packet for mac to broadcast (see Addr). It is
+// sent three times: it's a single UDP datagram with no reply to wait for.
+func Send(mac, broadcast string) error {
+	pkt, err := MagicPacket(mac)
+	if err != nil {
+		return err
+	}
+	addr, err := net.ResolveUDPAddr("udp", Addr(broadcast))
+	if err != nil {
+		return fmt.Errorf("wake-on-LAN: %w", err)
+	}
+	// Unconnected, so an ICMP error from an earlier copy can't fail the next.
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return fmt.Errorf("wake-on-LAN: %w", err)
+	}
+	defer conn.Close()
+	for i := 0; i < 3; i++ {
+		if _, err := conn.WriteTo(pkt, addr); err != nil {
+			return fmt.Errorf("wake-on-LAN: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package wol
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMagicPacket(t *testing.T) {
+	pkt, err := MagicPacket("3c-22-fb-12-34-56")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkt) != 102 || !bytes.Equal(pkt[:6], bytes.Repeat([]byte{0xff}, 6)) {
+		t.Fatalf("bad header: % x", pkt[:6])
+	}
+	mac := []byte{0x3c, 0x22, 0xfb, 0x12, 0x34, 0x56}
+	for i := 0; i < 16; i++ {
+		if got := pkt[6+6*i : 12+6*i]; !bytes.Equal(got, mac) {
+			t.Fatalf("repetition %d = % x", i, got)
+		}
+	}
+
+	if _, err := MagicPacket("not-a-mac"); err == nil {
+		t.Error("expected error for a bad MAC")
+	}
+	if _, err := MagicPacket("00:00:00:00:fe:80:00:00:00:00:00:00:02:00:5e:10:00:00:00:01"); err == nil {
+		t.Error("expected error for a 20-byte address")
+	}
+}
+
+func TestAddr(t *testing.T) {
+	for in, want := range map[string]string{
+		"":                DefaultAddr,
+		"192.168.1.255":   "192.168.1.255:9",
+		"192.168.1.255:7": "192.168.1.255:7",
+		"ff02::1":         "[ff02::1]:9",
+	} {
+		if got := Addr(in); got != want {
+			t.Errorf("Addr(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	l, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if err := Send("3c:22:fb:12:34:56", l.LocalAddr().String()); err != nil {
+		t.Fatal(err)
+	}
+	l.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 200)
+	n, _, err := l.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := MagicPacket("3c:22:fb:12:34:56")
+	if !bytes.Equal(buf[:n], want) {
+		t.Errorf("received % x", buf[:n])
+	}
+}